@@ -1,8 +1,6 @@
 package xmldsig
 
 import (
-	"errors"
-
 	"github.com/beevik/etree"
 	"github.com/deb-ict/go-xmldsig/canonicalizer"
 )
@@ -20,7 +18,23 @@ func newCanonicalizationMethod(signedInfo *SignedInfo) *CanonicalizationMethod {
 	}
 }
 
+func NewCanonicalizationMethod(uri string) *CanonicalizationMethod {
+	canonicalizationMethod := newCanonicalizationMethod(nil)
+	canonicalizationMethod.Algorithm = uri
+	return canonicalizationMethod
+}
+
+func NewCanonicalizationMethodFrom(c canonicalizer.Canonicalizer) *CanonicalizationMethod {
+	canonicalizationMethod := newCanonicalizationMethod(nil)
+	canonicalizationMethod.Algorithm = c.GetAlgorithm()
+	canonicalizationMethod.canonicalizer = c
+	return canonicalizationMethod
+}
+
 func (xml *CanonicalizationMethod) root() *SignedXml {
+	if xml.signedInfo == nil {
+		return nil
+	}
 	return xml.signedInfo.root()
 }
 
@@ -48,13 +62,25 @@ func (xml *CanonicalizationMethod) getXml() (*etree.Element, error) {
 
 	el.CreateAttr("Algorithm", xml.Algorithm)
 
-	if xml.canonicalizer == nil {
-		return nil, errors.New("canonicalizer is nil")
+	err := xml.ensureCanonicalizer()
+	if err != nil {
+		return nil, err
 	}
-	err := xml.canonicalizer.WriteXml(el)
+	err = xml.canonicalizer.WriteXml(el)
 	if err != nil {
 		return nil, err
 	}
 
 	return el, nil
 }
+
+func (xml *CanonicalizationMethod) ensureCanonicalizer() error {
+	if xml.canonicalizer == nil {
+		canonicalizer, err := canonicalizer.GetCanonicalizer(xml.Algorithm)
+		if err != nil {
+			return err
+		}
+		xml.canonicalizer = canonicalizer
+	}
+	return nil
+}
@@ -0,0 +1,554 @@
+package canonicalizer
+
+import (
+	"bufio"
+	"io"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/beevik/etree"
+)
+
+const (
+	xmlNamespaceUri string = "http://www.w3.org/XML/1998/namespace"
+)
+
+type c14nMode int
+
+const (
+	c14nMode10 c14nMode = iota
+	c14nMode11
+	c14nModeExclusive
+)
+
+var (
+	uriReferencePattern = regexp.MustCompile(`^(([^:/?#]+):)?(//([^/?#]*))?([^?#]*)(\?([^#]*))?(#(.*))?$`)
+
+	textEscaper = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;", "\r", "&#xD;")
+	attrEscaper = strings.NewReplacer("&", "&amp;", "<", "&lt;", "\"", "&quot;", "\t", "&#x9;", "\n", "&#xA;", "\r", "&#xD;")
+)
+
+// c14nWriter writes the canonical form of a node-set using Canonical XML 1.0,
+// Canonical XML 1.1 or Exclusive XML Canonicalization.
+type c14nWriter struct {
+	w                 *bufio.Writer
+	nodeSet           *NodeSet
+	mode              c14nMode
+	comments          bool
+	inclusivePrefixes map[string]bool
+}
+
+// c14nScope is the namespace context while walking the tree. The output
+// namespaces are the namespace nodes of the nearest output ancestor, or the
+// namespaces rendered in effect for exclusive canonicalization.
+type c14nScope struct {
+	inScope map[string]string
+	output  map[string]string
+}
+
+type c14nAttr struct {
+	uri   string
+	local string
+	name  string
+	value string
+}
+
+func canonicalizeNodeSet(w io.Writer, nodeSet *NodeSet, mode c14nMode, comments bool, prefixList string) error {
+	cw := &c14nWriter{
+		w:                 bufio.NewWriter(w),
+		nodeSet:           nodeSet,
+		mode:              mode,
+		comments:          comments,
+		inclusivePrefixes: map[string]bool{},
+	}
+	for _, prefix := range strings.Fields(prefixList) {
+		if prefix == "#default" {
+			prefix = ""
+		}
+		cw.inclusivePrefixes[prefix] = true
+	}
+
+	root := nodeSet.Root()
+	if isDocumentNode(root) {
+		cw.writeDocument(root)
+	} else {
+		scope := &c14nScope{
+			inScope: map[string]string{},
+			output:  map[string]string{},
+		}
+		if parent := root.Parent(); parent != nil {
+			scope.inScope = inScopeNamespaces(parent)
+		}
+		cw.writeElement(root, scope)
+	}
+	return cw.w.Flush()
+}
+
+func (cw *c14nWriter) writeDocument(doc *etree.Element) {
+	scope := &c14nScope{
+		inScope: map[string]string{},
+		output:  map[string]string{},
+	}
+
+	// Nodes outside the document element are separated by a line feed
+	afterDocumentElement := false
+	for _, child := range doc.Child {
+		switch t := child.(type) {
+		case *etree.Element:
+			cw.writeElement(t, scope)
+			afterDocumentElement = true
+		case *etree.Comment, *etree.ProcInst:
+			if !cw.isRendered(child) {
+				continue
+			}
+			if afterDocumentElement {
+				cw.w.WriteString("\n")
+			}
+			cw.writeToken(child)
+			if !afterDocumentElement {
+				cw.w.WriteString("\n")
+			}
+		}
+	}
+}
+
+func (cw *c14nWriter) writeElement(el *etree.Element, scope *c14nScope) {
+	childScope := &c14nScope{
+		inScope: scope.inScope,
+		output:  scope.output,
+	}
+	if declaresNamespaces(el) {
+		childScope.inScope = copyNamespaces(scope.inScope)
+		for _, attr := range el.Attr {
+			if !isNamespaceAttr(&attr) {
+				continue
+			}
+			prefix := namespaceAttrPrefix(&attr)
+			if attr.Value == "" {
+				delete(childScope.inScope, prefix)
+			} else {
+				childScope.inScope[prefix] = attr.Value
+			}
+		}
+	}
+
+	included := cw.nodeSet.ContainsToken(el)
+	if included {
+		childScope.output = cw.writeStartElement(el, childScope.inScope, scope.output)
+	}
+	for _, child := range el.Child {
+		if childElement, ok := child.(*etree.Element); ok {
+			cw.writeElement(childElement, childScope)
+			continue
+		}
+		if cw.isRendered(child) {
+			cw.writeToken(child)
+		}
+	}
+	if included {
+		cw.w.WriteString("</")
+		cw.w.WriteString(el.FullTag())
+		cw.w.WriteString(">")
+	}
+}
+
+func (cw *c14nWriter) writeStartElement(el *etree.Element, inScope map[string]string, output map[string]string) map[string]string {
+	attrs := cw.getAttrs(el, inScope)
+
+	var namespaces map[string]string
+	var declarations map[string]string
+	if cw.mode == c14nModeExclusive {
+		namespaces, declarations = cw.getExclusiveNamespaces(el, inScope, output, attrs)
+	} else {
+		namespaces, declarations = cw.getNamespaces(el, inScope, output)
+	}
+
+	cw.w.WriteString("<")
+	cw.w.WriteString(el.FullTag())
+	prefixes := make([]string, 0, len(declarations))
+	for prefix := range declarations {
+		prefixes = append(prefixes, prefix)
+	}
+	sort.Strings(prefixes)
+	for _, prefix := range prefixes {
+		if prefix == "" {
+			cw.w.WriteString(" xmlns=\"")
+		} else {
+			cw.w.WriteString(" xmlns:" + prefix + "=\"")
+		}
+		cw.w.WriteString(attrEscaper.Replace(declarations[prefix]))
+		cw.w.WriteString("\"")
+	}
+	for _, attr := range attrs {
+		cw.w.WriteString(" " + attr.name + "=\"")
+		cw.w.WriteString(attrEscaper.Replace(attr.value))
+		cw.w.WriteString("\"")
+	}
+	cw.w.WriteString(">")
+	return namespaces
+}
+
+// getNamespaces returns the namespace nodes of the element and the declarations
+// to render: those that differ from the nearest output ancestor.
+func (cw *c14nWriter) getNamespaces(el *etree.Element, inScope map[string]string, output map[string]string) (map[string]string, map[string]string) {
+	namespaces := map[string]string{}
+	for prefix, uri := range inScope {
+		if prefix != "xml" && cw.nodeSet.ContainsNamespace(el, prefix) {
+			namespaces[prefix] = uri
+		}
+	}
+
+	declarations := map[string]string{}
+	for prefix, uri := range namespaces {
+		if outputUri, ok := output[prefix]; !ok || outputUri != uri {
+			declarations[prefix] = uri
+		}
+	}
+	if _, ok := namespaces[""]; !ok && output[""] != "" {
+		declarations[""] = ""
+	}
+	return namespaces, declarations
+}
+
+// getExclusiveNamespaces returns the namespaces in effect and the declarations
+// for the visibly utilized and inclusive prefixes. A visibly utilized prefix is
+// declared unless the nearest output ancestor that utilizes it has the same
+// namespace node in the node-set, inclusive prefixes are handled as in
+// Canonical XML.
+func (cw *c14nWriter) getExclusiveNamespaces(el *etree.Element, inScope map[string]string, output map[string]string, attrs []c14nAttr) (map[string]string, map[string]string) {
+	utilized := map[string]bool{
+		el.Space: true,
+	}
+	for _, attr := range attrs {
+		if prefix, _, ok := strings.Cut(attr.name, ":"); ok && prefix != "xml" {
+			utilized[prefix] = true
+		}
+	}
+	prefixes := map[string]bool{}
+	for prefix := range utilized {
+		prefixes[prefix] = true
+	}
+	for prefix := range cw.inclusivePrefixes {
+		if _, ok := inScope[prefix]; ok || prefix == "" {
+			prefixes[prefix] = true
+		}
+	}
+
+	namespaces := copyNamespaces(output)
+	declarations := map[string]string{}
+	for prefix := range prefixes {
+		uri, ok := inScope[prefix]
+		contained := ok && cw.nodeSet.ContainsNamespace(el, prefix)
+		if ok && (utilized[prefix] || contained) {
+			if outputUri, ok := output[prefix]; !ok || outputUri != uri {
+				declarations[prefix] = uri
+			}
+		} else if prefix == "" && output[""] != "" {
+			declarations[""] = ""
+		}
+		if contained {
+			namespaces[prefix] = uri
+		} else {
+			delete(namespaces, prefix)
+		}
+	}
+	return namespaces, declarations
+}
+
+// getAttrs returns the attributes of the element in the node-set, with the xml
+// namespace attributes inherited from ancestors that are not in the node-set.
+func (cw *c14nWriter) getAttrs(el *etree.Element, inScope map[string]string) []c14nAttr {
+	attrs := make([]c14nAttr, 0, len(el.Attr))
+	present := map[string]bool{}
+	base := ""
+	hasBase := false
+	for i := range el.Attr {
+		attr := &el.Attr[i]
+		if isNamespaceAttr(attr) {
+			continue
+		}
+		// Own xml namespace attributes are never inherited, even when omitted
+		if attr.Space == "xml" {
+			present[attr.Key] = true
+		}
+		if !cw.nodeSet.ContainsAttr(el, attr) {
+			continue
+		}
+		if attr.Space == "xml" && attr.Key == "base" && cw.mode == c14nMode11 {
+			base = attr.Value
+			hasBase = true
+			continue
+		}
+		attrs = append(attrs, c14nAttr{
+			uri:   attrNamespaceUri(attr, inScope),
+			local: attr.Key,
+			name:  attr.FullKey(),
+			value: attr.Value,
+		})
+	}
+
+	bases := make([]string, 0)
+	parent := el.Parent()
+	if cw.mode != c14nModeExclusive && parent != nil && !isDocumentNode(parent) && !cw.nodeSet.ContainsToken(parent) {
+		// All ancestors are searched for the nearest xml namespace attributes,
+		// xml:base values of the omitted ancestors are joined in 1.1
+		omitted := true
+		for ancestor := parent; ancestor != nil && !isDocumentNode(ancestor); ancestor = ancestor.Parent() {
+			omitted = omitted && !cw.nodeSet.ContainsToken(ancestor)
+			for _, attr := range ancestor.Attr {
+				if attr.Space != "xml" {
+					continue
+				}
+				if cw.mode == c14nMode11 {
+					if attr.Key == "base" {
+						if omitted {
+							bases = append(bases, attr.Value)
+						}
+						continue
+					}
+					if attr.Key == "id" {
+						continue
+					}
+				}
+				if present[attr.Key] {
+					continue
+				}
+				present[attr.Key] = true
+				attrs = append(attrs, c14nAttr{
+					uri:   xmlNamespaceUri,
+					local: attr.Key,
+					name:  attr.FullKey(),
+					value: attr.Value,
+				})
+			}
+		}
+	}
+	if cw.mode == c14nMode11 && (hasBase || len(bases) > 0) {
+		joined := ""
+		for i := len(bases) - 1; i >= 0; i-- {
+			joined = joinUriReferences(joined, bases[i])
+		}
+		if hasBase {
+			joined = joinUriReferences(joined, base)
+		}
+		attrs = append(attrs, c14nAttr{
+			uri:   xmlNamespaceUri,
+			local: "base",
+			name:  "xml:base",
+			value: joined,
+		})
+	}
+
+	sort.SliceStable(attrs, func(i, j int) bool {
+		if attrs[i].uri != attrs[j].uri {
+			return attrs[i].uri < attrs[j].uri
+		}
+		return attrs[i].local < attrs[j].local
+	})
+	return attrs
+}
+
+func (cw *c14nWriter) isRendered(token etree.Token) bool {
+	switch t := token.(type) {
+	case *etree.CharData:
+		// Text outside the document element is not part of the data model
+		if isDocumentNode(t.Parent()) {
+			return false
+		}
+	case *etree.Comment:
+		if !cw.comments {
+			return false
+		}
+	case *etree.ProcInst:
+		if t.Target == "xml" {
+			return false
+		}
+	default:
+		return false
+	}
+	return cw.nodeSet.ContainsToken(token)
+}
+
+func (cw *c14nWriter) writeToken(token etree.Token) {
+	switch t := token.(type) {
+	case *etree.CharData:
+		cw.w.WriteString(textEscaper.Replace(t.Data))
+	case *etree.Comment:
+		cw.w.WriteString("<!--")
+		cw.w.WriteString(t.Data)
+		cw.w.WriteString("-->")
+	case *etree.ProcInst:
+		cw.w.WriteString("<?")
+		cw.w.WriteString(t.Target)
+		if t.Inst != "" {
+			cw.w.WriteString(" ")
+			cw.w.WriteString(t.Inst)
+		}
+		cw.w.WriteString("?>")
+	}
+}
+
+// joinUriReferences joins xml:base values as defined by Canonical XML 1.1, which
+// resolves the reference against the base without requiring the base to be
+// absolute.
+func joinUriReferences(base string, reference string) string {
+	if base == "" {
+		return reference
+	}
+	if reference == "" {
+		return base
+	}
+
+	r := uriReferencePattern.FindStringSubmatch(reference)
+	b := uriReferencePattern.FindStringSubmatch(base)
+	var scheme, authority, path, query string
+	hasScheme, hasAuthority, hasQuery := false, false, false
+	switch {
+	case r[1] != "":
+		scheme, hasScheme = r[2], true
+		authority, hasAuthority = r[4], r[3] != ""
+		path = removeDotSegments(r[5])
+		query, hasQuery = r[7], r[6] != ""
+	case r[3] != "":
+		authority, hasAuthority = r[4], true
+		path = removeDotSegments(r[5])
+		query, hasQuery = r[7], r[6] != ""
+	default:
+		switch {
+		case r[5] == "":
+			path = b[5]
+			if r[6] != "" {
+				query, hasQuery = r[7], true
+			} else {
+				query, hasQuery = b[7], b[6] != ""
+			}
+		case strings.HasPrefix(r[5], "/"):
+			path = removeDotSegments(r[5])
+			query, hasQuery = r[7], r[6] != ""
+		default:
+			if b[3] != "" && b[5] == "" {
+				path = "/" + r[5]
+			} else {
+				path = b[5][:strings.LastIndex(b[5], "/")+1] + r[5]
+			}
+			path = removeDotSegments(path)
+			query, hasQuery = r[7], r[6] != ""
+		}
+		authority, hasAuthority = b[4], b[3] != ""
+	}
+	if !hasScheme {
+		scheme, hasScheme = b[2], b[1] != ""
+	}
+
+	var sb strings.Builder
+	if hasScheme {
+		sb.WriteString(scheme + ":")
+	}
+	if hasAuthority {
+		sb.WriteString("//" + authority)
+	}
+	sb.WriteString(path)
+	if hasQuery {
+		sb.WriteString("?" + query)
+	}
+	if r[8] != "" {
+		sb.WriteString("#" + r[9])
+	}
+	return sb.String()
+}
+
+// removeDotSegments removes the dot segments of a path, leading ".." segments
+// of a relative path are kept.
+func removeDotSegments(path string) string {
+	absolute := strings.HasPrefix(path, "/")
+	segments := strings.Split(strings.TrimPrefix(path, "/"), "/")
+	output := make([]string, 0, len(segments))
+	for i, segment := range segments {
+		last := i == len(segments)-1
+		switch segment {
+		case ".":
+			if last {
+				output = append(output, "")
+			}
+		case "..":
+			if len(output) > 0 && output[len(output)-1] != ".." {
+				output = output[:len(output)-1]
+			} else if !absolute {
+				output = append(output, "..")
+			}
+			if last {
+				output = append(output, "")
+			}
+		default:
+			output = append(output, segment)
+		}
+	}
+
+	result := strings.Join(output, "/")
+	if absolute {
+		return "/" + result
+	}
+	return result
+}
+
+func isDocumentNode(el *etree.Element) bool {
+	return el != nil && el.Parent() == nil && el.Tag == "" && el.Space == ""
+}
+
+func declaresNamespaces(el *etree.Element) bool {
+	for i := range el.Attr {
+		if isNamespaceAttr(&el.Attr[i]) {
+			return true
+		}
+	}
+	return false
+}
+
+func namespaceAttrPrefix(attr *etree.Attr) string {
+	if attr.Space == "xmlns" {
+		return attr.Key
+	}
+	return ""
+}
+
+func attrNamespaceUri(attr *etree.Attr, inScope map[string]string) string {
+	switch attr.Space {
+	case "":
+		return ""
+	case "xml":
+		return xmlNamespaceUri
+	}
+	return inScope[attr.Space]
+}
+
+func copyNamespaces(namespaces map[string]string) map[string]string {
+	result := make(map[string]string, len(namespaces)+1)
+	for prefix, uri := range namespaces {
+		result[prefix] = uri
+	}
+	return result
+}
+
+// inScopeNamespaces returns the namespaces declared by the element and its
+// ancestors, the default namespace is returned with an empty prefix.
+func inScopeNamespaces(el *etree.Element) map[string]string {
+	ancestors := make([]*etree.Element, 0)
+	for current := el; current != nil; current = current.Parent() {
+		ancestors = append(ancestors, current)
+	}
+	namespaces := map[string]string{}
+	for i := len(ancestors) - 1; i >= 0; i-- {
+		for _, attr := range ancestors[i].Attr {
+			if !isNamespaceAttr(&attr) {
+				continue
+			}
+			if attr.Value == "" {
+				delete(namespaces, namespaceAttrPrefix(&attr))
+			} else {
+				namespaces[namespaceAttrPrefix(&attr)] = attr.Value
+			}
+		}
+	}
+	return namespaces
+}
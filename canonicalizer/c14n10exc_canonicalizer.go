@@ -1,11 +1,13 @@
 package canonicalizer
 
 import (
+	"bytes"
 	"context"
 	"errors"
+	"io"
+	"strings"
 
 	"github.com/beevik/etree"
-	rhdsig "github.com/russellhaering/goxmldsig"
 )
 
 type c14N10ExcCanonicalizer struct {
@@ -25,6 +27,23 @@ func NewC14N10ExcWithCommentsCanonicalizer() Canonicalizer {
 	}
 }
 
+// NewC14N10ExcCanonicalizerWithPrefixList returns an exclusive canonicalizer
+// that handles the given prefixes as in Canonical XML, use "#default" for the
+// default namespace.
+func NewC14N10ExcCanonicalizerWithPrefixList(prefixes ...string) Canonicalizer {
+	return &c14N10ExcCanonicalizer{
+		prefixList: formatPrefixList(prefixes),
+		comments:   false,
+	}
+}
+
+func NewC14N10ExcWithCommentsCanonicalizerWithPrefixList(prefixes ...string) Canonicalizer {
+	return &c14N10ExcCanonicalizer{
+		prefixList: formatPrefixList(prefixes),
+		comments:   true,
+	}
+}
+
 func (can *c14N10ExcCanonicalizer) GetAlgorithm() string {
 	if can.comments {
 		return C14N10ExcWithCommentsNamespaceUri
@@ -38,41 +57,66 @@ func (can *c14N10ExcCanonicalizer) GetPrefixList() string {
 }
 
 func (can *c14N10ExcCanonicalizer) Canonicalize(ctx context.Context, el *etree.Element) ([]byte, error) {
-	canonicalizer := can.makeInternalCanonicalizer(can.prefixList)
-	canonicalized, err := canonicalizer.Canonicalize(el)
+	return can.CanonicalizeNodeSet(ctx, NewNodeSet(el))
+}
+
+func (can *c14N10ExcCanonicalizer) CanonicalizeNodeSet(ctx context.Context, nodeSet *NodeSet) ([]byte, error) {
+	var buffer bytes.Buffer
+	err := can.CanonicalizeNodeSetTo(ctx, nodeSet, &buffer)
 	if err != nil {
 		return nil, err
 	}
+	return buffer.Bytes(), nil
+}
 
-	return canonicalized, nil
+func (can *c14N10ExcCanonicalizer) CanonicalizeTo(ctx context.Context, el *etree.Element, w io.Writer) error {
+	return can.CanonicalizeNodeSetTo(ctx, NewNodeSet(el), w)
+}
+
+func (can *c14N10ExcCanonicalizer) CanonicalizeNodeSetTo(ctx context.Context, nodeSet *NodeSet, w io.Writer) error {
+	return canonicalizeNodeSet(w, nodeSet, c14nModeExclusive, can.comments, can.prefixList)
 }
 
 func (can *c14N10ExcCanonicalizer) ReadXml(el *etree.Element) error {
 	// Get the exclusive c14n prefix list
-	exclusiveNamespaceElements := el.SelectElements("InclusiveNamespaces")
-	if len(exclusiveNamespaceElements) > 1 {
+	inclusiveNamespacesElements := make([]*etree.Element, 0)
+	for _, child := range el.SelectElements("InclusiveNamespaces") {
+		if child.NamespaceURI() == C14N10ExcNamespaceUri {
+			inclusiveNamespacesElements = append(inclusiveNamespacesElements, child)
+		}
+	}
+	if len(inclusiveNamespacesElements) > 1 {
 		return errors.New("element does not contain a single InclusiveNamespaces element")
 	}
-	if len(exclusiveNamespaceElements) > 0 {
-		can.prefixList = exclusiveNamespaceElements[0].SelectAttrValue("PrefixList", "")
+	can.prefixList = ""
+	if len(inclusiveNamespacesElements) > 0 {
+		can.prefixList = formatPrefixList(strings.Fields(inclusiveNamespacesElements[0].SelectAttrValue("PrefixList", "")))
 	}
 
 	return nil
 }
 
 func (can *c14N10ExcCanonicalizer) WriteXml(el *etree.Element) error {
-	inclusiveNamespacesElement := etree.NewElement("InclusiveNamespaces")
-	inclusiveNamespacesElement.CreateAttr("xmlns", C14N10ExcNamespaceUri)
+	// The element is omitted for an empty prefix list
+	if can.prefixList == "" {
+		return nil
+	}
+
+	inclusiveNamespacesElement := el.CreateElement("InclusiveNamespaces")
+	inclusiveNamespacesElement.Space = "ec"
+	inclusiveNamespacesElement.CreateAttr("xmlns:ec", C14N10ExcNamespaceUri)
 	inclusiveNamespacesElement.CreateAttr("PrefixList", can.prefixList)
-	el.AddChild(inclusiveNamespacesElement)
 
 	return nil
 }
 
-func (can *c14N10ExcCanonicalizer) makeInternalCanonicalizer(prefixList string) rhdsig.Canonicalizer {
-	if can.comments {
-		return rhdsig.MakeC14N10ExclusiveWithCommentsCanonicalizerWithPrefixList(prefixList)
-	} else {
-		return rhdsig.MakeC14N10ExclusiveCanonicalizerWithPrefixList(can.prefixList)
+func formatPrefixList(prefixes []string) string {
+	fields := make([]string, 0, len(prefixes))
+	for _, prefix := range prefixes {
+		if prefix == "" {
+			prefix = "#default"
+		}
+		fields = append(fields, strings.Fields(prefix)...)
 	}
+	return strings.Join(fields, " ")
 }
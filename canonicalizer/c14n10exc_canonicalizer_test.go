@@ -0,0 +1,88 @@
+package canonicalizer
+
+import (
+	"testing"
+
+	"github.com/beevik/etree"
+)
+
+func Test_C14N10ExcCanonicalizer_WriteXml(t *testing.T) {
+	tests := []struct {
+		name       string
+		can        Canonicalizer
+		expected   string
+		prefixList string
+	}{
+		{
+			name:     "Empty",
+			can:      NewC14N10ExcCanonicalizer(),
+			expected: `<Transform/>`,
+		},
+		{
+			name:     "EmptyPrefixList",
+			can:      NewC14N10ExcWithCommentsCanonicalizerWithPrefixList(),
+			expected: `<Transform/>`,
+		},
+		{
+			name:       "PrefixList",
+			can:        NewC14N10ExcCanonicalizerWithPrefixList("ds", "", "xs"),
+			expected:   `<Transform><ec:InclusiveNamespaces xmlns:ec="http://www.w3.org/2001/10/xml-exc-c14n#" PrefixList="ds #default xs"/></Transform>`,
+			prefixList: "ds #default xs",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc := etree.NewDocument()
+			el := doc.CreateElement("Transform")
+			err := tt.can.WriteXml(el)
+			if err != nil {
+				t.Fatal(err)
+			}
+			actual, err := doc.WriteToString()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if actual != tt.expected {
+				t.Errorf("expected:\n%s\ngot:\n%s", tt.expected, actual)
+			}
+
+			// The written prefix list is read again
+			loaded := NewC14N10ExcCanonicalizer()
+			err = loaded.ReadXml(parseTestDocument(t, actual).Root())
+			if err != nil {
+				t.Fatal(err)
+			}
+			prefixList := loaded.(*c14N10ExcCanonicalizer).GetPrefixList()
+			if prefixList != tt.prefixList {
+				t.Errorf("expected the prefix list %q, got %q", tt.prefixList, prefixList)
+			}
+		})
+	}
+}
+
+func Test_C14N10ExcCanonicalizer_ReadXml(t *testing.T) {
+	// The prefixes of a read prefix list are rendered although they are not used
+	transform := parseTestDocument(t, `<ds:Transform xmlns:ds="http://www.w3.org/2000/09/xmldsig#" Algorithm="http://www.w3.org/2001/10/xml-exc-c14n#">`+
+		`<ec:InclusiveNamespaces xmlns:ec="http://www.w3.org/2001/10/xml-exc-c14n#" PrefixList=" xs&#9;#default "/>`+
+		`</ds:Transform>`)
+	can := NewC14N10ExcCanonicalizer()
+	err := can.ReadXml(transform.Root())
+	if err != nil {
+		t.Fatal(err)
+	}
+	doc := parseTestDocument(t, `<a xmlns="urn:default" xmlns:xs="http://www.w3.org/2001/XMLSchema" xmlns:p="urn:p"><p:b/></a>`)
+	actual := canonicalizeToString(t, can, NewNodeSet(doc.FindElement("//b")))
+	expected := `<p:b xmlns="urn:default" xmlns:p="urn:p" xmlns:xs="http://www.w3.org/2001/XMLSchema"></p:b>`
+	if actual != expected {
+		t.Errorf("expected:\n%s\ngot:\n%s", expected, actual)
+	}
+
+	// Multiple InclusiveNamespaces elements are rejected
+	transform = parseTestDocument(t, `<ds:Transform xmlns:ds="http://www.w3.org/2000/09/xmldsig#" xmlns:ec="http://www.w3.org/2001/10/xml-exc-c14n#">`+
+		`<ec:InclusiveNamespaces PrefixList="xs"/><ec:InclusiveNamespaces PrefixList="p"/>`+
+		`</ds:Transform>`)
+	err = NewC14N10ExcCanonicalizer().ReadXml(transform.Root())
+	if err == nil {
+		t.Error("expected multiple InclusiveNamespaces elements to be rejected")
+	}
+}
@@ -1,11 +1,11 @@
 package canonicalizer
 
 import (
+	"bytes"
 	"context"
+	"io"
 
 	"github.com/beevik/etree"
-	rhdsig "github.com/russellhaering/goxmldsig"
-	rhtree "github.com/russellhaering/goxmldsig/etreeutils"
 )
 
 type c14N10RecCanonicalizer struct {
@@ -33,22 +33,24 @@ func (can *c14N10RecCanonicalizer) GetAlgorithm() string {
 }
 
 func (can *c14N10RecCanonicalizer) Canonicalize(ctx context.Context, el *etree.Element) ([]byte, error) {
-	elementNsContext, err := rhtree.NSBuildParentContext(el)
-	if err != nil {
-		return nil, err
-	}
-	detachtedElement, err := rhtree.NSDetatch(elementNsContext, el)
-	if err != nil {
-		return nil, err
-	}
+	return can.CanonicalizeNodeSet(ctx, NewNodeSet(el))
+}
 
-	canonicalizer := can.makeInternalCanonicalizer()
-	canonicalized, err := canonicalizer.Canonicalize(detachtedElement)
+func (can *c14N10RecCanonicalizer) CanonicalizeNodeSet(ctx context.Context, nodeSet *NodeSet) ([]byte, error) {
+	var buffer bytes.Buffer
+	err := can.CanonicalizeNodeSetTo(ctx, nodeSet, &buffer)
 	if err != nil {
 		return nil, err
 	}
+	return buffer.Bytes(), nil
+}
 
-	return canonicalized, nil
+func (can *c14N10RecCanonicalizer) CanonicalizeTo(ctx context.Context, el *etree.Element, w io.Writer) error {
+	return can.CanonicalizeNodeSetTo(ctx, NewNodeSet(el), w)
+}
+
+func (can *c14N10RecCanonicalizer) CanonicalizeNodeSetTo(ctx context.Context, nodeSet *NodeSet, w io.Writer) error {
+	return canonicalizeNodeSet(w, nodeSet, c14nMode10, can.comments, "")
 }
 
 func (can *c14N10RecCanonicalizer) ReadXml(el *etree.Element) error {
@@ -58,11 +60,3 @@ func (can *c14N10RecCanonicalizer) ReadXml(el *etree.Element) error {
 func (can *c14N10RecCanonicalizer) WriteXml(el *etree.Element) error {
 	return nil
 }
-
-func (can *c14N10RecCanonicalizer) makeInternalCanonicalizer() rhdsig.Canonicalizer {
-	if can.comments {
-		return rhdsig.MakeC14N10WithCommentsCanonicalizer()
-	} else {
-		return rhdsig.MakeC14N10RecCanonicalizer()
-	}
-}
@@ -1,10 +1,11 @@
 package canonicalizer
 
 import (
+	"bytes"
 	"context"
+	"io"
 
 	"github.com/beevik/etree"
-	rhdsig "github.com/russellhaering/goxmldsig"
 )
 
 type c14N11Canonicalizer struct {
@@ -32,13 +33,24 @@ func (can *c14N11Canonicalizer) GetAlgorithm() string {
 }
 
 func (can *c14N11Canonicalizer) Canonicalize(ctx context.Context, el *etree.Element) ([]byte, error) {
-	canonicalizer := can.makeInternalCanonicalizer()
-	canonicalized, err := canonicalizer.Canonicalize(el)
+	return can.CanonicalizeNodeSet(ctx, NewNodeSet(el))
+}
+
+func (can *c14N11Canonicalizer) CanonicalizeNodeSet(ctx context.Context, nodeSet *NodeSet) ([]byte, error) {
+	var buffer bytes.Buffer
+	err := can.CanonicalizeNodeSetTo(ctx, nodeSet, &buffer)
 	if err != nil {
 		return nil, err
 	}
+	return buffer.Bytes(), nil
+}
+
+func (can *c14N11Canonicalizer) CanonicalizeTo(ctx context.Context, el *etree.Element, w io.Writer) error {
+	return can.CanonicalizeNodeSetTo(ctx, NewNodeSet(el), w)
+}
 
-	return canonicalized, nil
+func (can *c14N11Canonicalizer) CanonicalizeNodeSetTo(ctx context.Context, nodeSet *NodeSet, w io.Writer) error {
+	return canonicalizeNodeSet(w, nodeSet, c14nMode11, can.comments, "")
 }
 
 func (can *c14N11Canonicalizer) ReadXml(el *etree.Element) error {
@@ -48,11 +60,3 @@ func (can *c14N11Canonicalizer) ReadXml(el *etree.Element) error {
 func (can *c14N11Canonicalizer) WriteXml(el *etree.Element) error {
 	return nil
 }
-
-func (can *c14N11Canonicalizer) makeInternalCanonicalizer() rhdsig.Canonicalizer {
-	if can.comments {
-		return rhdsig.MakeC14N11WithCommentsCanonicalizer()
-	} else {
-		return rhdsig.MakeC14N11Canonicalizer()
-	}
-}
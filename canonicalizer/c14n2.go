@@ -0,0 +1,492 @@
+package canonicalizer
+
+import (
+	"bufio"
+	"io"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/beevik/etree"
+)
+
+var (
+	// xpathPrefixPattern matches the prefixes and axis names of an XPath expression
+	xpathPrefixPattern = regexp.MustCompile(`[A-Za-z_][\w.\-]*::?`)
+)
+
+// c14n2Writer writes the canonical form of a node-set using Canonical XML 2.0.
+// Namespace declarations are rendered for the visibly utilized prefixes, as in
+// exclusive canonicalization, and can be rewritten to sequential prefixes.
+type c14n2Writer struct {
+	w                     *bufio.Writer
+	nodeSet               *NodeSet
+	parameters            *C14N20Parameters
+	sequential            bool
+	qnameElements         map[c14n2Name]string
+	qnameAttrs            map[c14n2Name]bool
+	qnameUnqualifiedAttrs map[c14n2UnqualifiedName]bool
+}
+
+type c14n2Name struct {
+	uri   string
+	local string
+}
+
+type c14n2UnqualifiedName struct {
+	name   string
+	parent c14n2Name
+}
+
+// c14n2Scope is the namespace context while walking the tree. The output
+// namespaces map the prefixes rendered by output ancestors to their namespace,
+// or the namespaces to their rewritten prefix for sequential prefix rewriting.
+type c14n2Scope struct {
+	inScope  map[string]string
+	output   map[string]string
+	preserve bool
+}
+
+func canonicalizeNodeSet20(w io.Writer, nodeSet *NodeSet, parameters *C14N20Parameters) error {
+	if parameters == nil {
+		parameters = NewC14N20Parameters()
+	}
+	cw := &c14n2Writer{
+		w:                     bufio.NewWriter(w),
+		nodeSet:               nodeSet,
+		parameters:            parameters,
+		sequential:            parameters.PrefixRewrite == C14N20PrefixRewriteSequential,
+		qnameElements:         map[c14n2Name]string{},
+		qnameAttrs:            map[c14n2Name]bool{},
+		qnameUnqualifiedAttrs: map[c14n2UnqualifiedName]bool{},
+	}
+	for _, qnameAware := range parameters.QNameAware {
+		switch qnameAware.Kind {
+		case C14N20QNameAwareElement, C14N20QNameAwareXPathElement:
+			cw.qnameElements[c14n2Name{qnameAware.NS, qnameAware.Name}] = qnameAware.Kind
+		case C14N20QNameAwareQualifiedAttr:
+			cw.qnameAttrs[c14n2Name{qnameAware.NS, qnameAware.Name}] = true
+		case C14N20QNameAwareUnqualifiedAttr:
+			cw.qnameUnqualifiedAttrs[c14n2UnqualifiedName{qnameAware.Name, c14n2Name{qnameAware.ParentNS, qnameAware.ParentName}}] = true
+		}
+	}
+
+	scope := &c14n2Scope{
+		inScope: map[string]string{},
+		output:  map[string]string{},
+	}
+	root := nodeSet.Root()
+	if isDocumentNode(root) {
+		cw.writeDocument(root, scope)
+	} else {
+		if parent := root.Parent(); parent != nil {
+			scope.inScope = inScopeNamespaces(parent)
+		}
+		for ancestor := root.Parent(); ancestor != nil; ancestor = ancestor.Parent() {
+			if space := ancestor.SelectAttr("xml:space"); space != nil {
+				scope.preserve = space.Value == "preserve"
+				break
+			}
+		}
+		cw.writeElement(root, scope)
+	}
+	return cw.w.Flush()
+}
+
+func (cw *c14n2Writer) writeDocument(doc *etree.Element, scope *c14n2Scope) {
+	// Nodes outside the document element are separated by a line feed
+	afterDocumentElement := false
+	for _, child := range doc.Child {
+		switch t := child.(type) {
+		case *etree.Element:
+			cw.writeElement(t, scope)
+			afterDocumentElement = true
+		case *etree.Comment, *etree.ProcInst:
+			if !cw.isRendered(child) {
+				continue
+			}
+			if afterDocumentElement {
+				cw.w.WriteString("\n")
+			}
+			cw.writeToken(child)
+			if !afterDocumentElement {
+				cw.w.WriteString("\n")
+			}
+		}
+	}
+}
+
+func (cw *c14n2Writer) writeElement(el *etree.Element, scope *c14n2Scope) {
+	childScope := &c14n2Scope{
+		inScope:  scope.inScope,
+		output:   scope.output,
+		preserve: scope.preserve,
+	}
+	if declaresNamespaces(el) {
+		childScope.inScope = copyNamespaces(scope.inScope)
+		for _, attr := range el.Attr {
+			if !isNamespaceAttr(&attr) {
+				continue
+			}
+			prefix := namespaceAttrPrefix(&attr)
+			if attr.Value == "" {
+				delete(childScope.inScope, prefix)
+			} else {
+				childScope.inScope[prefix] = attr.Value
+			}
+		}
+	}
+	if space := el.SelectAttr("xml:space"); space != nil {
+		childScope.preserve = space.Value == "preserve"
+	}
+
+	name := c14n2Name{childScope.inScope[el.Space], el.Tag}
+	if el.Space == "" {
+		name.uri = childScope.inScope[""]
+	}
+	qnameKind := cw.qnameElements[name]
+
+	included := cw.nodeSet.ContainsToken(el)
+	tag := el.FullTag()
+	if included {
+		childScope.output, tag = cw.writeStartElement(el, name, qnameKind, childScope)
+	}
+
+	// Adjacent text nodes are joined before they are trimmed
+	var text strings.Builder
+	flush := func() {
+		value := text.String()
+		text.Reset()
+		if cw.parameters.TrimTextNodes && !childScope.preserve {
+			value = strings.Trim(value, " \t\r\n")
+		}
+		if value == "" {
+			return
+		}
+		if cw.sequential {
+			value = cw.rewriteContent(value, qnameKind, childScope)
+		}
+		cw.w.WriteString(textEscaper.Replace(value))
+	}
+	for _, child := range el.Child {
+		if charData, ok := child.(*etree.CharData); ok {
+			if cw.isRendered(child) {
+				text.WriteString(charData.Data)
+			}
+			continue
+		}
+		flush()
+		if childElement, ok := child.(*etree.Element); ok {
+			cw.writeElement(childElement, childScope)
+			continue
+		}
+		if cw.isRendered(child) {
+			cw.writeToken(child)
+		}
+	}
+	flush()
+
+	if included {
+		cw.w.WriteString("</")
+		cw.w.WriteString(tag)
+		cw.w.WriteString(">")
+	}
+}
+
+// writeStartElement writes the start tag and returns the output namespaces of
+// the element and its canonical tag.
+func (cw *c14n2Writer) writeStartElement(el *etree.Element, name c14n2Name, qnameKind string, scope *c14n2Scope) (map[string]string, string) {
+	attrs := make([]c14nAttr, 0, len(el.Attr))
+	qnameValues := make([]string, 0)
+	qnameAttrs := map[int]bool{}
+	for i := range el.Attr {
+		attr := &el.Attr[i]
+		if isNamespaceAttr(attr) || !cw.nodeSet.ContainsAttr(el, attr) {
+			continue
+		}
+		uri := attrNamespaceUri(attr, scope.inScope)
+		if (attr.Space != "" && cw.qnameAttrs[c14n2Name{uri, attr.Key}]) || (attr.Space == "" && cw.qnameUnqualifiedAttrs[c14n2UnqualifiedName{attr.Key, name}]) {
+			qnameAttrs[len(attrs)] = true
+			qnameValues = append(qnameValues, attr.Value)
+		}
+		attrs = append(attrs, c14nAttr{
+			uri:   uri,
+			local: attr.Key,
+			name:  attr.FullKey(),
+			value: attr.Value,
+		})
+	}
+
+	// The prefixes used in the names and the QName aware content
+	prefixes := map[string]bool{
+		el.Space: true,
+	}
+	for _, attr := range el.Attr {
+		if attr.Space != "" && attr.Space != "xmlns" && attr.Space != "xml" && cw.nodeSet.ContainsAttr(el, &attr) {
+			prefixes[attr.Space] = true
+		}
+	}
+	for _, value := range qnameValues {
+		prefixes[qnamePrefix(value)] = true
+	}
+	if qnameKind != "" {
+		content := el.Text()
+		if qnameKind == C14N20QNameAwareXPathElement {
+			for _, prefix := range xpathPrefixes(content) {
+				prefixes[prefix] = true
+			}
+		} else {
+			prefixes[qnamePrefix(content)] = true
+		}
+	}
+
+	var output map[string]string
+	var declarations [][2]string
+	tag := el.FullTag()
+	if cw.sequential {
+		output, declarations = cw.getSequentialNamespaces(prefixes, scope)
+		tag = rewriteName(name.uri, el.Tag, output)
+		for i := range attrs {
+			if attrs[i].uri != xmlNamespaceUri && attrs[i].uri != "" {
+				attrs[i].name = rewriteName(attrs[i].uri, attrs[i].local, output)
+			}
+			if qnameAttrs[i] {
+				attrs[i].value = rewriteQName(attrs[i].value, scope.inScope, output)
+			}
+		}
+	} else {
+		output, declarations = cw.getNamespaces(prefixes, scope)
+	}
+
+	cw.w.WriteString("<")
+	cw.w.WriteString(tag)
+	for _, declaration := range declarations {
+		if declaration[0] == "" {
+			cw.w.WriteString(" xmlns=\"")
+		} else {
+			cw.w.WriteString(" xmlns:" + declaration[0] + "=\"")
+		}
+		cw.w.WriteString(attrEscaper.Replace(declaration[1]))
+		cw.w.WriteString("\"")
+	}
+	sort.SliceStable(attrs, func(i, j int) bool {
+		if attrs[i].uri != attrs[j].uri {
+			return attrs[i].uri < attrs[j].uri
+		}
+		return attrs[i].local < attrs[j].local
+	})
+	for _, attr := range attrs {
+		cw.w.WriteString(" " + attr.name + "=\"")
+		cw.w.WriteString(attrEscaper.Replace(attr.value))
+		cw.w.WriteString("\"")
+	}
+	cw.w.WriteString(">")
+	return output, tag
+}
+
+// getNamespaces returns the declarations of the used prefixes that are not
+// rendered by an output ancestor, sorted by prefix.
+func (cw *c14n2Writer) getNamespaces(prefixes map[string]bool, scope *c14n2Scope) (map[string]string, [][2]string) {
+	output := scope.output
+	declared := map[string]string{}
+	for prefix := range prefixes {
+		if prefix == "xml" {
+			continue
+		}
+		if uri, ok := scope.inScope[prefix]; ok {
+			if outputUri, ok := output[prefix]; !ok || outputUri != uri {
+				declared[prefix] = uri
+			}
+		} else if prefix == "" && output[""] != "" {
+			declared[""] = ""
+		}
+	}
+	if len(declared) == 0 {
+		return output, nil
+	}
+
+	output = copyNamespaces(output)
+	declarations := make([][2]string, 0, len(declared))
+	for prefix, uri := range declared {
+		if uri == "" {
+			delete(output, prefix)
+		} else {
+			output[prefix] = uri
+		}
+		declarations = append(declarations, [2]string{prefix, uri})
+	}
+	sort.Slice(declarations, func(i, j int) bool {
+		return declarations[i][0] < declarations[j][0]
+	})
+	return output, declarations
+}
+
+// getSequentialNamespaces assigns the prefixes n0, n1, ... to the used
+// namespaces that do not have a prefix yet, in namespace order.
+func (cw *c14n2Writer) getSequentialNamespaces(prefixes map[string]bool, scope *c14n2Scope) (map[string]string, [][2]string) {
+	uris := make([]string, 0, len(prefixes))
+	for prefix := range prefixes {
+		if prefix == "xml" {
+			continue
+		}
+		if uri, ok := scope.inScope[prefix]; ok {
+			if _, ok := scope.output[uri]; !ok {
+				uris = append(uris, uri)
+			}
+		}
+	}
+	if len(uris) == 0 {
+		return scope.output, nil
+	}
+
+	sort.Strings(uris)
+	output := copyNamespaces(scope.output)
+	declarations := make([][2]string, 0, len(uris))
+	for _, uri := range uris {
+		if _, ok := output[uri]; ok {
+			continue
+		}
+		prefix := "n" + strconv.Itoa(len(output))
+		output[uri] = prefix
+		declarations = append(declarations, [2]string{prefix, uri})
+	}
+	return output, declarations
+}
+
+// rewriteContent rewrites the prefixes in QName aware element content
+func (cw *c14n2Writer) rewriteContent(value string, qnameKind string, scope *c14n2Scope) string {
+	switch qnameKind {
+	case C14N20QNameAwareElement:
+		return rewriteQName(value, scope.inScope, scope.output)
+	case C14N20QNameAwareXPathElement:
+		return rewriteXPath(value, scope.inScope, scope.output)
+	}
+	return value
+}
+
+func (cw *c14n2Writer) isRendered(token etree.Token) bool {
+	switch t := token.(type) {
+	case *etree.CharData:
+		// Text outside the document element is not part of the data model
+		if isDocumentNode(t.Parent()) {
+			return false
+		}
+	case *etree.Comment:
+		if cw.parameters.IgnoreComments {
+			return false
+		}
+	case *etree.ProcInst:
+		if t.Target == "xml" {
+			return false
+		}
+	default:
+		return false
+	}
+	return cw.nodeSet.ContainsToken(token)
+}
+
+func (cw *c14n2Writer) writeToken(token etree.Token) {
+	switch t := token.(type) {
+	case *etree.Comment:
+		cw.w.WriteString("<!--")
+		cw.w.WriteString(t.Data)
+		cw.w.WriteString("-->")
+	case *etree.ProcInst:
+		cw.w.WriteString("<?")
+		cw.w.WriteString(t.Target)
+		if t.Inst != "" {
+			cw.w.WriteString(" ")
+			cw.w.WriteString(t.Inst)
+		}
+		cw.w.WriteString("?>")
+	}
+}
+
+func rewriteName(uri string, local string, rewritten map[string]string) string {
+	if prefix, ok := rewritten[uri]; ok && uri != "" {
+		return prefix + ":" + local
+	}
+	return local
+}
+
+// qnamePrefix returns the prefix of a QName value, the empty prefix refers to
+// the default namespace.
+func qnamePrefix(value string) string {
+	prefix, _, ok := strings.Cut(strings.TrimSpace(value), ":")
+	if !ok {
+		return ""
+	}
+	return prefix
+}
+
+func rewriteQName(value string, inScope map[string]string, rewritten map[string]string) string {
+	trimmed := strings.TrimSpace(value)
+	if trimmed == "" {
+		return value
+	}
+	start := strings.Index(value, trimmed)
+	prefix, local, ok := strings.Cut(trimmed, ":")
+	if !ok {
+		prefix, local = "", trimmed
+	}
+	uri, ok := inScope[prefix]
+	if !ok {
+		return value
+	}
+	return value[:start] + rewriteName(uri, local, rewritten) + value[start+len(trimmed):]
+}
+
+// xpathPrefixes returns the prefixes used by the names of an XPath expression
+func xpathPrefixes(expression string) []string {
+	prefixes := make([]string, 0)
+	forEachXPathSegment(expression, func(segment string) string {
+		for _, match := range xpathPrefixPattern.FindAllString(segment, -1) {
+			if !strings.HasSuffix(match, "::") {
+				prefixes = append(prefixes, strings.TrimSuffix(match, ":"))
+			}
+		}
+		return segment
+	})
+	return prefixes
+}
+
+func rewriteXPath(expression string, inScope map[string]string, rewritten map[string]string) string {
+	return forEachXPathSegment(expression, func(segment string) string {
+		return xpathPrefixPattern.ReplaceAllStringFunc(segment, func(match string) string {
+			if strings.HasSuffix(match, "::") {
+				return match
+			}
+			uri, ok := inScope[strings.TrimSuffix(match, ":")]
+			if !ok {
+				return match
+			}
+			if prefix, ok := rewritten[uri]; ok {
+				return prefix + ":"
+			}
+			return match
+		})
+	})
+}
+
+// forEachXPathSegment replaces the parts of an XPath expression outside of its
+// string literals.
+func forEachXPathSegment(expression string, replace func(segment string) string) string {
+	var sb strings.Builder
+	for expression != "" {
+		index := strings.IndexAny(expression, "'\"")
+		if index < 0 {
+			sb.WriteString(replace(expression))
+			break
+		}
+		sb.WriteString(replace(expression[:index]))
+		end := strings.IndexByte(expression[index+1:], expression[index])
+		if end < 0 {
+			sb.WriteString(expression[index:])
+			break
+		}
+		sb.WriteString(expression[index : index+end+2])
+		expression = expression[index+end+2:]
+	}
+	return sb.String()
+}
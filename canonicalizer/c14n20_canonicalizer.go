@@ -0,0 +1,241 @@
+package canonicalizer
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"strings"
+
+	"github.com/beevik/etree"
+)
+
+const (
+	C14N20PrefixRewriteNone       string = "none"
+	C14N20PrefixRewriteSequential string = "sequential"
+
+	C14N20QNameAwareElement         string = "Element"
+	C14N20QNameAwareXPathElement    string = "XPathElement"
+	C14N20QNameAwareQualifiedAttr   string = "QualifiedAttr"
+	C14N20QNameAwareUnqualifiedAttr string = "UnqualifiedAttr"
+)
+
+// C14N20Parameters are the parameters of Canonical XML 2.0
+type C14N20Parameters struct {
+	IgnoreComments bool
+	TrimTextNodes  bool
+	PrefixRewrite  string
+	QNameAware     []*C14N20QNameAware
+}
+
+// C14N20QNameAware identifies element content or an attribute value that
+// contains a QName, or element content that contains an XPath expression. An
+// unqualified attribute is identified by its name and the name and namespace of
+// its parent element.
+type C14N20QNameAware struct {
+	Kind       string
+	Name       string
+	NS         string
+	ParentName string
+	ParentNS   string
+}
+
+// NewC14N20Parameters returns the default parameters: comments are ignored,
+// text nodes and prefixes are left as is.
+func NewC14N20Parameters() *C14N20Parameters {
+	return &C14N20Parameters{
+		IgnoreComments: true,
+		TrimTextNodes:  false,
+		PrefixRewrite:  C14N20PrefixRewriteNone,
+		QNameAware:     make([]*C14N20QNameAware, 0),
+	}
+}
+
+func (p *C14N20Parameters) WithIgnoreComments(ignoreComments bool) *C14N20Parameters {
+	p.IgnoreComments = ignoreComments
+	return p
+}
+
+func (p *C14N20Parameters) WithTrimTextNodes(trimTextNodes bool) *C14N20Parameters {
+	p.TrimTextNodes = trimTextNodes
+	return p
+}
+
+func (p *C14N20Parameters) WithPrefixRewrite(prefixRewrite string) *C14N20Parameters {
+	p.PrefixRewrite = prefixRewrite
+	return p
+}
+
+func (p *C14N20Parameters) WithQNameAwareElement(name string, ns string) *C14N20Parameters {
+	p.QNameAware = append(p.QNameAware, &C14N20QNameAware{Kind: C14N20QNameAwareElement, Name: name, NS: ns})
+	return p
+}
+
+func (p *C14N20Parameters) WithQNameAwareXPathElement(name string, ns string) *C14N20Parameters {
+	p.QNameAware = append(p.QNameAware, &C14N20QNameAware{Kind: C14N20QNameAwareXPathElement, Name: name, NS: ns})
+	return p
+}
+
+func (p *C14N20Parameters) WithQNameAwareQualifiedAttr(name string, ns string) *C14N20Parameters {
+	p.QNameAware = append(p.QNameAware, &C14N20QNameAware{Kind: C14N20QNameAwareQualifiedAttr, Name: name, NS: ns})
+	return p
+}
+
+func (p *C14N20Parameters) WithQNameAwareUnqualifiedAttr(name string, parentName string, parentNS string) *C14N20Parameters {
+	p.QNameAware = append(p.QNameAware, &C14N20QNameAware{Kind: C14N20QNameAwareUnqualifiedAttr, Name: name, ParentName: parentName, ParentNS: parentNS})
+	return p
+}
+
+type c14N20Canonicalizer struct {
+	parameters *C14N20Parameters
+}
+
+func NewC14N20Canonicalizer() Canonicalizer {
+	return &c14N20Canonicalizer{
+		parameters: NewC14N20Parameters(),
+	}
+}
+
+func NewC14N20CanonicalizerWithParameters(parameters *C14N20Parameters) Canonicalizer {
+	return &c14N20Canonicalizer{
+		parameters: parameters,
+	}
+}
+
+func (can *c14N20Canonicalizer) GetAlgorithm() string {
+	return C14N20NamespaceUri
+}
+
+func (can *c14N20Canonicalizer) GetParameters() *C14N20Parameters {
+	return can.parameters
+}
+
+func (can *c14N20Canonicalizer) Canonicalize(ctx context.Context, el *etree.Element) ([]byte, error) {
+	return can.CanonicalizeNodeSet(ctx, NewNodeSet(el))
+}
+
+func (can *c14N20Canonicalizer) CanonicalizeNodeSet(ctx context.Context, nodeSet *NodeSet) ([]byte, error) {
+	var buffer bytes.Buffer
+	err := can.CanonicalizeNodeSetTo(ctx, nodeSet, &buffer)
+	if err != nil {
+		return nil, err
+	}
+	return buffer.Bytes(), nil
+}
+
+func (can *c14N20Canonicalizer) CanonicalizeTo(ctx context.Context, el *etree.Element, w io.Writer) error {
+	return can.CanonicalizeNodeSetTo(ctx, NewNodeSet(el), w)
+}
+
+func (can *c14N20Canonicalizer) CanonicalizeNodeSetTo(ctx context.Context, nodeSet *NodeSet, w io.Writer) error {
+	return canonicalizeNodeSet20(w, nodeSet, can.parameters)
+}
+
+func (can *c14N20Canonicalizer) ReadXml(el *etree.Element) error {
+	parameters := NewC14N20Parameters()
+	for _, child := range el.ChildElements() {
+		if child.NamespaceURI() != C14N20NamespaceUri {
+			continue
+		}
+
+		var err error
+		switch child.Tag {
+		case "IgnoreComments":
+			parameters.IgnoreComments, err = parseC14N20Boolean(child)
+		case "TrimTextNodes":
+			parameters.TrimTextNodes, err = parseC14N20Boolean(child)
+		case "PrefixRewrite":
+			parameters.PrefixRewrite = strings.TrimSpace(child.Text())
+			if parameters.PrefixRewrite != C14N20PrefixRewriteNone && parameters.PrefixRewrite != C14N20PrefixRewriteSequential {
+				err = errors.New("unsupported c14n 2.0 PrefixRewrite value: " + parameters.PrefixRewrite)
+			}
+		case "QNameAware":
+			for _, qnameElement := range child.ChildElements() {
+				if qnameElement.NamespaceURI() != C14N20NamespaceUri {
+					continue
+				}
+				switch qnameElement.Tag {
+				case C14N20QNameAwareElement, C14N20QNameAwareXPathElement, C14N20QNameAwareQualifiedAttr, C14N20QNameAwareUnqualifiedAttr:
+					parameters.QNameAware = append(parameters.QNameAware, &C14N20QNameAware{
+						Kind:       qnameElement.Tag,
+						Name:       qnameElement.SelectAttrValue("Name", ""),
+						NS:         qnameElement.SelectAttrValue("NS", ""),
+						ParentName: qnameElement.SelectAttrValue("ParentName", ""),
+						ParentNS:   qnameElement.SelectAttrValue("ParentNS", ""),
+					})
+				}
+			}
+		}
+		if err != nil {
+			return err
+		}
+	}
+	can.parameters = parameters
+
+	return nil
+}
+
+// WriteXml only writes the parameters that differ from their defaults, the
+// namespace is declared once on the element when a parameter is written.
+func (can *c14N20Canonicalizer) WriteXml(el *etree.Element) error {
+	parameters := can.parameters
+	if parameters == nil {
+		parameters = NewC14N20Parameters()
+	}
+	prefixRewrite := parameters.PrefixRewrite
+	if prefixRewrite == "" {
+		prefixRewrite = C14N20PrefixRewriteNone
+	}
+	if parameters.IgnoreComments && !parameters.TrimTextNodes && prefixRewrite == C14N20PrefixRewriteNone && len(parameters.QNameAware) == 0 {
+		return nil
+	}
+
+	el.CreateAttr("xmlns:c14n2", C14N20NamespaceUri)
+	if !parameters.IgnoreComments {
+		createC14N20Element(el, "IgnoreComments").SetText(formatC14N20Boolean(parameters.IgnoreComments))
+	}
+	if parameters.TrimTextNodes {
+		createC14N20Element(el, "TrimTextNodes").SetText(formatC14N20Boolean(parameters.TrimTextNodes))
+	}
+	if prefixRewrite != C14N20PrefixRewriteNone {
+		createC14N20Element(el, "PrefixRewrite").SetText(prefixRewrite)
+	}
+	if len(parameters.QNameAware) > 0 {
+		qnameAwareElement := createC14N20Element(el, "QNameAware")
+		for _, qnameAware := range parameters.QNameAware {
+			qnameElement := createC14N20Element(qnameAwareElement, qnameAware.Kind)
+			qnameElement.CreateAttr("Name", qnameAware.Name)
+			if qnameAware.Kind == C14N20QNameAwareUnqualifiedAttr {
+				qnameElement.CreateAttr("ParentName", qnameAware.ParentName)
+				qnameElement.CreateAttr("ParentNS", qnameAware.ParentNS)
+			} else {
+				qnameElement.CreateAttr("NS", qnameAware.NS)
+			}
+		}
+	}
+
+	return nil
+}
+
+func createC14N20Element(el *etree.Element, tag string) *etree.Element {
+	child := el.CreateElement(tag)
+	child.Space = "c14n2"
+	return child
+}
+
+func parseC14N20Boolean(el *etree.Element) (bool, error) {
+	switch strings.TrimSpace(el.Text()) {
+	case "true", "1":
+		return true, nil
+	case "false", "0":
+		return false, nil
+	}
+	return false, errors.New("invalid c14n 2.0 boolean parameter: " + el.Tag)
+}
+
+func formatC14N20Boolean(value bool) string {
+	if value {
+		return "true"
+	}
+	return "false"
+}
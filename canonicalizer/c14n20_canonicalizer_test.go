@@ -0,0 +1,225 @@
+package canonicalizer
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/beevik/etree"
+)
+
+func Test_C14N20Canonicalizer_WriteXml(t *testing.T) {
+	tests := []struct {
+		name       string
+		parameters *C14N20Parameters
+		expected   string
+	}{
+		{
+			name:       "Defaults",
+			parameters: NewC14N20Parameters(),
+			expected:   `<Transform/>`,
+		},
+		{
+			name:       "EmptyPrefixRewrite",
+			parameters: NewC14N20Parameters().WithPrefixRewrite(""),
+			expected:   `<Transform/>`,
+		},
+		{
+			name:       "Comments",
+			parameters: NewC14N20Parameters().WithIgnoreComments(false),
+			expected:   `<Transform xmlns:c14n2="http://www.w3.org/2010/xml-c14n2"><c14n2:IgnoreComments>false</c14n2:IgnoreComments></Transform>`,
+		},
+		{
+			name: "All",
+			parameters: NewC14N20Parameters().
+				WithIgnoreComments(false).
+				WithTrimTextNodes(true).
+				WithPrefixRewrite(C14N20PrefixRewriteSequential).
+				WithQNameAwareElement("Type", "urn:a").
+				WithQNameAwareUnqualifiedAttr("type", "Value", "urn:b"),
+			expected: `<Transform xmlns:c14n2="http://www.w3.org/2010/xml-c14n2">` +
+				`<c14n2:IgnoreComments>false</c14n2:IgnoreComments>` +
+				`<c14n2:TrimTextNodes>true</c14n2:TrimTextNodes>` +
+				`<c14n2:PrefixRewrite>sequential</c14n2:PrefixRewrite>` +
+				`<c14n2:QNameAware>` +
+				`<c14n2:Element Name="Type" NS="urn:a"/>` +
+				`<c14n2:UnqualifiedAttr Name="type" ParentName="Value" ParentNS="urn:b"/>` +
+				`</c14n2:QNameAware>` +
+				`</Transform>`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc := etree.NewDocument()
+			el := doc.CreateElement("Transform")
+			err := NewC14N20CanonicalizerWithParameters(tt.parameters).WriteXml(el)
+			if err != nil {
+				t.Fatal(err)
+			}
+			actual, err := doc.WriteToString()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if actual != tt.expected {
+				t.Errorf("expected:\n%s\ngot:\n%s", tt.expected, actual)
+			}
+
+			// The written parameters are read again
+			loaded := NewC14N20Canonicalizer()
+			err = loaded.ReadXml(parseTestDocument(t, actual).Root())
+			if err != nil {
+				t.Fatal(err)
+			}
+			expected := *tt.parameters
+			if expected.PrefixRewrite == "" {
+				expected.PrefixRewrite = C14N20PrefixRewriteNone
+			}
+			if !reflect.DeepEqual(loaded.(*c14N20Canonicalizer).GetParameters(), &expected) {
+				t.Errorf("expected the parameters %+v, got %+v", expected, *loaded.(*c14N20Canonicalizer).GetParameters())
+			}
+		})
+	}
+}
+
+// The input of the QName aware test cases, in the style of the inNsContent
+// document of the Canonical XML 2.0 test cases.
+const testC14N20QNameDocument = `<a:foo xmlns:a="http://a" xmlns:b="http://b" xmlns:child="http://c" xmlns:soap-env="http://schemas.xmlsoap.org/wsdl/soap/"` +
+	` xmlns:xsd="http://www.w3.org/2001/XMLSchema" xmlns:xsi="http://www.w3.org/2001/XMLSchema-instance">` +
+	`<a:bar xsi:type="xsd:string">value1</a:bar>` +
+	`<b:bar type="child:type">xsd:string</b:bar>` +
+	`<dsig2:IncludedXPath xmlns:dsig2="http://www.w3.org/2010/xmldsig2#">/soap-env:body/child::b:foo[@att1 != "c:val" and @att2 != 'xsd:string']</dsig2:IncludedXPath>` +
+	`</a:foo>`
+
+func Test_C14N20_W3CExamples(t *testing.T) {
+	tests := []struct {
+		name       string
+		document   string
+		parameters *C14N20Parameters
+		expected   string
+	}{
+		{
+			name:       "Trim",
+			document:   testC14NWhitespaceDocument,
+			parameters: NewC14N20Parameters().WithTrimTextNodes(true),
+			expected:   `<doc><clean></clean><dirty>A   B</dirty><mixed>A<clean></clean>B<dirty>A   B</dirty>C</mixed></doc>`,
+		},
+		{
+			name:       "TrimStartAndEndTags",
+			document:   testC14NTagsDocument,
+			parameters: NewC14N20Parameters().WithTrimTextNodes(true),
+			expected: `<doc><e1></e1><e2></e2><e3 id="elem3" name="elem3"></e3><e4 id="elem4" name="elem4"></e4>` +
+				`<e5 xmlns="http://example.org" xmlns:a="http://www.w3.org" xmlns:b="http://www.ietf.org" attr="I'm" attr2="all" b:attr="sorted" a:attr="out"></e5>` +
+				`<e6><e7 xmlns="http://www.ietf.org"><e8 xmlns=""><e9 attr="default"></e9></e8></e7></e6></doc>`,
+		},
+		{
+			// Text is not trimmed within xml:space="preserve"
+			name:       "TrimPreserve",
+			document:   testC14NSubsetDocument,
+			parameters: NewC14N20Parameters().WithTrimTextNodes(true),
+			expected: `<doc xmlns="http://www.ietf.org"><e1><e2 xmlns="" xml:space="preserve">` + "\n" +
+				`         <e3 id="E3"></e3>` + "\n" +
+				`      </e2></e1></doc>`,
+		},
+		{
+			// The namespace counter continues from the prefixes of the output ancestors
+			name: "PrefixRewrite",
+			document: "<a:foo xmlns:a=\"http://a\" xmlns:b=\"http://b\" xmlns:c=\"http://c\">\n" +
+				" <b:bar/>\n" +
+				" <b:bar/>\n" +
+				" <b:bar/>\n" +
+				" <a:bar b:att1=\"val\"/>\n" +
+				"</a:foo>",
+			parameters: NewC14N20Parameters().WithPrefixRewrite(C14N20PrefixRewriteSequential),
+			expected: "<n0:foo xmlns:n0=\"http://a\">\n" +
+				" <n1:bar xmlns:n1=\"http://b\"></n1:bar>\n" +
+				" <n1:bar xmlns:n1=\"http://b\"></n1:bar>\n" +
+				" <n1:bar xmlns:n1=\"http://b\"></n1:bar>\n" +
+				" <n0:bar xmlns:n1=\"http://b\" n1:att1=\"val\"></n0:bar>\n" +
+				"</n0:foo>",
+		},
+		{
+			// New namespaces are numbered in the order of their URI
+			name:       "PrefixRewriteSorted",
+			document:   `<a:foo xmlns:a="http://z3" xmlns:b="http://z2" a:att1="val1" b:att2="val2"><c:bar xmlns:c="http://z1" c:att3="val3"/></a:foo>`,
+			parameters: NewC14N20Parameters().WithPrefixRewrite(C14N20PrefixRewriteSequential),
+			expected:   `<n1:foo xmlns:n0="http://z2" xmlns:n1="http://z3" n0:att2="val2" n1:att1="val1"><n2:bar xmlns:n2="http://z1" n2:att3="val3"></n2:bar></n1:foo>`,
+		},
+		{
+			name:       "QNameUnaware",
+			document:   testC14N20QNameDocument,
+			parameters: NewC14N20Parameters(),
+			expected: `<a:foo xmlns:a="http://a">` +
+				`<a:bar xmlns:xsi="http://www.w3.org/2001/XMLSchema-instance" xsi:type="xsd:string">value1</a:bar>` +
+				`<b:bar xmlns:b="http://b" type="child:type">xsd:string</b:bar>` +
+				`<dsig2:IncludedXPath xmlns:dsig2="http://www.w3.org/2010/xmldsig2#">/soap-env:body/child::b:foo[@att1 != "c:val" and @att2 != 'xsd:string']</dsig2:IncludedXPath>` +
+				`</a:foo>`,
+		},
+		{
+			name:       "QNameAwareElement",
+			document:   testC14N20QNameDocument,
+			parameters: NewC14N20Parameters().WithQNameAwareElement("bar", "http://b"),
+			expected: `<a:foo xmlns:a="http://a">` +
+				`<a:bar xmlns:xsi="http://www.w3.org/2001/XMLSchema-instance" xsi:type="xsd:string">value1</a:bar>` +
+				`<b:bar xmlns:b="http://b" xmlns:xsd="http://www.w3.org/2001/XMLSchema" type="child:type">xsd:string</b:bar>` +
+				`<dsig2:IncludedXPath xmlns:dsig2="http://www.w3.org/2010/xmldsig2#">/soap-env:body/child::b:foo[@att1 != "c:val" and @att2 != 'xsd:string']</dsig2:IncludedXPath>` +
+				`</a:foo>`,
+		},
+		{
+			// Axis names and string literals do not use a prefix
+			name:       "QNameAwareXPathElement",
+			document:   testC14N20QNameDocument,
+			parameters: NewC14N20Parameters().WithQNameAwareXPathElement("IncludedXPath", "http://www.w3.org/2010/xmldsig2#"),
+			expected: `<a:foo xmlns:a="http://a">` +
+				`<a:bar xmlns:xsi="http://www.w3.org/2001/XMLSchema-instance" xsi:type="xsd:string">value1</a:bar>` +
+				`<b:bar xmlns:b="http://b" type="child:type">xsd:string</b:bar>` +
+				`<dsig2:IncludedXPath xmlns:b="http://b" xmlns:dsig2="http://www.w3.org/2010/xmldsig2#" xmlns:soap-env="http://schemas.xmlsoap.org/wsdl/soap/">` +
+				`/soap-env:body/child::b:foo[@att1 != "c:val" and @att2 != 'xsd:string']</dsig2:IncludedXPath>` +
+				`</a:foo>`,
+		},
+		{
+			name:       "QNameAwareQualifiedAttr",
+			document:   testC14N20QNameDocument,
+			parameters: NewC14N20Parameters().WithQNameAwareQualifiedAttr("type", "http://www.w3.org/2001/XMLSchema-instance"),
+			expected: `<a:foo xmlns:a="http://a">` +
+				`<a:bar xmlns:xsd="http://www.w3.org/2001/XMLSchema" xmlns:xsi="http://www.w3.org/2001/XMLSchema-instance" xsi:type="xsd:string">value1</a:bar>` +
+				`<b:bar xmlns:b="http://b" type="child:type">xsd:string</b:bar>` +
+				`<dsig2:IncludedXPath xmlns:dsig2="http://www.w3.org/2010/xmldsig2#">/soap-env:body/child::b:foo[@att1 != "c:val" and @att2 != 'xsd:string']</dsig2:IncludedXPath>` +
+				`</a:foo>`,
+		},
+		{
+			name:       "QNameAwareUnqualifiedAttr",
+			document:   testC14N20QNameDocument,
+			parameters: NewC14N20Parameters().WithQNameAwareUnqualifiedAttr("type", "bar", "http://b"),
+			expected: `<a:foo xmlns:a="http://a">` +
+				`<a:bar xmlns:xsi="http://www.w3.org/2001/XMLSchema-instance" xsi:type="xsd:string">value1</a:bar>` +
+				`<b:bar xmlns:b="http://b" xmlns:child="http://c" type="child:type">xsd:string</b:bar>` +
+				`<dsig2:IncludedXPath xmlns:dsig2="http://www.w3.org/2010/xmldsig2#">/soap-env:body/child::b:foo[@att1 != "c:val" and @att2 != 'xsd:string']</dsig2:IncludedXPath>` +
+				`</a:foo>`,
+		},
+		{
+			// The prefixes in QName aware content are rewritten as well
+			name:     "PrefixRewriteQNameAware",
+			document: testC14N20QNameDocument,
+			parameters: NewC14N20Parameters().
+				WithPrefixRewrite(C14N20PrefixRewriteSequential).
+				WithQNameAwareElement("bar", "http://b").
+				WithQNameAwareXPathElement("IncludedXPath", "http://www.w3.org/2010/xmldsig2#").
+				WithQNameAwareQualifiedAttr("type", "http://www.w3.org/2001/XMLSchema-instance").
+				WithQNameAwareUnqualifiedAttr("type", "bar", "http://b"),
+			expected: `<n0:foo xmlns:n0="http://a">` +
+				`<n0:bar xmlns:n1="http://www.w3.org/2001/XMLSchema" xmlns:n2="http://www.w3.org/2001/XMLSchema-instance" n2:type="n1:string">value1</n0:bar>` +
+				`<n1:bar xmlns:n1="http://b" xmlns:n2="http://c" xmlns:n3="http://www.w3.org/2001/XMLSchema" type="n2:type">n3:string</n1:bar>` +
+				`<n3:IncludedXPath xmlns:n1="http://b" xmlns:n2="http://schemas.xmlsoap.org/wsdl/soap/" xmlns:n3="http://www.w3.org/2010/xmldsig2#">` +
+				`/n2:body/child::n1:foo[@att1 != "c:val" and @att2 != 'xsd:string']</n3:IncludedXPath>` +
+				`</n0:foo>`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc := parseTestDocument(t, tt.document)
+			actual := canonicalizeToString(t, NewC14N20CanonicalizerWithParameters(tt.parameters), NewNodeSet(&doc.Element))
+			if actual != tt.expected {
+				t.Errorf("expected:\n%s\ngot:\n%s", tt.expected, actual)
+			}
+		})
+	}
+}
@@ -0,0 +1,425 @@
+package canonicalizer
+
+import (
+	"context"
+	"testing"
+
+	"github.com/beevik/etree"
+)
+
+func parseTestDocument(t *testing.T, s string) *etree.Document {
+	t.Helper()
+	doc := etree.NewDocument()
+	err := doc.ReadFromString(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return doc
+}
+
+func canonicalizeToString(t *testing.T, can Canonicalizer, nodeSet *NodeSet) string {
+	t.Helper()
+	nodeSetCanonicalizer, ok := can.(NodeSetCanonicalizer)
+	if !ok {
+		t.Fatalf("%s does not canonicalize node-sets", can.GetAlgorithm())
+	}
+	data, err := nodeSetCanonicalizer.CanonicalizeNodeSet(context.Background(), nodeSet)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+// The input documents of the Canonical XML 1.0 examples, a DTD is not processed
+// so the attribute defaults of the examples are written in the documents.
+const (
+	testC14NPIsCommentsDocument = "<?xml version=\"1.0\"?>\n\n" +
+		"<?xml-stylesheet   href=\"doc.xsl\"\n   type=\"text/xsl\"   ?>\n\n" +
+		"<!DOCTYPE doc SYSTEM \"doc.dtd\">\n\n" +
+		"<doc>Hello, world!<!-- Comment 1 --></doc>\n\n" +
+		"<?pi-without-data     ?>\n\n" +
+		"<!-- Comment 2 -->\n\n" +
+		"<!-- Comment 3 -->"
+
+	testC14NWhitespaceDocument = "<doc>\n" +
+		"   <clean>   </clean>\n" +
+		"   <dirty>   A   B   </dirty>\n" +
+		"   <mixed>\n" +
+		"      A\n" +
+		"      <clean>   </clean>\n" +
+		"      B\n" +
+		"      <dirty>   A   B   </dirty>\n" +
+		"      C\n" +
+		"   </mixed>\n" +
+		"</doc>"
+
+	testC14NTagsDocument = "<doc>\n" +
+		"   <e1   />\n" +
+		"   <e2   ></e2>\n" +
+		"   <e3   name = \"elem3\"   id=\"elem3\"   />\n" +
+		"   <e4   name=\"elem4\"   id=\"elem4\"   ></e4>\n" +
+		"   <e5 a:attr=\"out\" b:attr=\"sorted\" attr2=\"all\" attr=\"I'm\"\n" +
+		"      xmlns:b=\"http://www.ietf.org\"\n" +
+		"      xmlns:a=\"http://www.w3.org\"\n" +
+		"      xmlns=\"http://example.org\"/>\n" +
+		"   <e6 xmlns=\"\" xmlns:a=\"http://www.w3.org\">\n" +
+		"      <e7 xmlns=\"http://www.ietf.org\">\n" +
+		"         <e8 xmlns=\"\" xmlns:a=\"http://www.w3.org\">\n" +
+		"            <e9 xmlns=\"\" xmlns:a=\"http://www.ietf.org\" attr=\"default\"/>\n" +
+		"         </e8>\n" +
+		"      </e7>\n" +
+		"   </e6>\n" +
+		"</doc>"
+
+	testC14NCharactersDocument = "<doc>\n" +
+		"   <text>First line&#x0d;&#10;Second line</text>\n" +
+		"   <value>&#x32;</value>\n" +
+		"   <compute><![CDATA[value>\"0\" && value<\"10\" ?\"valid\":\"error\"]]></compute>\n" +
+		"   <compute expr='value>\"0\" &amp;&amp; value&lt;\"10\" ?\"valid\":\"error\"'>valid</compute>\n" +
+		"   <norm attr=' &apos;   &#x20;&#13;&#xa;&#9;   &apos; '/>\n" +
+		"</doc>"
+
+	testC14NEntitiesDocument = "<!DOCTYPE doc [\n" +
+		"<!ATTLIST doc attrExtEnt ENTITY #IMPLIED>\n" +
+		"<!ENTITY ent1 \"Hello\">\n" +
+		"<!ENTITY ent2 SYSTEM \"world.txt\">\n" +
+		"<!ENTITY entExt SYSTEM \"earth.gif\" NDATA gif>\n" +
+		"<!NOTATION gif SYSTEM \"viewgif.exe\">\n" +
+		"]>\n" +
+		"<doc attrExtEnt=\"entExt\">\n" +
+		"   &ent1;, &ent2;!\n" +
+		"</doc>\n\n" +
+		"<!-- Let world.txt contain \"world\" (excluding the quotes) -->"
+
+	testC14NSubsetDocument = "<doc xmlns=\"http://www.ietf.org\" xmlns:w3c=\"http://www.w3.org\">\n" +
+		"   <e1>\n" +
+		"      <e2 xmlns=\"\" xml:space=\"preserve\">\n" +
+		"         <e3 id=\"E3\"/>\n" +
+		"      </e2>\n" +
+		"   </e1>\n" +
+		"</doc>"
+)
+
+func Test_C14N10Rec_W3CExamples(t *testing.T) {
+	tests := []struct {
+		name     string
+		document string
+		comments bool
+		expected string
+	}{
+		{
+			name:     "PIsCommentsAndOutsideOfDocumentElement",
+			document: testC14NPIsCommentsDocument,
+			expected: "<?xml-stylesheet href=\"doc.xsl\"\n   type=\"text/xsl\"   ?>\n" +
+				"<doc>Hello, world!</doc>\n" +
+				"<?pi-without-data?>",
+		},
+		{
+			name:     "PIsCommentsAndOutsideOfDocumentElementWithComments",
+			document: testC14NPIsCommentsDocument,
+			comments: true,
+			expected: "<?xml-stylesheet href=\"doc.xsl\"\n   type=\"text/xsl\"   ?>\n" +
+				"<doc>Hello, world!<!-- Comment 1 --></doc>\n" +
+				"<?pi-without-data?>\n" +
+				"<!-- Comment 2 -->\n" +
+				"<!-- Comment 3 -->",
+		},
+		{
+			name:     "WhitespaceInDocumentContent",
+			document: testC14NWhitespaceDocument,
+			expected: testC14NWhitespaceDocument,
+		},
+		{
+			name:     "StartAndEndTags",
+			document: testC14NTagsDocument,
+			expected: "<doc>\n" +
+				"   <e1></e1>\n" +
+				"   <e2></e2>\n" +
+				"   <e3 id=\"elem3\" name=\"elem3\"></e3>\n" +
+				"   <e4 id=\"elem4\" name=\"elem4\"></e4>\n" +
+				"   <e5 xmlns=\"http://example.org\" xmlns:a=\"http://www.w3.org\" xmlns:b=\"http://www.ietf.org\" attr=\"I'm\" attr2=\"all\" b:attr=\"sorted\" a:attr=\"out\"></e5>\n" +
+				"   <e6 xmlns:a=\"http://www.w3.org\">\n" +
+				"      <e7 xmlns=\"http://www.ietf.org\">\n" +
+				"         <e8 xmlns=\"\">\n" +
+				"            <e9 xmlns:a=\"http://www.ietf.org\" attr=\"default\"></e9>\n" +
+				"         </e8>\n" +
+				"      </e7>\n" +
+				"   </e6>\n" +
+				"</doc>",
+		},
+		{
+			name:     "CharacterModificationsAndCharacterReferences",
+			document: testC14NCharactersDocument,
+			expected: "<doc>\n" +
+				"   <text>First line&#xD;\nSecond line</text>\n" +
+				"   <value>2</value>\n" +
+				"   <compute>value&gt;\"0\" &amp;&amp; value&lt;\"10\" ?\"valid\":\"error\"</compute>\n" +
+				"   <compute expr=\"value>&quot;0&quot; &amp;&amp; value&lt;&quot;10&quot; ?&quot;valid&quot;:&quot;error&quot;\">valid</compute>\n" +
+				"   <norm attr=\" '    &#xD;&#xA;&#x9;   ' \"></norm>\n" +
+				"</doc>",
+		},
+		{
+			name:     "UTF8Encoding",
+			document: `<doc>&#169;</doc>`,
+			expected: "<doc>©</doc>",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			can := NewC14N10RecCanonicalizer()
+			if tt.comments {
+				can = NewC14N10RecWithCommentsCanonicalizer()
+			}
+			doc := parseTestDocument(t, tt.document)
+			actual := canonicalizeToString(t, can, NewNodeSet(&doc.Element))
+			if actual != tt.expected {
+				t.Errorf("expected:\n%s\ngot:\n%s", tt.expected, actual)
+			}
+		})
+	}
+}
+
+func Test_C14N10Rec_EntityReferences(t *testing.T) {
+	// The DTD is not read, the internal and external entities are provided
+	doc := etree.NewDocument()
+	doc.ReadSettings.Entity = map[string]string{"ent1": "Hello", "ent2": "world"}
+	err := doc.ReadFromString(testC14NEntitiesDocument)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := "<doc attrExtEnt=\"entExt\">\n   Hello, world!\n</doc>"
+	actual := canonicalizeToString(t, NewC14N10RecCanonicalizer(), NewNodeSet(&doc.Element))
+	if actual != expected {
+		t.Errorf("expected:\n%s\ngot:\n%s", expected, actual)
+	}
+}
+
+func Test_C14N10Rec_DocumentSubset(t *testing.T) {
+	// The node-set of the expression
+	// (//. | //@* | //namespace::*)
+	// [
+	//    self::ietf:e1 or (parent::ietf:e1 and not(self::text() or self::e2))
+	//    or
+	//    count(id("E3")|ancestor-or-self::node()) = count(ancestor-or-self::node())
+	// ]
+	doc := parseTestDocument(t, testC14NSubsetDocument)
+	e1 := doc.FindElement("//e1")
+	e3 := doc.FindElement("//e3")
+	nodeSet := NewNodeSet(&doc.Element).Filter(func(token etree.Token) bool {
+		return token == e1 || IsDescendantOrSelf(token, e3)
+	}).FilterAttrs(func(el *etree.Element, attr *etree.Attr) bool {
+		return IsDescendantOrSelf(el, e3)
+	}).FilterNamespaces(func(el *etree.Element, prefix string) bool {
+		return el == e1 || IsDescendantOrSelf(el, e3)
+	})
+
+	// The xml:space attribute of the omitted e2 element is inherited by e3
+	expected := `<e1 xmlns="http://www.ietf.org" xmlns:w3c="http://www.w3.org"><e3 xmlns="" id="E3" xml:space="preserve"></e3></e1>`
+	actual := canonicalizeToString(t, NewC14N10RecCanonicalizer(), nodeSet)
+	if actual != expected {
+		t.Errorf("expected:\n%s\ngot:\n%s", expected, actual)
+	}
+}
+
+func Test_C14N_XmlAttributeInheritance(t *testing.T) {
+	document := `<doc xml:base="http://example.org/a/" xml:lang="en" xml:id="doc">` +
+		`<skip xml:base="b/" xml:id="skip">` +
+		`<e xml:base="c/d">text</e>` +
+		`</skip>` +
+		`</doc>`
+
+	tests := []struct {
+		name     string
+		can      Canonicalizer
+		expected string
+	}{
+		{
+			// The nearest xml attributes of the omitted ancestors are inherited
+			name:     "C14N10",
+			can:      NewC14N10RecCanonicalizer(),
+			expected: `<e xml:base="c/d" xml:id="skip" xml:lang="en">text</e>`,
+		},
+		{
+			// The xml:base values of the omitted ancestors are joined and xml:id
+			// is not inherited
+			name:     "C14N11",
+			can:      NewC14N11Canonicalizer(),
+			expected: `<e xml:base="http://example.org/a/b/c/d" xml:lang="en">text</e>`,
+		},
+		{
+			name:     "C14N10Exc",
+			can:      NewC14N10ExcCanonicalizer(),
+			expected: `<e xml:base="c/d">text</e>`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc := parseTestDocument(t, document)
+			e := doc.FindElement("//e")
+			nodeSet := NewNodeSet(&doc.Element).Filter(func(token etree.Token) bool {
+				return IsDescendantOrSelf(token, e)
+			})
+			actual := canonicalizeToString(t, tt.can, nodeSet)
+			if actual != tt.expected {
+				t.Errorf("expected:\n%s\ngot:\n%s", tt.expected, actual)
+			}
+		})
+	}
+}
+
+func Test_C14N11_XmlBaseOfIncludedParent(t *testing.T) {
+	// Only the xml:base values of the ancestors omitted between the element and
+	// its nearest output ancestor are joined
+	doc := parseTestDocument(t, `<doc xml:base="http://example.org/a/"><skip xml:base="b/"><e xml:base="c/">text</e></skip></doc>`)
+	skip := doc.FindElement("//skip")
+	nodeSet := NewNodeSet(&doc.Element).Filter(func(token etree.Token) bool {
+		return token != skip
+	})
+
+	expected := `<doc xml:base="http://example.org/a/"><e xml:base="b/c/">text</e></doc>`
+	actual := canonicalizeToString(t, NewC14N11Canonicalizer(), nodeSet)
+	if actual != expected {
+		t.Errorf("expected:\n%s\ngot:\n%s", expected, actual)
+	}
+}
+
+func Test_JoinUriReferences(t *testing.T) {
+	tests := []struct {
+		base      string
+		reference string
+		expected  string
+	}{
+		{"", "b/", "b/"},
+		{"http://example.org/a/", "", "http://example.org/a/"},
+		{"http://example.org/a/", "b/", "http://example.org/a/b/"},
+		{"http://example.org/a/b", "c", "http://example.org/a/c"},
+		{"http://example.org/a/b/", "../c", "http://example.org/a/c"},
+		{"http://example.org/a/", "/c", "http://example.org/c"},
+		{"http://example.org/a/", "http://example.com/", "http://example.com/"},
+		{"a/", "b/", "a/b/"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.base+"+"+tt.reference, func(t *testing.T) {
+			actual := joinUriReferences(tt.base, tt.reference)
+			if actual != tt.expected {
+				t.Errorf("expected %q, got %q", tt.expected, actual)
+			}
+		})
+	}
+}
+
+func Test_C14N10Exc_W3CExamples(t *testing.T) {
+	// The examples of section 2.2 of Exclusive XML Canonicalization
+	documents := []string{
+		"<n0:local xmlns:n0=\"foo:bar\" xmlns:n3=\"ftp://example.org\">\n" +
+			"  <n1:elem2 xmlns:n1=\"http://example.net\" xml:lang=\"en\">\n" +
+			"    <n3:stuff xmlns:n3=\"ftp://example.org\"/>\n" +
+			"  </n1:elem2>\n" +
+			"</n0:local>",
+		"<n2:pdu xmlns:n1=\"http://example.com\" xmlns:n2=\"http://foo.example\" xml:lang=\"fr\" xml:space=\"retain\">\n" +
+			"  <n1:elem2 xmlns:n1=\"http://example.net\" xml:lang=\"en\">\n" +
+			"    <n3:stuff xmlns:n3=\"ftp://example.org\"/>\n" +
+			"  </n1:elem2>\n" +
+			"</n2:pdu>",
+	}
+
+	tests := []struct {
+		name     string
+		document int
+		can      Canonicalizer
+		expected string
+	}{
+		{
+			name:     "Inclusive1",
+			document: 0,
+			can:      NewC14N10RecCanonicalizer(),
+			expected: "<n1:elem2 xmlns:n0=\"foo:bar\" xmlns:n1=\"http://example.net\" xmlns:n3=\"ftp://example.org\" xml:lang=\"en\">\n" +
+				"    <n3:stuff></n3:stuff>\n" +
+				"  </n1:elem2>",
+		},
+		{
+			name:     "Inclusive2",
+			document: 1,
+			can:      NewC14N10RecCanonicalizer(),
+			expected: "<n1:elem2 xmlns:n1=\"http://example.net\" xmlns:n2=\"http://foo.example\" xml:lang=\"en\" xml:space=\"retain\">\n" +
+				"    <n3:stuff xmlns:n3=\"ftp://example.org\"></n3:stuff>\n" +
+				"  </n1:elem2>",
+		},
+		{
+			name:     "Exclusive1",
+			document: 0,
+			can:      NewC14N10ExcCanonicalizer(),
+			expected: "<n1:elem2 xmlns:n1=\"http://example.net\" xml:lang=\"en\">\n" +
+				"    <n3:stuff xmlns:n3=\"ftp://example.org\"></n3:stuff>\n" +
+				"  </n1:elem2>",
+		},
+		{
+			name:     "Exclusive2",
+			document: 1,
+			can:      NewC14N10ExcCanonicalizer(),
+			expected: "<n1:elem2 xmlns:n1=\"http://example.net\" xml:lang=\"en\">\n" +
+				"    <n3:stuff xmlns:n3=\"ftp://example.org\"></n3:stuff>\n" +
+				"  </n1:elem2>",
+		},
+		{
+			// The prefixes of the InclusiveNamespaces PrefixList are rendered as
+			// in Canonical XML
+			name:     "InclusiveNamespacesPrefixList",
+			document: 0,
+			can:      NewC14N10ExcCanonicalizerWithPrefixList("n0", "n3"),
+			expected: "<n1:elem2 xmlns:n0=\"foo:bar\" xmlns:n1=\"http://example.net\" xmlns:n3=\"ftp://example.org\" xml:lang=\"en\">\n" +
+				"    <n3:stuff></n3:stuff>\n" +
+				"  </n1:elem2>",
+		},
+		{
+			name:     "InclusiveNamespacesUnknownPrefix",
+			document: 1,
+			can:      NewC14N10ExcCanonicalizerWithPrefixList("n0"),
+			expected: "<n1:elem2 xmlns:n1=\"http://example.net\" xml:lang=\"en\">\n" +
+				"    <n3:stuff xmlns:n3=\"ftp://example.org\"></n3:stuff>\n" +
+				"  </n1:elem2>",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc := parseTestDocument(t, documents[tt.document])
+			elem2 := doc.FindElement("//elem2")
+			actual := canonicalizeToString(t, tt.can, NewNodeSet(elem2))
+			if actual != tt.expected {
+				t.Errorf("expected:\n%s\ngot:\n%s", tt.expected, actual)
+			}
+		})
+	}
+}
+
+func Test_C14N10Exc_InclusiveNamespacesDefault(t *testing.T) {
+	document := `<p:a xmlns="urn:default" xmlns:p="urn:p" xmlns:q="urn:q"><p:b q:attr="1"/></p:a>`
+
+	tests := []struct {
+		name     string
+		can      Canonicalizer
+		expected string
+	}{
+		{
+			name:     "Exclusive",
+			can:      NewC14N10ExcCanonicalizer(),
+			expected: `<p:b xmlns:p="urn:p" xmlns:q="urn:q" q:attr="1"></p:b>`,
+		},
+		{
+			name:     "PrefixListDefault",
+			can:      NewC14N10ExcCanonicalizerWithPrefixList("#default"),
+			expected: `<p:b xmlns="urn:default" xmlns:p="urn:p" xmlns:q="urn:q" q:attr="1"></p:b>`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc := parseTestDocument(t, document)
+			b := doc.FindElement("//b")
+			actual := canonicalizeToString(t, tt.can, NewNodeSet(b))
+			if actual != tt.expected {
+				t.Errorf("expected:\n%s\ngot:\n%s", tt.expected, actual)
+			}
+		})
+	}
+}
@@ -3,6 +3,8 @@ package canonicalizer
 import (
 	"context"
 	"fmt"
+	"io"
+	"sync"
 
 	"github.com/beevik/etree"
 )
@@ -16,6 +18,7 @@ const (
 	C14N10ExcWithCommentsNamespaceUri string = "http://www.w3.org/2001/10/xml-exc-c14n#WithComments"
 	C14N11NamespaceUri                string = "http://www.w3.org/2006/12/xml-c14n11"
 	C14N11WithCommentsNamespaceUri    string = "http://www.w3.org/2006/12/xml-c14n11#WithComments"
+	C14N20NamespaceUri                string = "http://www.w3.org/2010/xml-c14n2"
 )
 
 var (
@@ -26,7 +29,9 @@ var (
 		C14N10ExcWithCommentsNamespaceUri: NewC14N10ExcWithCommentsCanonicalizer,
 		C14N11NamespaceUri:                NewC14N11Canonicalizer,
 		C14N11WithCommentsNamespaceUri:    NewC14N11WithCommentsCanonicalizer,
+		C14N20NamespaceUri:                NewC14N20Canonicalizer,
 	}
+	registeredCanonicalizersLock sync.RWMutex
 )
 
 type Canonicalizer interface {
@@ -36,12 +41,34 @@ type Canonicalizer interface {
 	WriteXml(el *etree.Element) error
 }
 
+// NodeSetCanonicalizer is implemented by canonicalizers that canonicalize a
+// document subset instead of an element subtree.
+type NodeSetCanonicalizer interface {
+	CanonicalizeNodeSet(ctx context.Context, nodeSet *NodeSet) ([]byte, error)
+}
+
+// StreamCanonicalizer is implemented by canonicalizers that write the canonical
+// form to a writer, so large documents do not have to be buffered.
+type StreamCanonicalizer interface {
+	CanonicalizeTo(ctx context.Context, el *etree.Element, w io.Writer) error
+	CanonicalizeNodeSetTo(ctx context.Context, nodeSet *NodeSet, w io.Writer) error
+}
+
 func RegisterCanonicalizer(uri string, method CreateCanonicalizerMethod) {
+	registeredCanonicalizersLock.Lock()
+	defer registeredCanonicalizersLock.Unlock()
 	registeredCanonicalizers[uri] = method
 }
 
+func GetCanonicalizer(uri string) (Canonicalizer, error) {
+	if method, ok := getRegisteredCanonicalizer(uri); ok {
+		return method(), nil
+	}
+	return nil, fmt.Errorf("no canonicalizer registered for URI: %s", uri)
+}
+
 func LoadCanonicalizer(uri string, el *etree.Element) (Canonicalizer, error) {
-	if method, ok := registeredCanonicalizers[uri]; ok {
+	if method, ok := getRegisteredCanonicalizer(uri); ok {
 		m := method()
 		err := m.ReadXml(el)
 		if err != nil {
@@ -51,3 +78,10 @@ func LoadCanonicalizer(uri string, el *etree.Element) (Canonicalizer, error) {
 	}
 	return nil, fmt.Errorf("no canonicalizer registered for URI: %s", uri)
 }
+
+func getRegisteredCanonicalizer(uri string) (CreateCanonicalizerMethod, bool) {
+	registeredCanonicalizersLock.RLock()
+	defer registeredCanonicalizersLock.RUnlock()
+	method, ok := registeredCanonicalizers[uri]
+	return method, ok
+}
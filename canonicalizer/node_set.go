@@ -0,0 +1,235 @@
+package canonicalizer
+
+import (
+	"errors"
+	"sort"
+
+	"github.com/beevik/etree"
+)
+
+type TokenFilter func(token etree.Token) bool
+
+type AttrFilter func(el *etree.Element, attr *etree.Attr) bool
+
+type NamespaceFilter func(el *etree.Element, prefix string) bool
+
+// NodeSet is an XPath node-set over the subtree of its root element, which is
+// either an element or a document. Namespace nodes are identified by their
+// element and prefix, the default namespace has an empty prefix.
+type NodeSet struct {
+	root             *etree.Element
+	tokenFilters     []TokenFilter
+	attrFilters      []AttrFilter
+	namespaceFilters []NamespaceFilter
+}
+
+func NewNodeSet(root *etree.Element) *NodeSet {
+	return &NodeSet{
+		root: root,
+	}
+}
+
+func (ns *NodeSet) Root() *etree.Element {
+	return ns.root
+}
+
+func (ns *NodeSet) Filter(filter TokenFilter) *NodeSet {
+	result := ns.clone()
+	result.tokenFilters = append(result.tokenFilters, filter)
+	return result
+}
+
+func (ns *NodeSet) FilterAttrs(filter AttrFilter) *NodeSet {
+	result := ns.clone()
+	result.attrFilters = append(result.attrFilters, filter)
+	return result
+}
+
+func (ns *NodeSet) FilterNamespaces(filter NamespaceFilter) *NodeSet {
+	result := ns.clone()
+	result.namespaceFilters = append(result.namespaceFilters, filter)
+	return result
+}
+
+func (ns *NodeSet) WithoutComments() *NodeSet {
+	return ns.Filter(func(token etree.Token) bool {
+		_, ok := token.(*etree.Comment)
+		return !ok
+	})
+}
+
+func (ns *NodeSet) WithoutSubtree(el *etree.Element) *NodeSet {
+	return ns.Filter(func(token etree.Token) bool {
+		return !IsDescendantOrSelf(token, el)
+	}).FilterAttrs(func(owner *etree.Element, attr *etree.Attr) bool {
+		return !IsDescendantOrSelf(owner, el)
+	})
+}
+
+func (ns *NodeSet) ContainsToken(token etree.Token) bool {
+	if !IsDescendantOrSelf(token, ns.root) {
+		return false
+	}
+	for _, filter := range ns.tokenFilters {
+		if !filter(token) {
+			return false
+		}
+	}
+	return true
+}
+
+func (ns *NodeSet) ContainsAttr(el *etree.Element, attr *etree.Attr) bool {
+	if !IsDescendantOrSelf(el, ns.root) {
+		return false
+	}
+	for _, filter := range ns.attrFilters {
+		if !filter(el, attr) {
+			return false
+		}
+	}
+	return true
+}
+
+func (ns *NodeSet) ContainsNamespace(el *etree.Element, prefix string) bool {
+	if !IsDescendantOrSelf(el, ns.root) {
+		return false
+	}
+	for _, filter := range ns.namespaceFilters {
+		if !filter(el, prefix) {
+			return false
+		}
+	}
+	return true
+}
+
+// Element returns a detached copy of the node-set. This only succeeds when the
+// node-set is a subtree of the root element with nodes removed from it. For a
+// document the copy is that of the document element.
+func (ns *NodeSet) Element() (*etree.Element, error) {
+	root := ns.root
+	if isDocumentNode(root) {
+		root = nil
+		if elements := ns.root.ChildElements(); len(elements) > 0 {
+			root = elements[0]
+		}
+	}
+	if root == nil || !ns.ContainsToken(root) {
+		return nil, errors.New("node-set does not contain its root element")
+	}
+
+	detachedElement := DetachElement(root)
+	err := ns.filterElement(root, detachedElement)
+	if err != nil {
+		return nil, err
+	}
+	return detachedElement, nil
+}
+
+func (ns *NodeSet) filterElement(source *etree.Element, target *etree.Element) error {
+	for i := range source.Attr {
+		attr := &source.Attr[i]
+		if isNamespaceAttr(attr) || ns.ContainsAttr(source, attr) {
+			continue
+		}
+		target.RemoveAttr(attr.FullKey())
+	}
+
+	removed := make([]etree.Token, 0)
+	for i, child := range source.Child {
+		if ns.ContainsToken(child) {
+			if childElement, ok := child.(*etree.Element); ok {
+				err := ns.filterElement(childElement, target.Child[i].(*etree.Element))
+				if err != nil {
+					return err
+				}
+			}
+			continue
+		}
+		if childElement, ok := child.(*etree.Element); ok && ns.containsAny(childElement) {
+			return errors.New("node-set cannot be represented as an element subtree")
+		}
+		removed = append(removed, target.Child[i])
+	}
+	for _, token := range removed {
+		target.RemoveChild(token)
+	}
+
+	return nil
+}
+
+func (ns *NodeSet) containsAny(el *etree.Element) bool {
+	for i := range el.Attr {
+		if !isNamespaceAttr(&el.Attr[i]) && ns.ContainsAttr(el, &el.Attr[i]) {
+			return true
+		}
+	}
+	for _, child := range el.Child {
+		if ns.ContainsToken(child) {
+			return true
+		}
+		if childElement, ok := child.(*etree.Element); ok && ns.containsAny(childElement) {
+			return true
+		}
+	}
+	return false
+}
+
+func (ns *NodeSet) clone() *NodeSet {
+	return &NodeSet{
+		root:             ns.root,
+		tokenFilters:     append([]TokenFilter{}, ns.tokenFilters...),
+		attrFilters:      append([]AttrFilter{}, ns.attrFilters...),
+		namespaceFilters: append([]NamespaceFilter{}, ns.namespaceFilters...),
+	}
+}
+
+// DetachElement returns a copy of the element that declares the namespaces in
+// scope of the element.
+func DetachElement(el *etree.Element) *etree.Element {
+	detachedElement := el.Copy()
+	if el.Parent() == nil {
+		return detachedElement
+	}
+
+	declared := map[string]bool{}
+	for i := range el.Attr {
+		if isNamespaceAttr(&el.Attr[i]) {
+			declared[namespaceAttrPrefix(&el.Attr[i])] = true
+		}
+	}
+	namespaces := inScopeNamespaces(el.Parent())
+	prefixes := make([]string, 0, len(namespaces))
+	for prefix := range namespaces {
+		if !declared[prefix] {
+			prefixes = append(prefixes, prefix)
+		}
+	}
+	sort.Strings(prefixes)
+	for _, prefix := range prefixes {
+		if prefix == "" {
+			detachedElement.CreateAttr("xmlns", namespaces[prefix])
+		} else {
+			detachedElement.CreateAttr("xmlns:"+prefix, namespaces[prefix])
+		}
+	}
+	return detachedElement
+}
+
+func IsDescendantOrSelf(token etree.Token, el *etree.Element) bool {
+	if token == nil || el == nil {
+		return false
+	}
+	if tokenElement, ok := token.(*etree.Element); ok && tokenElement == el {
+		return true
+	}
+	for parent := token.Parent(); parent != nil; parent = parent.Parent() {
+		if parent == el {
+			return true
+		}
+	}
+	return false
+}
+
+func isNamespaceAttr(attr *etree.Attr) bool {
+	return attr.Space == "xmlns" || (attr.Space == "" && attr.Key == "xmlns")
+}
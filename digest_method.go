@@ -16,7 +16,16 @@ func newDigestMethod(reference *Reference) *DigestMethod {
 	}
 }
 
+func NewDigestMethod(uri string) *DigestMethod {
+	digestMethod := newDigestMethod(nil)
+	digestMethod.Algorithm = uri
+	return digestMethod
+}
+
 func (xml *DigestMethod) root() *SignedXml {
+	if xml.reference == nil {
+		return nil
+	}
 	return xml.reference.root()
 }
 
@@ -0,0 +1,85 @@
+package xmldsig
+
+import (
+	"crypto"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha3"
+	"crypto/sha512"
+	"hash"
+	"sync"
+)
+
+type CreateHashMethod func() hash.Hash
+
+const (
+	DigestMethod_SHA1     string = "http://www.w3.org/2000/09/xmldsig#sha1"
+	DigestMethod_SHA224   string = "http://www.w3.org/2001/04/xmldsig-more#sha224"
+	DigestMethod_SHA256   string = "http://www.w3.org/2001/04/xmlenc#sha256"
+	DigestMethod_SHA384   string = "http://www.w3.org/2001/04/xmldsig-more#sha384"
+	DigestMethod_SHA512   string = "http://www.w3.org/2001/04/xmlenc#sha512"
+	DigestMethod_SHA3_224 string = "http://www.w3.org/2007/05/xmldsig-more#sha3-224"
+	DigestMethod_SHA3_256 string = "http://www.w3.org/2007/05/xmldsig-more#sha3-256"
+	DigestMethod_SHA3_384 string = "http://www.w3.org/2007/05/xmldsig-more#sha3-384"
+	DigestMethod_SHA3_512 string = "http://www.w3.org/2007/05/xmldsig-more#sha3-512"
+)
+
+type digestAlgorithm struct {
+	hash   crypto.Hash
+	create CreateHashMethod
+}
+
+var (
+	registeredDigestMethods map[string]*digestAlgorithm = map[string]*digestAlgorithm{
+		DigestMethod_SHA1:     {hash: crypto.SHA1, create: sha1.New},
+		DigestMethod_SHA224:   {hash: crypto.SHA224, create: sha256.New224},
+		DigestMethod_SHA256:   {hash: crypto.SHA256, create: sha256.New},
+		DigestMethod_SHA384:   {hash: crypto.SHA384, create: sha512.New384},
+		DigestMethod_SHA512:   {hash: crypto.SHA512, create: sha512.New},
+		DigestMethod_SHA3_224: {hash: crypto.SHA3_224, create: func() hash.Hash { return sha3.New224() }},
+		DigestMethod_SHA3_256: {hash: crypto.SHA3_256, create: func() hash.Hash { return sha3.New256() }},
+		DigestMethod_SHA3_384: {hash: crypto.SHA3_384, create: func() hash.Hash { return sha3.New384() }},
+		DigestMethod_SHA3_512: {hash: crypto.SHA3_512, create: func() hash.Hash { return sha3.New512() }},
+	}
+	registeredDigestMethodsLock sync.RWMutex
+)
+
+// RegisterDigestMethod registers a digest for references only, a digest without
+// a crypto.Hash can not be used to sign or in RSAPSSParams. Use
+// RegisterDigestHash to register a digest for all uses.
+func RegisterDigestMethod(uri string, method CreateHashMethod) {
+	registeredDigestMethodsLock.Lock()
+	defer registeredDigestMethodsLock.Unlock()
+	registeredDigestMethods[uri] = &digestAlgorithm{
+		create: method,
+	}
+}
+
+// RegisterDigestHash registers a digest that is implemented by a crypto.Hash,
+// the hash must be available.
+func RegisterDigestHash(uri string, hash crypto.Hash) {
+	registeredDigestMethodsLock.Lock()
+	defer registeredDigestMethodsLock.Unlock()
+	registeredDigestMethods[uri] = &digestAlgorithm{
+		hash:   hash,
+		create: hash.New,
+	}
+}
+
+func GetDigestMethod(uri string) (CreateHashMethod, error) {
+	registeredDigestMethodsLock.RLock()
+	defer registeredDigestMethodsLock.RUnlock()
+	if algorithm, ok := registeredDigestMethods[uri]; ok {
+		return algorithm.create, nil
+	}
+	return nil, ErrInvalidDigestMethod
+}
+
+func getDigestHash(uri string) (crypto.Hash, error) {
+	registeredDigestMethodsLock.RLock()
+	defer registeredDigestMethodsLock.RUnlock()
+	if algorithm, ok := registeredDigestMethods[uri]; ok && algorithm.hash != 0 {
+		return algorithm.hash, nil
+	}
+	return 0, ErrInvalidDigestMethod
+}
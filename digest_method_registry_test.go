@@ -0,0 +1,105 @@
+package xmldsig
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha512"
+	"sync"
+	"testing"
+)
+
+func Test_RegisterDigestHash(t *testing.T) {
+	const digestUri = "urn:test:sha512-256"
+	const referenceDigestUri = "urn:test:sha512-256-reference"
+	defer func() {
+		registeredDigestMethodsLock.Lock()
+		delete(registeredDigestMethods, digestUri)
+		delete(registeredDigestMethods, referenceDigestUri)
+		registeredDigestMethodsLock.Unlock()
+	}()
+	RegisterDigestHash(digestUri, crypto.SHA512_256)
+	RegisterDigestMethod(referenceDigestUri, sha512.New512_256)
+
+	// A digest registered without a crypto.Hash is only used for references
+	_, err := getDigestHash(referenceDigestUri)
+	if err != ErrInvalidDigestMethod {
+		t.Errorf("expected ErrInvalidDigestMethod, got %v", err)
+	}
+	hash, err := getDigestHash(digestUri)
+	if err != nil || hash != crypto.SHA512_256 {
+		t.Errorf("expected the registered hash, got %v, %v", hash, err)
+	}
+
+	ctx := context.Background()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	doc := parseTestDocument(t, testDocument)
+	signedXml := NewSignedXml(doc)
+	signedXml.GetSignature().SignedInfo.WithSignatureMethod(NewSignatureMethod(SignatureMethod_RSA_PSS).WithRSAPSSParams(digestUri, 0))
+	_, err = signedXml.AddReference("#line1", digestUri)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = signedXml.AddReference("#line1", referenceDigestUri)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = signedXml.ComputeSignature(ctx, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = signedXml.ValidateSignatureWithKey(ctx, key.Public())
+	if err != nil {
+		t.Errorf("validate: %v", err)
+	}
+
+	signedXml.GetSignature().SignedInfo.WithSignatureMethod(NewSignatureMethod(SignatureMethod_RSA_PSS).WithRSAPSSParams(referenceDigestUri, 0))
+	err = signedXml.ComputeSignature(ctx, key)
+	if err != ErrInvalidDigestMethod {
+		t.Errorf("expected ErrInvalidDigestMethod for RSAPSSParams, got %v", err)
+	}
+}
+
+func Test_Registries_Concurrent(t *testing.T) {
+	const uri = "urn:test:concurrent"
+	defer func() {
+		registeredDigestMethodsLock.Lock()
+		delete(registeredDigestMethods, uri)
+		registeredDigestMethodsLock.Unlock()
+		registeredSignatureMethodsLock.Lock()
+		delete(registeredSignatureMethods, uri)
+		registeredSignatureMethodsLock.Unlock()
+	}()
+	algorithm, err := GetSignatureMethod(SignatureMethod_RSA_SHA256)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			RegisterDigestHash(uri, crypto.SHA256)
+			RegisterSignatureMethod(uri, algorithm.Sign, algorithm.Verify)
+		}()
+		go func() {
+			defer wg.Done()
+			GetDigestMethod(uri)
+			getDigestHash(uri)
+			GetSignatureMethod(uri)
+		}()
+	}
+	wg.Wait()
+
+	if _, err := GetDigestMethod(uri); err != nil {
+		t.Errorf("expected the digest to be registered: %v", err)
+	}
+	if _, err := GetSignatureMethod(uri); err != nil {
+		t.Errorf("expected the signature method to be registered: %v", err)
+	}
+}
@@ -1,13 +1,5 @@
 module github.com/deb-ict/go-xmldsig
 
-go 1.23.1
+go 1.24.0
 
-require (
-	github.com/beevik/etree v1.5.0
-	github.com/russellhaering/goxmldsig v1.4.0
-)
-
-require (
-	github.com/deb-ict/go-xml v1.0.2 // indirect
-	github.com/jonboulle/clockwork v0.5.0 // indirect
-)
+require github.com/beevik/etree v1.5.0
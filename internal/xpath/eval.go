@@ -0,0 +1,509 @@
+package xpath
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+type evalContext struct {
+	node     Node
+	position int
+	size     int
+}
+
+type evaluator struct {
+	context *Context
+}
+
+func (ev *evaluator) eval(e expr, ctx evalContext) (interface{}, error) {
+	switch e := e.(type) {
+	case *literalExpr:
+		return e.value, nil
+	case *numberExpr:
+		return e.value, nil
+	case *variableExpr:
+		return nil, fmt.Errorf("xpath: variable reference not supported: $%s", e.name)
+	case *negateExpr:
+		value, err := ev.eval(e.operand, ctx)
+		if err != nil {
+			return nil, err
+		}
+		return -toNumber(value), nil
+	case *binaryExpr:
+		return ev.evalBinary(e, ctx)
+	case *functionExpr:
+		return ev.evalFunction(e, ctx)
+	case *filterExpr:
+		value, err := ev.eval(e.primary, ctx)
+		if err != nil {
+			return nil, err
+		}
+		nodes, ok := value.([]Node)
+		if !ok {
+			return nil, fmt.Errorf("xpath: predicate applied to a non node-set value")
+		}
+		for _, predicate := range e.predicates {
+			nodes, err = ev.filter(nodes, predicate)
+			if err != nil {
+				return nil, err
+			}
+		}
+		return nodes, nil
+	case *pathExpr:
+		return ev.evalPath(e, ctx)
+	}
+	return nil, fmt.Errorf("xpath: unsupported expression")
+}
+
+func (ev *evaluator) evalBinary(e *binaryExpr, ctx evalContext) (interface{}, error) {
+	left, err := ev.eval(e.left, ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// Short circuit the boolean operators
+	switch e.op {
+	case "or":
+		if toBoolean(left) {
+			return true, nil
+		}
+		right, err := ev.eval(e.right, ctx)
+		if err != nil {
+			return nil, err
+		}
+		return toBoolean(right), nil
+	case "and":
+		if !toBoolean(left) {
+			return false, nil
+		}
+		right, err := ev.eval(e.right, ctx)
+		if err != nil {
+			return nil, err
+		}
+		return toBoolean(right), nil
+	}
+
+	right, err := ev.eval(e.right, ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	switch e.op {
+	case "|":
+		leftNodes, leftOk := left.([]Node)
+		rightNodes, rightOk := right.([]Node)
+		if !leftOk || !rightOk {
+			return nil, fmt.Errorf("xpath: union of non node-set values")
+		}
+		return ev.context.DocumentOrder(append(append([]Node{}, leftNodes...), rightNodes...)), nil
+	case "=", "!=", "<", "<=", ">", ">=":
+		return compare(e.op, left, right), nil
+	case "+":
+		return toNumber(left) + toNumber(right), nil
+	case "-":
+		return toNumber(left) - toNumber(right), nil
+	case "*":
+		return toNumber(left) * toNumber(right), nil
+	case "div":
+		return toNumber(left) / toNumber(right), nil
+	case "mod":
+		return math.Mod(toNumber(left), toNumber(right)), nil
+	}
+	return nil, fmt.Errorf("xpath: unsupported operator: %s", e.op)
+}
+
+func (ev *evaluator) evalPath(e *pathExpr, ctx evalContext) (interface{}, error) {
+	var nodes []Node
+	switch {
+	case e.filter != nil:
+		value, err := ev.eval(e.filter, ctx)
+		if err != nil {
+			return nil, err
+		}
+		filtered, ok := value.([]Node)
+		if !ok {
+			return nil, fmt.Errorf("xpath: path applied to a non node-set value")
+		}
+		nodes = filtered
+	case e.absolute:
+		root := ctx.node
+		for {
+			parent, ok := root.Parent()
+			if !ok {
+				break
+			}
+			root = parent
+		}
+		nodes = []Node{root}
+	default:
+		nodes = []Node{ctx.node}
+	}
+
+	for _, s := range e.steps {
+		result := make([]Node, 0)
+		for _, node := range nodes {
+			selected, err := ev.evalStep(s, node)
+			if err != nil {
+				return nil, err
+			}
+			result = append(result, selected...)
+		}
+		nodes = ev.context.DocumentOrder(result)
+	}
+	return nodes, nil
+}
+
+func (ev *evaluator) evalStep(s *step, node Node) ([]Node, error) {
+	candidates := axisNodes(s.axis, node)
+	nodes := make([]Node, 0, len(candidates))
+	for _, candidate := range candidates {
+		ok, err := ev.matches(s, candidate)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			nodes = append(nodes, candidate)
+		}
+	}
+
+	// Predicates use the proximity position in axis order
+	var err error
+	for _, predicate := range s.predicates {
+		nodes, err = ev.filter(nodes, predicate)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return nodes, nil
+}
+
+func (ev *evaluator) filter(nodes []Node, predicate expr) ([]Node, error) {
+	result := make([]Node, 0, len(nodes))
+	for i, node := range nodes {
+		value, err := ev.eval(predicate, evalContext{node: node, position: i + 1, size: len(nodes)})
+		if err != nil {
+			return nil, err
+		}
+		keep := false
+		if number, ok := value.(float64); ok {
+			keep = number == float64(i+1)
+		} else {
+			keep = toBoolean(value)
+		}
+		if keep {
+			result = append(result, node)
+		}
+	}
+	return result, nil
+}
+
+func (ev *evaluator) matches(s *step, node Node) (bool, error) {
+	test := s.test
+	switch test.nodeType {
+	case "node":
+		return true, nil
+	case "text":
+		return node.Type == TextNode, nil
+	case "comment":
+		return node.Type == CommentNode, nil
+	case "processing-instruction":
+		return node.Type == ProcessingInstructionNode && (test.target == "" || node.LocalName() == test.target), nil
+	}
+
+	// A name test only matches the principal node type of the axis
+	principal := ElementNode
+	switch s.axis {
+	case axisAttribute:
+		principal = AttributeNode
+	case axisNamespace:
+		principal = NamespaceNode
+	}
+	if node.Type != principal {
+		return false, nil
+	}
+
+	if test.prefix == "" {
+		if test.local == "*" {
+			return true, nil
+		}
+		return node.LocalName() == test.local && node.NamespaceURI() == "", nil
+	}
+	uri, err := ev.context.resolvePrefix(test.prefix)
+	if err != nil {
+		return false, err
+	}
+	if node.Type == NamespaceNode || node.NamespaceURI() != uri {
+		return false, nil
+	}
+	return test.local == "*" || node.LocalName() == test.local, nil
+}
+
+func axisNodes(a axis, node Node) []Node {
+	switch a {
+	case axisSelf:
+		return []Node{node}
+	case axisChild:
+		return node.children()
+	case axisAttribute:
+		return node.attributes()
+	case axisNamespace:
+		return node.namespaces()
+	case axisParent:
+		if parent, ok := node.Parent(); ok {
+			return []Node{parent}
+		}
+		return nil
+	case axisAncestor, axisAncestorOrSelf:
+		nodes := make([]Node, 0)
+		if a == axisAncestorOrSelf {
+			nodes = append(nodes, node)
+		}
+		for parent, ok := node.Parent(); ok; parent, ok = parent.Parent() {
+			nodes = append(nodes, parent)
+		}
+		return nodes
+	case axisDescendant, axisDescendantOrSelf:
+		nodes := make([]Node, 0)
+		if a == axisDescendantOrSelf {
+			nodes = append(nodes, node)
+		}
+		return appendDescendants(nodes, node)
+	case axisFollowingSibling, axisPrecedingSibling:
+		if node.Type == AttributeNode || node.Type == NamespaceNode {
+			return nil
+		}
+		parent, ok := node.Parent()
+		if !ok {
+			return nil
+		}
+		siblings := parent.children()
+		index := indexOf(siblings, node)
+		if a == axisFollowingSibling {
+			return append([]Node{}, siblings[index+1:]...)
+		}
+		nodes := make([]Node, 0, index)
+		for i := index - 1; i >= 0; i-- {
+			nodes = append(nodes, siblings[i])
+		}
+		return nodes
+	case axisFollowing:
+		nodes := make([]Node, 0)
+		current := node
+		if node.Type == AttributeNode || node.Type == NamespaceNode {
+			current, _ = node.Parent()
+			nodes = appendDescendants(nodes, current)
+		}
+		for {
+			parent, ok := current.Parent()
+			if !ok {
+				break
+			}
+			siblings := parent.children()
+			for _, sibling := range siblings[indexOf(siblings, current)+1:] {
+				nodes = append(nodes, sibling)
+				nodes = appendDescendants(nodes, sibling)
+			}
+			current = parent
+		}
+		return nodes
+	case axisPreceding:
+		nodes := make([]Node, 0)
+		current := node
+		if node.Type == AttributeNode || node.Type == NamespaceNode {
+			current, _ = node.Parent()
+		}
+		for {
+			parent, ok := current.Parent()
+			if !ok {
+				break
+			}
+			siblings := parent.children()
+			for i := indexOf(siblings, current) - 1; i >= 0; i-- {
+				descendants := appendDescendants(nil, siblings[i])
+				for j := len(descendants) - 1; j >= 0; j-- {
+					nodes = append(nodes, descendants[j])
+				}
+				nodes = append(nodes, siblings[i])
+			}
+			current = parent
+		}
+		return nodes
+	}
+	return nil
+}
+
+func appendDescendants(nodes []Node, node Node) []Node {
+	for _, child := range node.children() {
+		nodes = append(nodes, child)
+		nodes = appendDescendants(nodes, child)
+	}
+	return nodes
+}
+
+func indexOf(nodes []Node, node Node) int {
+	for i, n := range nodes {
+		if n == node {
+			return i
+		}
+	}
+	return -1
+}
+
+func toBoolean(value interface{}) bool {
+	switch v := value.(type) {
+	case bool:
+		return v
+	case float64:
+		return v != 0 && !math.IsNaN(v)
+	case string:
+		return v != ""
+	case []Node:
+		return len(v) > 0
+	}
+	return false
+}
+
+func toNumber(value interface{}) float64 {
+	switch v := value.(type) {
+	case bool:
+		if v {
+			return 1
+		}
+		return 0
+	case float64:
+		return v
+	case string:
+		return parseNumber(v)
+	case []Node:
+		return parseNumber(toString(v))
+	}
+	return math.NaN()
+}
+
+func toString(value interface{}) string {
+	switch v := value.(type) {
+	case bool:
+		if v {
+			return "true"
+		}
+		return "false"
+	case float64:
+		return formatNumber(v)
+	case string:
+		return v
+	case []Node:
+		if len(v) == 0 {
+			return ""
+		}
+		return v[0].StringValue()
+	}
+	return ""
+}
+
+func parseNumber(s string) float64 {
+	s = strings.Trim(s, " \t\r\n")
+	digits := strings.TrimPrefix(s, "-")
+	if digits == "" || digits == "." || strings.Trim(digits, "0123456789.") != "" || strings.Count(digits, ".") > 1 {
+		return math.NaN()
+	}
+	value, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return math.NaN()
+	}
+	return value
+}
+
+func formatNumber(value float64) string {
+	switch {
+	case math.IsNaN(value):
+		return "NaN"
+	case math.IsInf(value, 1):
+		return "Infinity"
+	case math.IsInf(value, -1):
+		return "-Infinity"
+	case value == 0:
+		return "0"
+	}
+	return strconv.FormatFloat(value, 'f', -1, 64)
+}
+
+func compare(op string, left interface{}, right interface{}) bool {
+	leftNodes, leftIsNodes := left.([]Node)
+	rightNodes, rightIsNodes := right.([]Node)
+
+	// Comparisons involving node-sets are existential
+	switch {
+	case leftIsNodes && rightIsNodes:
+		for _, l := range leftNodes {
+			for _, r := range rightNodes {
+				if compareAtomic(op, l.StringValue(), r.StringValue()) {
+					return true
+				}
+			}
+		}
+		return false
+	case leftIsNodes:
+		if b, ok := right.(bool); ok {
+			return compareAtomic(op, toBoolean(left), b)
+		}
+		for _, l := range leftNodes {
+			if compareAtomic(op, convertLike(l.StringValue(), right), right) {
+				return true
+			}
+		}
+		return false
+	case rightIsNodes:
+		if b, ok := left.(bool); ok {
+			return compareAtomic(op, b, toBoolean(right))
+		}
+		for _, r := range rightNodes {
+			if compareAtomic(op, left, convertLike(r.StringValue(), left)) {
+				return true
+			}
+		}
+		return false
+	}
+	return compareAtomic(op, left, right)
+}
+
+func convertLike(s string, like interface{}) interface{} {
+	if _, ok := like.(float64); ok {
+		return parseNumber(s)
+	}
+	return s
+}
+
+func compareAtomic(op string, left interface{}, right interface{}) bool {
+	if op == "=" || op == "!=" {
+		equal := false
+		_, leftBool := left.(bool)
+		_, rightBool := right.(bool)
+		_, leftNumber := left.(float64)
+		_, rightNumber := right.(float64)
+		switch {
+		case leftBool || rightBool:
+			equal = toBoolean(left) == toBoolean(right)
+		case leftNumber || rightNumber:
+			equal = toNumber(left) == toNumber(right)
+		default:
+			equal = toString(left) == toString(right)
+		}
+		return equal == (op == "=")
+	}
+
+	l := toNumber(left)
+	r := toNumber(right)
+	switch op {
+	case "<":
+		return l < r
+	case "<=":
+		return l <= r
+	case ">":
+		return l > r
+	case ">=":
+		return l >= r
+	}
+	return false
+}
@@ -0,0 +1,240 @@
+package xpath
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"unicode/utf8"
+)
+
+func (ev *evaluator) evalFunction(e *functionExpr, ctx evalContext) (interface{}, error) {
+	args := make([]interface{}, len(e.args))
+	for i, arg := range e.args {
+		value, err := ev.eval(arg, ctx)
+		if err != nil {
+			return nil, err
+		}
+		args[i] = value
+	}
+
+	argCount := func(min int, max int) error {
+		if len(args) < min || (max >= 0 && len(args) > max) {
+			return fmt.Errorf("xpath: invalid number of arguments for function %s()", e.name)
+		}
+		return nil
+	}
+	nodeArg := func() (Node, bool, error) {
+		if len(args) == 0 {
+			return ctx.node, true, nil
+		}
+		nodes, ok := args[0].([]Node)
+		if !ok {
+			return Node{}, false, fmt.Errorf("xpath: function %s() requires a node-set argument", e.name)
+		}
+		if len(nodes) == 0 {
+			return Node{}, false, nil
+		}
+		return nodes[0], true, nil
+	}
+	stringArg := func() string {
+		if len(args) == 0 {
+			return ctx.node.StringValue()
+		}
+		return toString(args[0])
+	}
+
+	switch e.name {
+	case "last":
+		return float64(ctx.size), argCount(0, 0)
+	case "position":
+		return float64(ctx.position), argCount(0, 0)
+	case "count":
+		if err := argCount(1, 1); err != nil {
+			return nil, err
+		}
+		nodes, ok := args[0].([]Node)
+		if !ok {
+			return nil, fmt.Errorf("xpath: function count() requires a node-set argument")
+		}
+		return float64(len(nodes)), nil
+	case "local-name", "namespace-uri", "name":
+		if err := argCount(0, 1); err != nil {
+			return nil, err
+		}
+		node, ok, err := nodeArg()
+		if err != nil || !ok {
+			return "", err
+		}
+		switch e.name {
+		case "local-name":
+			return node.LocalName(), nil
+		case "namespace-uri":
+			return node.NamespaceURI(), nil
+		}
+		return node.Name(), nil
+	case "here":
+		if err := argCount(0, 0); err != nil {
+			return nil, err
+		}
+		if ev.context.Here == nil {
+			return nil, fmt.Errorf("xpath: function here() is not available in this context")
+		}
+		return []Node{*ev.context.Here}, nil
+	case "string":
+		return stringArg(), argCount(0, 1)
+	case "concat":
+		if err := argCount(2, -1); err != nil {
+			return nil, err
+		}
+		var sb strings.Builder
+		for _, arg := range args {
+			sb.WriteString(toString(arg))
+		}
+		return sb.String(), nil
+	case "starts-with", "contains", "substring-before", "substring-after":
+		if err := argCount(2, 2); err != nil {
+			return nil, err
+		}
+		s := toString(args[0])
+		t := toString(args[1])
+		switch e.name {
+		case "starts-with":
+			return strings.HasPrefix(s, t), nil
+		case "contains":
+			return strings.Contains(s, t), nil
+		case "substring-before":
+			if i := strings.Index(s, t); i >= 0 {
+				return s[:i], nil
+			}
+			return "", nil
+		}
+		if i := strings.Index(s, t); i >= 0 {
+			return s[i+len(t):], nil
+		}
+		return "", nil
+	case "substring":
+		if err := argCount(2, 3); err != nil {
+			return nil, err
+		}
+		runes := []rune(toString(args[0]))
+		start := round(toNumber(args[1]))
+		end := math.Inf(1)
+		if len(args) == 3 {
+			end = start + round(toNumber(args[2]))
+		}
+		var sb strings.Builder
+		for i, r := range runes {
+			position := float64(i + 1)
+			if position >= start && position < end {
+				sb.WriteRune(r)
+			}
+		}
+		return sb.String(), nil
+	case "string-length":
+		return float64(utf8.RuneCountInString(stringArg())), argCount(0, 1)
+	case "normalize-space":
+		return strings.Join(strings.Fields(stringArg()), " "), argCount(0, 1)
+	case "translate":
+		if err := argCount(3, 3); err != nil {
+			return nil, err
+		}
+		from := []rune(toString(args[1]))
+		to := []rune(toString(args[2]))
+		var sb strings.Builder
+		for _, r := range toString(args[0]) {
+			index := -1
+			for i, f := range from {
+				if f == r {
+					index = i
+					break
+				}
+			}
+			switch {
+			case index < 0:
+				sb.WriteRune(r)
+			case index < len(to):
+				sb.WriteRune(to[index])
+			}
+		}
+		return sb.String(), nil
+	case "boolean":
+		if err := argCount(1, 1); err != nil {
+			return nil, err
+		}
+		return toBoolean(args[0]), nil
+	case "not":
+		if err := argCount(1, 1); err != nil {
+			return nil, err
+		}
+		return !toBoolean(args[0]), nil
+	case "true":
+		return true, argCount(0, 0)
+	case "false":
+		return false, argCount(0, 0)
+	case "lang":
+		if err := argCount(1, 1); err != nil {
+			return nil, err
+		}
+		return lang(ctx.node, toString(args[0])), nil
+	case "number":
+		if err := argCount(0, 1); err != nil {
+			return nil, err
+		}
+		if len(args) == 0 {
+			return parseNumber(ctx.node.StringValue()), nil
+		}
+		return toNumber(args[0]), nil
+	case "sum":
+		if err := argCount(1, 1); err != nil {
+			return nil, err
+		}
+		nodes, ok := args[0].([]Node)
+		if !ok {
+			return nil, fmt.Errorf("xpath: function sum() requires a node-set argument")
+		}
+		sum := 0.0
+		for _, node := range nodes {
+			sum += parseNumber(node.StringValue())
+		}
+		return sum, nil
+	case "floor", "ceiling", "round":
+		if err := argCount(1, 1); err != nil {
+			return nil, err
+		}
+		number := toNumber(args[0])
+		switch e.name {
+		case "floor":
+			return math.Floor(number), nil
+		case "ceiling":
+			return math.Ceil(number), nil
+		}
+		return round(number), nil
+	}
+	return nil, fmt.Errorf("xpath: unsupported function: %s()", e.name)
+}
+
+func round(number float64) float64 {
+	if math.IsNaN(number) || math.IsInf(number, 0) {
+		return number
+	}
+	if number < 0 && number >= -0.5 {
+		return math.Copysign(0, -1)
+	}
+	return math.Floor(number + 0.5)
+}
+
+func lang(node Node, language string) bool {
+	for current, ok := node, true; ok; current, ok = current.Parent() {
+		if current.Type != ElementNode {
+			continue
+		}
+		for _, attr := range current.Element().Attr {
+			if attr.Space == "xml" && attr.Key == "lang" {
+				value := strings.ToLower(attr.Value)
+				language = strings.ToLower(language)
+				return value == language || strings.HasPrefix(value, language+"-")
+			}
+		}
+	}
+	return false
+}
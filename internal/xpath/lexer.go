@@ -0,0 +1,250 @@
+package xpath
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+type tokenKind int
+
+const (
+	tokenEOF tokenKind = iota
+	tokenNumber
+	tokenLiteral
+	tokenNameTest
+	tokenNodeType
+	tokenFunctionName
+	tokenAxisName
+	tokenVariable
+	tokenOperator
+	tokenLeftParen
+	tokenRightParen
+	tokenLeftBracket
+	tokenRightBracket
+	tokenDot
+	tokenDotDot
+	tokenAt
+	tokenComma
+	tokenColonColon
+)
+
+type token struct {
+	kind  tokenKind
+	value string
+}
+
+var (
+	nodeTypes = map[string]bool{
+		"comment":                true,
+		"text":                   true,
+		"processing-instruction": true,
+		"node":                   true,
+	}
+	operatorNames = map[string]bool{
+		"and": true,
+		"or":  true,
+		"mod": true,
+		"div": true,
+	}
+)
+
+func tokenize(expr string) ([]token, error) {
+	tokens := make([]token, 0)
+	pos := 0
+
+	// An operator is expected when the preceding token can end an operand
+	operatorExpected := func() bool {
+		if len(tokens) == 0 {
+			return false
+		}
+		switch tokens[len(tokens)-1].kind {
+		case tokenAt, tokenColonColon, tokenLeftParen, tokenLeftBracket, tokenComma, tokenOperator:
+			return false
+		}
+		return true
+	}
+
+	for pos < len(expr) {
+		c := expr[pos]
+		switch {
+		case c == ' ' || c == '\t' || c == '\r' || c == '\n':
+			pos++
+		case c == '(':
+			tokens = append(tokens, token{kind: tokenLeftParen})
+			pos++
+		case c == ')':
+			tokens = append(tokens, token{kind: tokenRightParen})
+			pos++
+		case c == '[':
+			tokens = append(tokens, token{kind: tokenLeftBracket})
+			pos++
+		case c == ']':
+			tokens = append(tokens, token{kind: tokenRightBracket})
+			pos++
+		case c == '@':
+			tokens = append(tokens, token{kind: tokenAt})
+			pos++
+		case c == ',':
+			tokens = append(tokens, token{kind: tokenComma})
+			pos++
+		case c == ':' && strings.HasPrefix(expr[pos:], "::"):
+			tokens = append(tokens, token{kind: tokenColonColon})
+			pos += 2
+		case c == '.' && strings.HasPrefix(expr[pos:], ".."):
+			tokens = append(tokens, token{kind: tokenDotDot})
+			pos += 2
+		case c == '.' && (pos+1 >= len(expr) || !isDigit(expr[pos+1])):
+			tokens = append(tokens, token{kind: tokenDot})
+			pos++
+		case c == '.' || isDigit(c):
+			start := pos
+			for pos < len(expr) && isDigit(expr[pos]) {
+				pos++
+			}
+			if pos < len(expr) && expr[pos] == '.' {
+				pos++
+				for pos < len(expr) && isDigit(expr[pos]) {
+					pos++
+				}
+			}
+			tokens = append(tokens, token{kind: tokenNumber, value: expr[start:pos]})
+		case c == '"' || c == '\'':
+			end := strings.IndexByte(expr[pos+1:], c)
+			if end < 0 {
+				return nil, fmt.Errorf("xpath: unterminated literal at position %d", pos)
+			}
+			tokens = append(tokens, token{kind: tokenLiteral, value: expr[pos+1 : pos+1+end]})
+			pos += end + 2
+		case c == '/':
+			if strings.HasPrefix(expr[pos:], "//") {
+				tokens = append(tokens, token{kind: tokenOperator, value: "//"})
+				pos += 2
+			} else {
+				tokens = append(tokens, token{kind: tokenOperator, value: "/"})
+				pos++
+			}
+		case c == '|' || c == '+' || c == '-' || c == '=':
+			tokens = append(tokens, token{kind: tokenOperator, value: string(c)})
+			pos++
+		case c == '!' || c == '<' || c == '>':
+			if strings.HasPrefix(expr[pos+1:], "=") {
+				tokens = append(tokens, token{kind: tokenOperator, value: expr[pos : pos+2]})
+				pos += 2
+			} else if c == '!' {
+				return nil, fmt.Errorf("xpath: unexpected character '!' at position %d", pos)
+			} else {
+				tokens = append(tokens, token{kind: tokenOperator, value: string(c)})
+				pos++
+			}
+		case c == '*':
+			if operatorExpected() {
+				tokens = append(tokens, token{kind: tokenOperator, value: "*"})
+			} else {
+				tokens = append(tokens, token{kind: tokenNameTest, value: "*"})
+			}
+			pos++
+		case c == '$':
+			pos++
+			name, n := scanQName(expr[pos:])
+			if n == 0 {
+				return nil, fmt.Errorf("xpath: invalid variable reference at position %d", pos)
+			}
+			tokens = append(tokens, token{kind: tokenVariable, value: name})
+			pos += n
+		default:
+			name, n := scanNCName(expr[pos:])
+			if n == 0 {
+				return nil, fmt.Errorf("xpath: unexpected character at position %d", pos)
+			}
+			if operatorExpected() {
+				if !operatorNames[name] {
+					return nil, fmt.Errorf("xpath: unexpected name '%s' at position %d", name, pos)
+				}
+				tokens = append(tokens, token{kind: tokenOperator, value: name})
+				pos += n
+				continue
+			}
+			pos += n
+
+			// Check for a qualified name or a prefixed wildcard
+			if pos < len(expr) && expr[pos] == ':' && !strings.HasPrefix(expr[pos:], "::") {
+				if pos+1 < len(expr) && expr[pos+1] == '*' {
+					tokens = append(tokens, token{kind: tokenNameTest, value: name + ":*"})
+					pos += 2
+					continue
+				}
+				local, m := scanNCName(expr[pos+1:])
+				if m == 0 {
+					return nil, fmt.Errorf("xpath: invalid qualified name at position %d", pos)
+				}
+				name = name + ":" + local
+				pos += m + 1
+			}
+
+			next := skipSpace(expr, pos)
+			switch {
+			case strings.HasPrefix(expr[next:], "::"):
+				tokens = append(tokens, token{kind: tokenAxisName, value: name})
+			case strings.HasPrefix(expr[next:], "(") && nodeTypes[name]:
+				tokens = append(tokens, token{kind: tokenNodeType, value: name})
+			case strings.HasPrefix(expr[next:], "("):
+				tokens = append(tokens, token{kind: tokenFunctionName, value: name})
+			default:
+				tokens = append(tokens, token{kind: tokenNameTest, value: name})
+			}
+		}
+	}
+
+	tokens = append(tokens, token{kind: tokenEOF})
+	return tokens, nil
+}
+
+func scanQName(s string) (string, int) {
+	name, n := scanNCName(s)
+	if n == 0 {
+		return "", 0
+	}
+	if n < len(s) && s[n] == ':' {
+		local, m := scanNCName(s[n+1:])
+		if m > 0 {
+			return name + ":" + local, n + 1 + m
+		}
+	}
+	return name, n
+}
+
+func scanNCName(s string) (string, int) {
+	n := 0
+	for n < len(s) {
+		r, size := utf8.DecodeRuneInString(s[n:])
+		if n == 0 && !isNameStartChar(r) {
+			break
+		}
+		if n > 0 && !isNameChar(r) {
+			break
+		}
+		n += size
+	}
+	return s[:n], n
+}
+
+func isNameStartChar(r rune) bool {
+	return r == '_' || unicode.IsLetter(r)
+}
+
+func isNameChar(r rune) bool {
+	return isNameStartChar(r) || r == '-' || r == '.' || unicode.IsDigit(r) || unicode.Is(unicode.Mn, r) || unicode.Is(unicode.Mc, r) || r == 0xB7
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+func skipSpace(s string, pos int) int {
+	for pos < len(s) && (s[pos] == ' ' || s[pos] == '\t' || s[pos] == '\r' || s[pos] == '\n') {
+		pos++
+	}
+	return pos
+}
@@ -0,0 +1,249 @@
+package xpath
+
+import (
+	"strings"
+
+	"github.com/beevik/etree"
+)
+
+const (
+	XmlNamespaceUri   string = "http://www.w3.org/XML/1998/namespace"
+	XmlnsNamespaceUri string = "http://www.w3.org/2000/xmlns/"
+)
+
+type NodeType int
+
+const (
+	RootNode NodeType = iota
+	ElementNode
+	AttributeNode
+	NamespaceNode
+	TextNode
+	CommentNode
+	ProcessingInstructionNode
+)
+
+// Node is a node of the XPath data model. Attribute and namespace nodes refer
+// to their owner element through Token.
+type Node struct {
+	Type   NodeType
+	Token  etree.Token
+	Attr   *etree.Attr
+	Prefix string
+	Uri    string
+}
+
+func NewTokenNode(token etree.Token) (Node, bool) {
+	switch t := token.(type) {
+	case *etree.Element:
+		if isRootElement(t) {
+			return Node{Type: RootNode, Token: t}, true
+		}
+		return Node{Type: ElementNode, Token: t}, true
+	case *etree.CharData:
+		return Node{Type: TextNode, Token: t}, true
+	case *etree.Comment:
+		return Node{Type: CommentNode, Token: t}, true
+	case *etree.ProcInst:
+		// The XML declaration is not a processing instruction
+		if t.Target == "xml" {
+			return Node{}, false
+		}
+		return Node{Type: ProcessingInstructionNode, Token: t}, true
+	}
+	return Node{}, false
+}
+
+func NewAttrNode(el *etree.Element, attr *etree.Attr) Node {
+	return Node{Type: AttributeNode, Token: el, Attr: attr}
+}
+
+func NewNamespaceNode(el *etree.Element, prefix string, uri string) Node {
+	return Node{Type: NamespaceNode, Token: el, Prefix: prefix, Uri: uri}
+}
+
+// Element returns the element of an element or root node, or the owner element
+// of an attribute or namespace node.
+func (n Node) Element() *etree.Element {
+	el, _ := n.Token.(*etree.Element)
+	return el
+}
+
+func (n Node) StringValue() string {
+	switch n.Type {
+	case RootNode, ElementNode:
+		var sb strings.Builder
+		writeText(&sb, n.Element())
+		return sb.String()
+	case AttributeNode:
+		return n.Attr.Value
+	case NamespaceNode:
+		return n.Uri
+	case TextNode:
+		return n.Token.(*etree.CharData).Data
+	case CommentNode:
+		return n.Token.(*etree.Comment).Data
+	case ProcessingInstructionNode:
+		return n.Token.(*etree.ProcInst).Inst
+	}
+	return ""
+}
+
+func (n Node) LocalName() string {
+	switch n.Type {
+	case ElementNode:
+		return n.Element().Tag
+	case AttributeNode:
+		return n.Attr.Key
+	case NamespaceNode:
+		return n.Prefix
+	case ProcessingInstructionNode:
+		return n.Token.(*etree.ProcInst).Target
+	}
+	return ""
+}
+
+func (n Node) NamespaceURI() string {
+	switch n.Type {
+	case ElementNode:
+		return n.Element().NamespaceURI()
+	case AttributeNode:
+		return attrNamespaceURI(n.Attr)
+	}
+	return ""
+}
+
+func (n Node) Name() string {
+	switch n.Type {
+	case ElementNode:
+		return n.Element().FullTag()
+	case AttributeNode:
+		return n.Attr.FullKey()
+	}
+	return n.LocalName()
+}
+
+func (n Node) Parent() (Node, bool) {
+	switch n.Type {
+	case RootNode:
+		return Node{}, false
+	case AttributeNode, NamespaceNode:
+		return NewTokenNode(n.Token)
+	}
+	parent := n.Token.Parent()
+	if parent == nil {
+		return Node{}, false
+	}
+	return NewTokenNode(parent)
+}
+
+func (n Node) children() []Node {
+	if n.Type != RootNode && n.Type != ElementNode {
+		return nil
+	}
+	el := n.Element()
+	children := make([]Node, 0, len(el.Child))
+	for _, child := range el.Child {
+		// The root node does not have text children
+		if charData, ok := child.(*etree.CharData); ok && n.Type == RootNode && strings.TrimSpace(charData.Data) == "" {
+			continue
+		}
+		if node, ok := NewTokenNode(child); ok {
+			children = append(children, node)
+		}
+	}
+	return children
+}
+
+func (n Node) attributes() []Node {
+	if n.Type != ElementNode {
+		return nil
+	}
+	el := n.Element()
+	attributes := make([]Node, 0, len(el.Attr))
+	for i := range el.Attr {
+		if IsNamespaceAttr(&el.Attr[i]) {
+			continue
+		}
+		attributes = append(attributes, NewAttrNode(el, &el.Attr[i]))
+	}
+	return attributes
+}
+
+func (n Node) namespaces() []Node {
+	if n.Type != ElementNode {
+		return nil
+	}
+	el := n.Element()
+	namespaces := []Node{NewNamespaceNode(el, "xml", XmlNamespaceUri)}
+	for prefix, uri := range InScopeNamespaces(el) {
+		if prefix == "xml" {
+			continue
+		}
+		namespaces = append(namespaces, NewNamespaceNode(el, prefix, uri))
+	}
+	return namespaces
+}
+
+// InScopeNamespaces returns the namespace declarations in scope for the element,
+// the default namespace is returned with an empty prefix.
+func InScopeNamespaces(el *etree.Element) map[string]string {
+	namespaces := map[string]string{}
+	undeclared := map[string]bool{}
+	for current := el; current != nil; current = current.Parent() {
+		for _, attr := range current.Attr {
+			prefix := ""
+			switch {
+			case attr.Space == "xmlns":
+				prefix = attr.Key
+			case attr.Space == "" && attr.Key == "xmlns":
+				prefix = ""
+			default:
+				continue
+			}
+			if _, ok := namespaces[prefix]; ok || undeclared[prefix] {
+				continue
+			}
+			if attr.Value == "" {
+				undeclared[prefix] = true
+				continue
+			}
+			namespaces[prefix] = attr.Value
+		}
+	}
+	return namespaces
+}
+
+func IsNamespaceAttr(attr *etree.Attr) bool {
+	return attr.Space == "xmlns" || (attr.Space == "" && attr.Key == "xmlns")
+}
+
+func attrNamespaceURI(attr *etree.Attr) string {
+	switch attr.Space {
+	case "":
+		return ""
+	case "xml":
+		return XmlNamespaceUri
+	case "xmlns":
+		return XmlnsNamespaceUri
+	}
+	return attr.NamespaceURI()
+}
+
+func isRootElement(el *etree.Element) bool {
+	return el.Parent() == nil && el.Tag == "" && el.Space == ""
+}
+
+func writeText(sb *strings.Builder, el *etree.Element) {
+	for _, child := range el.Child {
+		switch t := child.(type) {
+		case *etree.CharData:
+			if el.Parent() == nil && isRootElement(el) {
+				continue
+			}
+			sb.WriteString(t.Data)
+		case *etree.Element:
+			writeText(sb, t)
+		}
+	}
+}
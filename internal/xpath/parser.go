@@ -0,0 +1,431 @@
+package xpath
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+type axis int
+
+const (
+	axisAncestor axis = iota
+	axisAncestorOrSelf
+	axisAttribute
+	axisChild
+	axisDescendant
+	axisDescendantOrSelf
+	axisFollowing
+	axisFollowingSibling
+	axisNamespace
+	axisParent
+	axisPreceding
+	axisPrecedingSibling
+	axisSelf
+)
+
+var (
+	axisNames = map[string]axis{
+		"ancestor":           axisAncestor,
+		"ancestor-or-self":   axisAncestorOrSelf,
+		"attribute":          axisAttribute,
+		"child":              axisChild,
+		"descendant":         axisDescendant,
+		"descendant-or-self": axisDescendantOrSelf,
+		"following":          axisFollowing,
+		"following-sibling":  axisFollowingSibling,
+		"namespace":          axisNamespace,
+		"parent":             axisParent,
+		"preceding":          axisPreceding,
+		"preceding-sibling":  axisPrecedingSibling,
+		"self":               axisSelf,
+	}
+)
+
+type expr interface{}
+
+type binaryExpr struct {
+	op    string
+	left  expr
+	right expr
+}
+
+type negateExpr struct {
+	operand expr
+}
+
+type literalExpr struct {
+	value string
+}
+
+type numberExpr struct {
+	value float64
+}
+
+type variableExpr struct {
+	name string
+}
+
+type functionExpr struct {
+	name string
+	args []expr
+}
+
+type filterExpr struct {
+	primary    expr
+	predicates []expr
+}
+
+// pathExpr is a location path, optionally starting from a filter expression.
+type pathExpr struct {
+	filter   expr
+	absolute bool
+	steps    []*step
+}
+
+type nodeTest struct {
+	nodeType string
+	prefix   string
+	local    string
+	target   string
+}
+
+type step struct {
+	axis       axis
+	test       nodeTest
+	predicates []expr
+}
+
+type parser struct {
+	tokens []token
+	pos    int
+}
+
+func parse(s string) (expr, error) {
+	tokens, err := tokenize(s)
+	if err != nil {
+		return nil, err
+	}
+	p := &parser{tokens: tokens}
+	e, err := p.parseOrExpr()
+	if err != nil {
+		return nil, err
+	}
+	if p.peek().kind != tokenEOF {
+		return nil, fmt.Errorf("xpath: unexpected token in expression: %s", s)
+	}
+	return e, nil
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.pos]
+}
+
+func (p *parser) next() token {
+	t := p.tokens[p.pos]
+	if t.kind != tokenEOF {
+		p.pos++
+	}
+	return t
+}
+
+func (p *parser) isOperator(values ...string) bool {
+	t := p.peek()
+	if t.kind != tokenOperator {
+		return false
+	}
+	for _, value := range values {
+		if t.value == value {
+			return true
+		}
+	}
+	return false
+}
+
+func (p *parser) expect(kind tokenKind, description string) error {
+	if p.next().kind != kind {
+		return fmt.Errorf("xpath: expected %s", description)
+	}
+	return nil
+}
+
+func (p *parser) parseBinary(next func() (expr, error), operators ...string) (expr, error) {
+	left, err := next()
+	if err != nil {
+		return nil, err
+	}
+	for p.isOperator(operators...) {
+		op := p.next().value
+		right, err := next()
+		if err != nil {
+			return nil, err
+		}
+		left = &binaryExpr{op: op, left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *parser) parseOrExpr() (expr, error) {
+	return p.parseBinary(p.parseAndExpr, "or")
+}
+
+func (p *parser) parseAndExpr() (expr, error) {
+	return p.parseBinary(p.parseEqualityExpr, "and")
+}
+
+func (p *parser) parseEqualityExpr() (expr, error) {
+	return p.parseBinary(p.parseRelationalExpr, "=", "!=")
+}
+
+func (p *parser) parseRelationalExpr() (expr, error) {
+	return p.parseBinary(p.parseAdditiveExpr, "<", "<=", ">", ">=")
+}
+
+func (p *parser) parseAdditiveExpr() (expr, error) {
+	return p.parseBinary(p.parseMultiplicativeExpr, "+", "-")
+}
+
+func (p *parser) parseMultiplicativeExpr() (expr, error) {
+	return p.parseBinary(p.parseUnaryExpr, "*", "div", "mod")
+}
+
+func (p *parser) parseUnaryExpr() (expr, error) {
+	if p.isOperator("-") {
+		p.next()
+		operand, err := p.parseUnaryExpr()
+		if err != nil {
+			return nil, err
+		}
+		return &negateExpr{operand: operand}, nil
+	}
+	return p.parseUnionExpr()
+}
+
+func (p *parser) parseUnionExpr() (expr, error) {
+	return p.parseBinary(p.parsePathExpr, "|")
+}
+
+func (p *parser) parsePathExpr() (expr, error) {
+	t := p.peek()
+	switch t.kind {
+	case tokenVariable, tokenLeftParen, tokenLiteral, tokenNumber, tokenFunctionName:
+		filter, err := p.parseFilterExpr()
+		if err != nil {
+			return nil, err
+		}
+		if !p.isOperator("/", "//") {
+			return filter, nil
+		}
+		path := &pathExpr{filter: filter}
+		err = p.parseRelativeLocationPath(path, true)
+		if err != nil {
+			return nil, err
+		}
+		return path, nil
+	}
+
+	path := &pathExpr{}
+	if p.isOperator("/") {
+		p.next()
+		path.absolute = true
+		if !p.isStepStart() {
+			return path, nil
+		}
+	} else if p.isOperator("//") {
+		path.absolute = true
+		err := p.parseRelativeLocationPath(path, true)
+		if err != nil {
+			return nil, err
+		}
+		return path, nil
+	}
+	err := p.parseRelativeLocationPath(path, false)
+	if err != nil {
+		return nil, err
+	}
+	return path, nil
+}
+
+func (p *parser) isStepStart() bool {
+	switch p.peek().kind {
+	case tokenNameTest, tokenNodeType, tokenAxisName, tokenAt, tokenDot, tokenDotDot:
+		return true
+	}
+	return false
+}
+
+// parseRelativeLocationPath parses the steps of a path, when separated is set
+// the path must start with a '/' or '//' separator.
+func (p *parser) parseRelativeLocationPath(path *pathExpr, separated bool) error {
+	for {
+		if separated {
+			if p.isOperator("//") {
+				path.steps = append(path.steps, &step{axis: axisDescendantOrSelf, test: nodeTest{nodeType: "node"}})
+			} else if !p.isOperator("/") {
+				return nil
+			}
+			p.next()
+		}
+		s, err := p.parseStep()
+		if err != nil {
+			return err
+		}
+		path.steps = append(path.steps, s)
+		separated = true
+	}
+}
+
+func (p *parser) parseStep() (*step, error) {
+	switch p.peek().kind {
+	case tokenDot:
+		p.next()
+		return &step{axis: axisSelf, test: nodeTest{nodeType: "node"}}, nil
+	case tokenDotDot:
+		p.next()
+		return &step{axis: axisParent, test: nodeTest{nodeType: "node"}}, nil
+	}
+
+	s := &step{axis: axisChild}
+	switch p.peek().kind {
+	case tokenAt:
+		p.next()
+		s.axis = axisAttribute
+	case tokenAxisName:
+		name := p.next().value
+		a, ok := axisNames[name]
+		if !ok {
+			return nil, fmt.Errorf("xpath: unknown axis: %s", name)
+		}
+		s.axis = a
+		err := p.expect(tokenColonColon, "'::'")
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	t := p.next()
+	switch t.kind {
+	case tokenNameTest:
+		if t.value != "*" {
+			s.test.prefix, s.test.local = splitQName(t.value)
+		} else {
+			s.test.local = "*"
+		}
+	case tokenNodeType:
+		s.test.nodeType = t.value
+		err := p.expect(tokenLeftParen, "'('")
+		if err != nil {
+			return nil, err
+		}
+		if t.value == "processing-instruction" && p.peek().kind == tokenLiteral {
+			s.test.target = p.next().value
+		}
+		err = p.expect(tokenRightParen, "')'")
+		if err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("xpath: expected a node test")
+	}
+
+	for p.peek().kind == tokenLeftBracket {
+		predicate, err := p.parsePredicate()
+		if err != nil {
+			return nil, err
+		}
+		s.predicates = append(s.predicates, predicate)
+	}
+	return s, nil
+}
+
+func (p *parser) parsePredicate() (expr, error) {
+	p.next()
+	predicate, err := p.parseOrExpr()
+	if err != nil {
+		return nil, err
+	}
+	err = p.expect(tokenRightBracket, "']'")
+	if err != nil {
+		return nil, err
+	}
+	return predicate, nil
+}
+
+func (p *parser) parseFilterExpr() (expr, error) {
+	primary, err := p.parsePrimaryExpr()
+	if err != nil {
+		return nil, err
+	}
+	if p.peek().kind != tokenLeftBracket {
+		return primary, nil
+	}
+	filter := &filterExpr{primary: primary}
+	for p.peek().kind == tokenLeftBracket {
+		predicate, err := p.parsePredicate()
+		if err != nil {
+			return nil, err
+		}
+		filter.predicates = append(filter.predicates, predicate)
+	}
+	return filter, nil
+}
+
+func (p *parser) parsePrimaryExpr() (expr, error) {
+	t := p.next()
+	switch t.kind {
+	case tokenVariable:
+		return &variableExpr{name: t.value}, nil
+	case tokenLiteral:
+		return &literalExpr{value: t.value}, nil
+	case tokenNumber:
+		value, err := strconv.ParseFloat(t.value, 64)
+		if err != nil {
+			return nil, err
+		}
+		return &numberExpr{value: value}, nil
+	case tokenLeftParen:
+		e, err := p.parseOrExpr()
+		if err != nil {
+			return nil, err
+		}
+		err = p.expect(tokenRightParen, "')'")
+		if err != nil {
+			return nil, err
+		}
+		return e, nil
+	case tokenFunctionName:
+		function := &functionExpr{name: t.value}
+		err := p.expect(tokenLeftParen, "'('")
+		if err != nil {
+			return nil, err
+		}
+		if p.peek().kind == tokenRightParen {
+			p.next()
+			return function, nil
+		}
+		for {
+			arg, err := p.parseOrExpr()
+			if err != nil {
+				return nil, err
+			}
+			function.args = append(function.args, arg)
+			if p.peek().kind != tokenComma {
+				break
+			}
+			p.next()
+		}
+		err = p.expect(tokenRightParen, "')'")
+		if err != nil {
+			return nil, err
+		}
+		return function, nil
+	}
+	return nil, fmt.Errorf("xpath: unexpected token")
+}
+
+func splitQName(name string) (string, string) {
+	if i := strings.IndexByte(name, ':'); i >= 0 {
+		return name[:i], name[i+1:]
+	}
+	return "", name
+}
@@ -0,0 +1,153 @@
+// Package xpath implements XPath 1.0 expressions over etree documents, as
+// required by the XMLDSig XPath filtering transforms.
+package xpath
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/beevik/etree"
+)
+
+type Expr struct {
+	source string
+	root   expr
+}
+
+// Context holds the static evaluation context of an expression. A context may
+// be reused for several evaluations on the same document.
+type Context struct {
+	Namespaces map[string]string
+	Here       *Node
+	order      map[Node]int
+	orderRoot  *etree.Element
+}
+
+func Compile(s string) (*Expr, error) {
+	root, err := parse(s)
+	if err != nil {
+		return nil, err
+	}
+	return &Expr{
+		source: s,
+		root:   root,
+	}, nil
+}
+
+func (e *Expr) String() string {
+	return e.source
+}
+
+// Evaluate returns the value of the expression, either a node-set ([]Node), a
+// string, a number (float64) or a boolean.
+func (e *Expr) Evaluate(ctx *Context, node Node) (interface{}, error) {
+	return e.evaluate(ctx, node)
+}
+
+func (e *Expr) EvaluateBoolean(ctx *Context, node Node) (bool, error) {
+	value, err := e.evaluate(ctx, node)
+	if err != nil {
+		return false, err
+	}
+	return toBoolean(value), nil
+}
+
+func (e *Expr) EvaluateString(ctx *Context, node Node) (string, error) {
+	value, err := e.evaluate(ctx, node)
+	if err != nil {
+		return "", err
+	}
+	return toString(value), nil
+}
+
+func (e *Expr) EvaluateNodeSet(ctx *Context, node Node) ([]Node, error) {
+	value, err := e.evaluate(ctx, node)
+	if err != nil {
+		return nil, err
+	}
+	nodes, ok := value.([]Node)
+	if !ok {
+		return nil, errors.New("xpath: expression does not evaluate to a node-set")
+	}
+	return nodes, nil
+}
+
+func (e *Expr) evaluate(ctx *Context, node Node) (interface{}, error) {
+	if ctx == nil {
+		ctx = &Context{}
+	}
+	ev := &evaluator{
+		context: ctx,
+	}
+	return ev.eval(e.root, evalContext{node: node, position: 1, size: 1})
+}
+
+// DocumentOrder sorts the nodes in document order and removes duplicates.
+func (ctx *Context) DocumentOrder(nodes []Node) []Node {
+	if len(nodes) < 2 {
+		return nodes
+	}
+	order := ctx.documentOrder(nodes[0])
+	unique := make([]Node, 0, len(nodes))
+	seen := make(map[Node]bool, len(nodes))
+	for _, node := range nodes {
+		if !seen[node] {
+			seen[node] = true
+			unique = append(unique, node)
+		}
+	}
+	sort.SliceStable(unique, func(i, j int) bool {
+		return order[unique[i]] < order[unique[j]]
+	})
+	return unique
+}
+
+func (ctx *Context) documentOrder(node Node) map[Node]int {
+	root := node
+	for {
+		parent, ok := root.Parent()
+		if !ok {
+			break
+		}
+		root = parent
+	}
+	rootElement := root.Element()
+	if ctx.order != nil && ctx.orderRoot == rootElement {
+		return ctx.order
+	}
+
+	order := map[Node]int{}
+	var visit func(n Node)
+	visit = func(n Node) {
+		order[n] = len(order)
+		namespaces := n.namespaces()
+		sort.Slice(namespaces, func(i, j int) bool {
+			return namespaces[i].Prefix < namespaces[j].Prefix
+		})
+		for _, namespace := range namespaces {
+			order[namespace] = len(order)
+		}
+		for _, attribute := range n.attributes() {
+			order[attribute] = len(order)
+		}
+		for _, child := range n.children() {
+			visit(child)
+		}
+	}
+	visit(root)
+
+	ctx.order = order
+	ctx.orderRoot = rootElement
+	return order
+}
+
+func (ctx *Context) resolvePrefix(prefix string) (string, error) {
+	if prefix == "xml" {
+		return XmlNamespaceUri, nil
+	}
+	if uri, ok := ctx.Namespaces[prefix]; ok {
+		return uri, nil
+	}
+	return "", fmt.Errorf("xpath: undeclared namespace prefix: %s", prefix)
+}
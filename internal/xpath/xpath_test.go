@@ -0,0 +1,243 @@
+package xpath
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/beevik/etree"
+)
+
+const testDocument = `<doc xmlns:a="urn:a" xml:lang="en">` +
+	`<a:item id="1">one</a:item>` +
+	`<a:item id="2"><sub>two</sub><sub>deep</sub></a:item>` +
+	`<item id="3" xml:lang="de">three</item>` +
+	`<!--comment-->` +
+	`<?pi data?>` +
+	`</doc>`
+
+func parseTestDocument(t *testing.T, s string) *etree.Document {
+	t.Helper()
+	doc := etree.NewDocument()
+	err := doc.ReadFromString(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return doc
+}
+
+func nodeTestString(node Node) string {
+	switch node.Type {
+	case RootNode:
+		return "/"
+	case ElementNode:
+		return node.Name() + "#" + node.Element().SelectAttrValue("id", "")
+	case AttributeNode:
+		return "@" + node.Name() + "=" + node.StringValue()
+	case NamespaceNode:
+		return "ns:" + node.Prefix
+	case TextNode:
+		return "text:" + node.StringValue()
+	case CommentNode:
+		return "comment:" + node.StringValue()
+	case ProcessingInstructionNode:
+		return "pi:" + node.LocalName()
+	}
+	return "?"
+}
+
+func evaluateToString(t *testing.T, ctx *Context, expression string, node Node) string {
+	t.Helper()
+	expr, err := Compile(expression)
+	if err != nil {
+		t.Fatalf("compile %s: %v", expression, err)
+	}
+	value, err := expr.Evaluate(ctx, node)
+	if err != nil {
+		t.Fatalf("evaluate %s: %v", expression, err)
+	}
+	nodes, ok := value.([]Node)
+	if !ok {
+		return toString(value)
+	}
+	parts := make([]string, 0, len(nodes))
+	for _, n := range ctx.DocumentOrder(nodes) {
+		parts = append(parts, nodeTestString(n))
+	}
+	return strings.Join(parts, " ")
+}
+
+func Test_Evaluate_Axes(t *testing.T) {
+	doc := parseTestDocument(t, testDocument)
+	root, _ := NewTokenNode(&doc.Element)
+	second, _ := NewTokenNode(doc.FindElement("//item[@id='2']"))
+	ctx := &Context{Namespaces: map[string]string{"a": "urn:a"}}
+
+	tests := []struct {
+		expression string
+		node       Node
+		expected   string
+	}{
+		{"/doc/a:item", root, "a:item#1 a:item#2"},
+		{"/doc/item", root, "item#3"},
+		{"/doc/*", root, "a:item#1 a:item#2 item#3"},
+		{"//sub", root, "sub# sub#"},
+		{"count(//node())", root, "12"},
+		{"child::node()", second, "sub# sub#"},
+		{"parent::doc", second, "doc#"},
+		{"ancestor::*", second, "doc#"},
+		{"ancestor-or-self::node()", second, "/ doc# a:item#2"},
+		{"descendant::text()", second, "text:two text:deep"},
+		{"descendant-or-self::*", second, "a:item#2 sub# sub#"},
+		{"following-sibling::*", second, "item#3"},
+		{"preceding-sibling::*", second, "a:item#1"},
+		{"following::node()", second, "item#3 text:three comment:comment pi:pi"},
+		{"preceding::node()", second, "a:item#1 text:one"},
+		{"attribute::*", second, "@id=2"},
+		{"namespace::*", second, "ns:a ns:xml"},
+		{"self::a:item", second, "a:item#2"},
+		{"self::item", second, ""},
+		{"//comment()", root, "comment:comment"},
+		{"//processing-instruction('pi')", root, "pi:pi"},
+		{"..", second, "doc#"},
+		{".", second, "a:item#2"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.expression, func(t *testing.T) {
+			actual := evaluateToString(t, ctx, tt.expression, tt.node)
+			if actual != tt.expected {
+				t.Errorf("expected %q, got %q", tt.expected, actual)
+			}
+		})
+	}
+}
+
+func Test_Evaluate_Predicates(t *testing.T) {
+	doc := parseTestDocument(t, testDocument)
+	root, _ := NewTokenNode(&doc.Element)
+	ctx := &Context{Namespaces: map[string]string{"a": "urn:a"}}
+
+	tests := []struct {
+		expression string
+		expected   string
+	}{
+		{"/doc/*[2]", "a:item#2"},
+		{"/doc/*[last()]", "item#3"},
+		{"/doc/*[position() < 3]", "a:item#1 a:item#2"},
+		{"/doc/*[@id = '3']", "item#3"},
+		{"/doc/*[@id > 1][1]", "a:item#2"},
+		{"/doc/a:item[sub]", "a:item#2"},
+		{"/doc/*[not(sub)]", "a:item#1 item#3"},
+		{"/doc/*[lang('de')]", "item#3"},
+		{"/doc/*[lang('en')]", "a:item#1 a:item#2"},
+		{"//sub[2]/text()", "text:deep"},
+		{"(//sub)[1]/text()", "text:two"},
+		{"/doc/*[local-name() = 'item' and namespace-uri() = '']", "item#3"},
+		{"//*[@id = 1] | //*[@id = 3]", "a:item#1 item#3"},
+		{"string(/doc/a:item[2])", "twodeep"},
+		{"concat(/doc/item, '-', string-length(/doc/item))", "three-5"},
+		{"sum(/doc/*/@id) div 2", "3"},
+		{"substring-after(name(/doc/a:item[1]), ':')", "item"},
+		{"normalize-space('  a  b ')", "a b"},
+		{"translate('abc', 'ab', 'AB')", "ABc"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.expression, func(t *testing.T) {
+			actual := evaluateToString(t, ctx, tt.expression, root)
+			if actual != tt.expected {
+				t.Errorf("expected %q, got %q", tt.expected, actual)
+			}
+		})
+	}
+}
+
+func Test_Evaluate_Here(t *testing.T) {
+	doc := parseTestDocument(t, `<doc><ds:Signature xmlns:ds="http://www.w3.org/2000/09/xmldsig#" Id="s"><ds:XPath>expression</ds:XPath></ds:Signature></doc>`)
+	xpathElement := doc.FindElement("//XPath")
+	here, _ := NewTokenNode(xpathElement.Child[0])
+	root, _ := NewTokenNode(&doc.Element)
+	signature, _ := NewTokenNode(doc.FindElement("//Signature"))
+	ctx := &Context{
+		Namespaces: InScopeNamespaces(xpathElement),
+		Here:       &here,
+	}
+
+	tests := []struct {
+		expression string
+		node       Node
+		expected   string
+	}{
+		{"here()", root, "text:expression"},
+		{"here()/ancestor::ds:Signature[1]", root, "ds:Signature#"},
+		{"count(ancestor-or-self::ds:Signature | here()/ancestor::ds:Signature[1]) > count(ancestor-or-self::ds:Signature)", root, "true"},
+		{"count(ancestor-or-self::ds:Signature | here()/ancestor::ds:Signature[1]) > count(ancestor-or-self::ds:Signature)", signature, "false"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.expression, func(t *testing.T) {
+			actual := evaluateToString(t, ctx, tt.expression, tt.node)
+			if actual != tt.expected {
+				t.Errorf("expected %q, got %q", tt.expected, actual)
+			}
+		})
+	}
+
+	// here() is only available when the context provides it
+	expr, err := Compile("here()")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = expr.Evaluate(&Context{}, root)
+	if err == nil {
+		t.Error("expected here() without a context node to fail")
+	}
+}
+
+func Test_Evaluate_NamespaceBindings(t *testing.T) {
+	doc := parseTestDocument(t, `<doc xmlns="urn:default" xmlns:x="urn:x"><x:v>1</x:v><v>2</v></doc>`)
+	root, _ := NewTokenNode(&doc.Element)
+
+	// Prefixes are bound by the XPath element, not by the document
+	xpathDoc := parseTestDocument(t, `<XPath xmlns="urn:other" xmlns:p="urn:x" xmlns:d="urn:default">expression</XPath>`)
+	ctx := &Context{Namespaces: InScopeNamespaces(xpathDoc.Root())}
+
+	tests := []struct {
+		expression string
+		expected   string
+	}{
+		{"/d:doc/p:v", "x:v#"},
+		{"/d:doc/d:v", "v#"},
+		{"count(/doc)", "0"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.expression, func(t *testing.T) {
+			actual := evaluateToString(t, ctx, tt.expression, root)
+			if actual != tt.expected {
+				t.Errorf("expected %q, got %q", tt.expected, actual)
+			}
+		})
+	}
+
+	// The document prefix is not bound by the XPath element
+	expr, err := Compile("/d:doc/x:v")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = expr.Evaluate(ctx, root)
+	if err == nil {
+		t.Error("expected an undeclared prefix to fail")
+	}
+}
+
+func Test_Compile_Errors(t *testing.T) {
+	for _, expression := range []string{"", "/doc[", "//", "unknown-function()", "child::", "'unterminated"} {
+		t.Run(expression, func(t *testing.T) {
+			expr, err := Compile(expression)
+			if err == nil {
+				// Unknown functions are reported when they are evaluated
+				_, err = expr.Evaluate(&Context{}, Node{Type: RootNode, Token: etree.NewDocument().Element.Copy()})
+			}
+			if err == nil {
+				t.Errorf("expected %q to fail", expression)
+			}
+		})
+	}
+}
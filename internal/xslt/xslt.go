@@ -0,0 +1,549 @@
+// Package xslt implements a subset of XSLT 1.0, sufficient for the stylesheets
+// used by "what you see is what you sign" signatures.
+//
+// Supported are templates with match patterns and priorities, literal result
+// elements with attribute value templates, xsl:apply-templates, xsl:value-of,
+// xsl:copy-of, xsl:if, xsl:choose, xsl:for-each, xsl:text and the xml and text
+// output methods. Expressions are evaluated with the namespaces in scope of the
+// stylesheet element.
+package xslt
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/beevik/etree"
+	"github.com/deb-ict/go-xmldsig/canonicalizer"
+	"github.com/deb-ict/go-xmldsig/internal/xpath"
+)
+
+const (
+	XslNamespaceUri string = "http://www.w3.org/1999/XSL/Transform"
+
+	maxDepth int = 1000
+)
+
+var (
+	qnamePattern    = regexp.MustCompile(`^(@|child::|attribute::)?[\pL_][\pL\pN_.-]*(:[\pL_][\pL\pN_.-]*)?$`)
+	wildcardPattern = regexp.MustCompile(`^(@|child::|attribute::)?[\pL_][\pL\pN_.-]*:\*$`)
+	nodeTestPattern = regexp.MustCompile(`^(@|child::|attribute::)?(\*|node\(\)|text\(\)|comment\(\)|processing-instruction\(\))$`)
+	piPattern       = regexp.MustCompile(`^(child::)?processing-instruction\(\s*('[^']*'|"[^"]*")\s*\)$`)
+)
+
+type Stylesheet struct {
+	templates          []*template
+	namespaces         map[string]string
+	method             string
+	omitXmlDeclaration bool
+}
+
+type template struct {
+	body     *etree.Element
+	patterns []*pattern
+}
+
+type pattern struct {
+	expression *xpath.Expr
+	priority   float64
+}
+
+// Compile compiles an xsl:stylesheet or xsl:transform element.
+func Compile(el *etree.Element) (*Stylesheet, error) {
+	if el == nil || el.NamespaceURI() != XslNamespaceUri || (el.Tag != "stylesheet" && el.Tag != "transform") {
+		return nil, errors.New("xslt: element is not an xsl:stylesheet element")
+	}
+
+	s := &Stylesheet{
+		templates:  make([]*template, 0),
+		namespaces: xpath.InScopeNamespaces(el),
+		method:     "xml",
+	}
+	for _, child := range el.ChildElements() {
+		if child.NamespaceURI() != XslNamespaceUri {
+			continue
+		}
+		switch child.Tag {
+		case "template":
+			t, err := compileTemplate(child)
+			if err != nil {
+				return nil, err
+			}
+			s.templates = append(s.templates, t)
+		case "output":
+			s.method = child.SelectAttrValue("method", "xml")
+			s.omitXmlDeclaration = child.SelectAttrValue("omit-xml-declaration", "no") == "yes"
+			if s.method != "xml" && s.method != "text" {
+				return nil, fmt.Errorf("xslt: unsupported output method: %s", s.method)
+			}
+		default:
+			return nil, fmt.Errorf("xslt: unsupported top-level element: xsl:%s", child.Tag)
+		}
+	}
+	return s, nil
+}
+
+func compileTemplate(el *etree.Element) (*template, error) {
+	if el.SelectAttr("mode") != nil || el.SelectAttr("name") != nil {
+		return nil, errors.New("xslt: named templates and modes are not supported")
+	}
+	match := el.SelectAttr("match")
+	if match == nil {
+		return nil, errors.New("xslt: template does not contain a match pattern")
+	}
+
+	t := &template{
+		body:     el,
+		patterns: make([]*pattern, 0),
+	}
+	for _, alternative := range splitPattern(match.Value) {
+		expression, err := xpath.Compile(alternative)
+		if err != nil {
+			return nil, err
+		}
+		p := &pattern{
+			expression: expression,
+			priority:   defaultPriority(alternative),
+		}
+		if priority := el.SelectAttr("priority"); priority != nil {
+			value, err := strconv.ParseFloat(strings.TrimSpace(priority.Value), 64)
+			if err != nil {
+				return nil, fmt.Errorf("xslt: invalid template priority: %s", priority.Value)
+			}
+			p.priority = value
+		}
+		t.patterns = append(t.patterns, p)
+	}
+	return t, nil
+}
+
+// Transform applies the stylesheet to the document and serializes the result
+// tree using the output method of the stylesheet.
+func (s *Stylesheet) Transform(doc *etree.Document) ([]byte, error) {
+	p := &processor{
+		stylesheet: s,
+		context: &xpath.Context{
+			Namespaces: s.namespaces,
+		},
+		expressions: map[string]*xpath.Expr{},
+	}
+
+	result := etree.NewDocument()
+	root, _ := xpath.NewTokenNode(&doc.Element)
+	err := p.applyTemplates([]xpath.Node{root}, &result.Element, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	var buffer bytes.Buffer
+	if s.method == "text" {
+		writeText(&buffer, &result.Element)
+		return buffer.Bytes(), nil
+	}
+	if !s.omitXmlDeclaration {
+		buffer.WriteString("<?xml version=\"1.0\" encoding=\"UTF-8\"?>\n")
+	}
+	_, err = result.WriteTo(&buffer)
+	if err != nil {
+		return nil, err
+	}
+	return buffer.Bytes(), nil
+}
+
+type processor struct {
+	stylesheet  *Stylesheet
+	context     *xpath.Context
+	expressions map[string]*xpath.Expr
+}
+
+func (p *processor) applyTemplates(nodes []xpath.Node, out *etree.Element, depth int) error {
+	if depth > maxDepth {
+		return errors.New("xslt: maximum template depth exceeded")
+	}
+	for _, node := range nodes {
+		t, err := p.findTemplate(node)
+		if err != nil {
+			return err
+		}
+		if t != nil {
+			err = p.execute(t.body, node, out, depth+1)
+			if err != nil {
+				return err
+			}
+			continue
+		}
+
+		// Built-in templates
+		switch node.Type {
+		case xpath.RootNode, xpath.ElementNode:
+			children, err := p.selectNodes("node()", node)
+			if err != nil {
+				return err
+			}
+			err = p.applyTemplates(children, out, depth+1)
+			if err != nil {
+				return err
+			}
+		case xpath.TextNode, xpath.AttributeNode:
+			out.CreateText(node.StringValue())
+		}
+	}
+	return nil
+}
+
+// findTemplate returns the matching template with the highest priority, the
+// last one in the stylesheet wins a conflict.
+func (p *processor) findTemplate(node xpath.Node) (*template, error) {
+	var found *template
+	var foundPriority float64
+	for _, t := range p.stylesheet.templates {
+		for _, pt := range t.patterns {
+			if found != nil && pt.priority < foundPriority {
+				continue
+			}
+			matched, err := p.matches(pt, node)
+			if err != nil {
+				return nil, err
+			}
+			if matched {
+				found = t
+				foundPriority = pt.priority
+			}
+		}
+	}
+	return found, nil
+}
+
+// matches reports whether the node is selected by the pattern evaluated with
+// the node or one of its ancestors as context node.
+func (p *processor) matches(pt *pattern, node xpath.Node) (bool, error) {
+	for current, ok := node, true; ok; current, ok = current.Parent() {
+		nodes, err := pt.expression.EvaluateNodeSet(p.context, current)
+		if err != nil {
+			return false, err
+		}
+		for _, selected := range nodes {
+			if selected == node {
+				return true, nil
+			}
+		}
+	}
+	return false, nil
+}
+
+func (p *processor) execute(body *etree.Element, node xpath.Node, out *etree.Element, depth int) error {
+	for _, child := range body.Child {
+		switch t := child.(type) {
+		case *etree.CharData:
+			// Whitespace only text is stripped from the stylesheet
+			if strings.TrimSpace(t.Data) != "" {
+				out.CreateText(t.Data)
+			}
+		case *etree.Element:
+			var err error
+			if t.NamespaceURI() == XslNamespaceUri {
+				err = p.executeInstruction(t, node, out, depth)
+			} else {
+				err = p.executeLiteral(t, node, out, depth)
+			}
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (p *processor) executeInstruction(el *etree.Element, node xpath.Node, out *etree.Element, depth int) error {
+	switch el.Tag {
+	case "apply-templates":
+		if len(el.ChildElements()) > 0 {
+			return errors.New("xslt: xsl:apply-templates parameters and sorting are not supported")
+		}
+		if el.SelectAttr("mode") != nil {
+			return errors.New("xslt: named templates and modes are not supported")
+		}
+		nodes, err := p.selectNodes(el.SelectAttrValue("select", "node()"), node)
+		if err != nil {
+			return err
+		}
+		return p.applyTemplates(nodes, out, depth+1)
+	case "value-of":
+		value, err := p.evaluateString(el.SelectAttrValue("select", ""), node)
+		if err != nil {
+			return err
+		}
+		if value != "" {
+			out.CreateText(value)
+		}
+	case "copy-of":
+		value, err := p.evaluate(el.SelectAttrValue("select", ""), node)
+		if err != nil {
+			return err
+		}
+		nodes, ok := value.([]xpath.Node)
+		if !ok {
+			text, err := p.evaluateString(el.SelectAttrValue("select", ""), node)
+			if err != nil {
+				return err
+			}
+			out.CreateText(text)
+			return nil
+		}
+		for _, n := range nodes {
+			err := copyNode(n, out)
+			if err != nil {
+				return err
+			}
+		}
+	case "if":
+		test, err := p.evaluateBoolean(el.SelectAttrValue("test", ""), node)
+		if err != nil {
+			return err
+		}
+		if test {
+			return p.execute(el, node, out, depth+1)
+		}
+	case "choose":
+		for _, branch := range el.ChildElements() {
+			if branch.NamespaceURI() != XslNamespaceUri {
+				continue
+			}
+			switch branch.Tag {
+			case "when":
+				test, err := p.evaluateBoolean(branch.SelectAttrValue("test", ""), node)
+				if err != nil {
+					return err
+				}
+				if test {
+					return p.execute(branch, node, out, depth+1)
+				}
+			case "otherwise":
+				return p.execute(branch, node, out, depth+1)
+			}
+		}
+	case "for-each":
+		nodes, err := p.selectNodes(el.SelectAttrValue("select", ""), node)
+		if err != nil {
+			return err
+		}
+		for _, n := range nodes {
+			err := p.execute(el, n, out, depth+1)
+			if err != nil {
+				return err
+			}
+		}
+	case "text":
+		if text := el.Text(); text != "" {
+			out.CreateText(text)
+		}
+	default:
+		return fmt.Errorf("xslt: unsupported instruction: xsl:%s", el.Tag)
+	}
+	return nil
+}
+
+func (p *processor) executeLiteral(el *etree.Element, node xpath.Node, out *etree.Element, depth int) error {
+	result := out.CreateElement(el.Tag)
+	result.Space = el.Space
+
+	// Declare the namespaces used by the element that are not in scope of the result
+	inScope := xpath.InScopeNamespaces(out)
+	declare := func(prefix string) {
+		if prefix == "xml" {
+			return
+		}
+		uri := p.stylesheet.namespaceUri(el, prefix)
+		if uri == XslNamespaceUri || inScope[prefix] == uri {
+			return
+		}
+		if prefix == "" {
+			result.CreateAttr("xmlns", uri)
+		} else {
+			result.CreateAttr("xmlns:"+prefix, uri)
+		}
+		inScope[prefix] = uri
+	}
+	declare(el.Space)
+	for _, attr := range el.Attr {
+		if xpath.IsNamespaceAttr(&attr) || attr.Space == "" {
+			continue
+		}
+		declare(attr.Space)
+	}
+
+	for _, attr := range el.Attr {
+		if xpath.IsNamespaceAttr(&attr) || attr.NamespaceURI() == XslNamespaceUri {
+			continue
+		}
+		value, err := p.evaluateAttributeValueTemplate(attr.Value, node)
+		if err != nil {
+			return err
+		}
+		result.CreateAttr(attr.FullKey(), value)
+	}
+	return p.execute(el, node, result, depth+1)
+}
+
+func (p *processor) evaluateAttributeValueTemplate(value string, node xpath.Node) (string, error) {
+	var sb strings.Builder
+	for i := 0; i < len(value); i++ {
+		c := value[i]
+		switch {
+		case c == '{' && i+1 < len(value) && value[i+1] == '{':
+			sb.WriteByte('{')
+			i++
+		case c == '}' && i+1 < len(value) && value[i+1] == '}':
+			sb.WriteByte('}')
+			i++
+		case c == '{':
+			end := strings.IndexByte(value[i:], '}')
+			if end < 0 {
+				return "", fmt.Errorf("xslt: invalid attribute value template: %s", value)
+			}
+			result, err := p.evaluateString(value[i+1:i+end], node)
+			if err != nil {
+				return "", err
+			}
+			sb.WriteString(result)
+			i += end
+		default:
+			sb.WriteByte(c)
+		}
+	}
+	return sb.String(), nil
+}
+
+func (p *processor) compile(expression string) (*xpath.Expr, error) {
+	if e, ok := p.expressions[expression]; ok {
+		return e, nil
+	}
+	e, err := xpath.Compile(expression)
+	if err != nil {
+		return nil, err
+	}
+	p.expressions[expression] = e
+	return e, nil
+}
+
+func (p *processor) evaluate(expression string, node xpath.Node) (interface{}, error) {
+	e, err := p.compile(expression)
+	if err != nil {
+		return nil, err
+	}
+	return e.Evaluate(p.context, node)
+}
+
+func (p *processor) evaluateString(expression string, node xpath.Node) (string, error) {
+	e, err := p.compile(expression)
+	if err != nil {
+		return "", err
+	}
+	return e.EvaluateString(p.context, node)
+}
+
+func (p *processor) evaluateBoolean(expression string, node xpath.Node) (bool, error) {
+	e, err := p.compile(expression)
+	if err != nil {
+		return false, err
+	}
+	return e.EvaluateBoolean(p.context, node)
+}
+
+func (p *processor) selectNodes(expression string, node xpath.Node) ([]xpath.Node, error) {
+	e, err := p.compile(expression)
+	if err != nil {
+		return nil, err
+	}
+	return e.EvaluateNodeSet(p.context, node)
+}
+
+// namespaceUri resolves a prefix of a literal result element, the namespaces
+// declared in the template take precedence over the stylesheet namespaces.
+func (s *Stylesheet) namespaceUri(el *etree.Element, prefix string) string {
+	if uri, ok := xpath.InScopeNamespaces(el)[prefix]; ok {
+		return uri
+	}
+	return s.namespaces[prefix]
+}
+
+func copyNode(node xpath.Node, out *etree.Element) error {
+	switch node.Type {
+	case xpath.RootNode:
+		for _, child := range node.Element().ChildElements() {
+			copied, err := canonicalizer.NewNodeSet(child).Element()
+			if err != nil {
+				return err
+			}
+			out.AddChild(copied)
+		}
+	case xpath.ElementNode:
+		copied, err := canonicalizer.NewNodeSet(node.Element()).Element()
+		if err != nil {
+			return err
+		}
+		out.AddChild(copied)
+	case xpath.AttributeNode:
+		out.CreateAttr(node.Attr.FullKey(), node.Attr.Value)
+	case xpath.TextNode:
+		out.CreateText(node.StringValue())
+	case xpath.CommentNode:
+		out.CreateComment(node.StringValue())
+	case xpath.ProcessingInstructionNode:
+		out.CreateProcInst(node.LocalName(), node.StringValue())
+	}
+	return nil
+}
+
+// splitPattern splits a pattern into its alternatives
+func splitPattern(s string) []string {
+	alternatives := make([]string, 0)
+	depth := 0
+	var quote byte
+	start := 0
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '\'' || c == '"':
+			quote = c
+		case c == '[' || c == '(':
+			depth++
+		case c == ']' || c == ')':
+			depth--
+		case c == '|' && depth == 0:
+			alternatives = append(alternatives, strings.TrimSpace(s[start:i]))
+			start = i + 1
+		}
+	}
+	return append(alternatives, strings.TrimSpace(s[start:]))
+}
+
+func defaultPriority(alternative string) float64 {
+	switch {
+	case qnamePattern.MatchString(alternative), piPattern.MatchString(alternative):
+		return 0
+	case wildcardPattern.MatchString(alternative):
+		return -0.25
+	case nodeTestPattern.MatchString(alternative):
+		return -0.5
+	}
+	return 0.5
+}
+
+func writeText(buffer *bytes.Buffer, el *etree.Element) {
+	for _, child := range el.Child {
+		switch t := child.(type) {
+		case *etree.CharData:
+			buffer.WriteString(t.Data)
+		case *etree.Element:
+			writeText(buffer, t)
+		}
+	}
+}
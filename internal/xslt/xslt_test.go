@@ -0,0 +1,139 @@
+package xslt
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/beevik/etree"
+)
+
+const testDocument = `<order xmlns="urn:order" id="42">` +
+	`<line qty="2"><name>Apple</name><price>0.50</price></line>` +
+	`<line qty="1"><name>Pear</name><price>0.75</price></line>` +
+	`<note>Deliver <b>today</b></note>` +
+	`</order>`
+
+func parseTestDocument(t *testing.T, s string) *etree.Document {
+	t.Helper()
+	doc := etree.NewDocument()
+	err := doc.ReadFromString(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return doc
+}
+
+func transformToString(t *testing.T, stylesheet string, document string) string {
+	t.Helper()
+	compiled, err := Compile(parseTestDocument(t, stylesheet).Root())
+	if err != nil {
+		t.Fatalf("compile: %v", err)
+	}
+	result, err := compiled.Transform(parseTestDocument(t, document))
+	if err != nil {
+		t.Fatalf("transform: %v", err)
+	}
+	return string(result)
+}
+
+func Test_Stylesheet_Transform(t *testing.T) {
+	tests := []struct {
+		name     string
+		body     string
+		expected string
+	}{
+		{
+			name:     "BuiltInTemplates",
+			body:     `<xsl:output method="text"/>`,
+			expected: "Apple0.50Pear0.75Deliver today",
+		},
+		{
+			name: "ValueOfAndForEach",
+			body: `<xsl:output method="text"/>` +
+				`<xsl:template match="/">Order <xsl:value-of select="o:order/@id"/>:<xsl:for-each select="//o:line"><xsl:text> </xsl:text><xsl:value-of select="o:name"/>=<xsl:value-of select="@qty"/></xsl:for-each></xsl:template>`,
+			expected: "Order 42: Apple=2 Pear=1",
+		},
+		{
+			name: "ApplyTemplatesWithPriority",
+			body: `<xsl:output method="text"/>` +
+				`<xsl:template match="/"><xsl:apply-templates select="//o:line"/></xsl:template>` +
+				`<xsl:template match="o:line">[line]</xsl:template>` +
+				`<xsl:template match="o:line[@qty = 1]">[single]</xsl:template>`,
+			expected: "[line][single]",
+		},
+		{
+			name: "IfAndChoose",
+			body: `<xsl:output method="text"/>` +
+				`<xsl:template match="/"><xsl:for-each select="//o:line">` +
+				`<xsl:if test="@qty &gt; 1">many </xsl:if>` +
+				`<xsl:choose><xsl:when test="o:name = 'Apple'">apple</xsl:when><xsl:otherwise>other</xsl:otherwise></xsl:choose>` +
+				`<xsl:text>;</xsl:text>` +
+				`</xsl:for-each></xsl:template>`,
+			expected: "many apple;other;",
+		},
+		{
+			name: "LiteralResultElements",
+			body: `<xsl:output method="xml" omit-xml-declaration="yes"/>` +
+				`<xsl:template match="/"><html><xsl:for-each select="//o:line"><p class="line-{@qty}"><xsl:value-of select="o:name"/></p></xsl:for-each></html></xsl:template>`,
+			expected: `<html><p class="line-2">Apple</p><p class="line-1">Pear</p></html>`,
+		},
+		{
+			name: "CopyOf",
+			body: `<xsl:output method="xml" omit-xml-declaration="yes"/>` +
+				`<xsl:template match="/"><doc><xsl:copy-of select="//o:note"/></doc></xsl:template>`,
+			expected: `<doc><note xmlns="urn:order">Deliver <b>today</b></note></doc>`,
+		},
+		{
+			name:     "XmlDeclaration",
+			body:     `<xsl:template match="/"><doc/></xsl:template>`,
+			expected: "<?xml version=\"1.0\" encoding=\"UTF-8\"?>\n<doc/>",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stylesheet := `<xsl:stylesheet version="1.0" xmlns:xsl="http://www.w3.org/1999/XSL/Transform" xmlns:o="urn:order">` + tt.body + `</xsl:stylesheet>`
+			actual := transformToString(t, stylesheet, testDocument)
+			if actual != tt.expected {
+				t.Errorf("expected %q, got %q", tt.expected, actual)
+			}
+		})
+	}
+}
+
+func Test_Compile_Errors(t *testing.T) {
+	tests := []struct {
+		name       string
+		stylesheet string
+	}{
+		{"NotAStylesheet", `<stylesheet/>`},
+		{"UnsupportedMethod", `<xsl:stylesheet xmlns:xsl="http://www.w3.org/1999/XSL/Transform"><xsl:output method="html"/></xsl:stylesheet>`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Compile(parseTestDocument(t, tt.stylesheet).Root())
+			if err == nil {
+				t.Error("expected the stylesheet to be rejected")
+			}
+		})
+	}
+
+	_, err := Compile(nil)
+	if err == nil {
+		t.Error("expected a nil stylesheet to be rejected")
+	}
+}
+
+func Test_Stylesheet_MaximumDepth(t *testing.T) {
+	// A template that applies itself does not recurse without bound
+	stylesheet := `<xsl:stylesheet version="1.0" xmlns:xsl="http://www.w3.org/1999/XSL/Transform">` +
+		`<xsl:template match="/"><xsl:apply-templates select="."/></xsl:template>` +
+		`</xsl:stylesheet>`
+	compiled, err := Compile(parseTestDocument(t, stylesheet).Root())
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = compiled.Transform(parseTestDocument(t, testDocument))
+	if err == nil || !strings.Contains(err.Error(), "depth") {
+		t.Errorf("expected the maximum depth to be exceeded, got %v", err)
+	}
+}
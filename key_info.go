@@ -0,0 +1,277 @@
+package xmldsig
+
+import (
+	"crypto/x509"
+	"errors"
+
+	"github.com/beevik/etree"
+	"github.com/deb-ict/go-xmldsig/canonicalizer"
+)
+
+type KeyInfo struct {
+	Id                      string
+	KeyNames                []string
+	KeyValues               []*KeyValue
+	RetrievalMethods        []*RetrievalMethod
+	X509Data                []*X509Data
+	SecurityTokenReferences []*SecurityTokenReference
+	Elements                []*etree.Element
+	children                []keyInfoChild
+	signature               *Signature
+	cachedXml               *etree.Element
+}
+
+// keyInfoChild is the position of a child in the document order, as an index
+// into the slice of its type.
+type keyInfoChild struct {
+	tag   string
+	index int
+}
+
+func newKeyInfo(signature *Signature) *KeyInfo {
+	return &KeyInfo{
+		signature: signature,
+	}
+}
+
+func NewKeyInfo() *KeyInfo {
+	return newKeyInfo(nil)
+}
+
+func (xml *KeyInfo) WithId(id string) *KeyInfo {
+	xml.Id = id
+	return xml
+}
+
+func (xml *KeyInfo) WithKeyName(keyName string) *KeyInfo {
+	xml.addChild("KeyName", len(xml.KeyNames))
+	xml.KeyNames = append(xml.KeyNames, keyName)
+	return xml
+}
+
+func (xml *KeyInfo) WithKeyValue(keyValue *KeyValue) *KeyInfo {
+	if keyValue != nil {
+		keyValue.keyInfo = xml
+		xml.addChild("KeyValue", len(xml.KeyValues))
+		xml.KeyValues = append(xml.KeyValues, keyValue)
+	}
+	return xml
+}
+
+func (xml *KeyInfo) WithRetrievalMethod(retrievalMethod *RetrievalMethod) *KeyInfo {
+	if retrievalMethod != nil {
+		retrievalMethod.bind(xml)
+		xml.addChild("RetrievalMethod", len(xml.RetrievalMethods))
+		xml.RetrievalMethods = append(xml.RetrievalMethods, retrievalMethod)
+	}
+	return xml
+}
+
+func (xml *KeyInfo) WithX509Data(x509Data *X509Data) *KeyInfo {
+	if x509Data != nil {
+		x509Data.keyInfo = xml
+		xml.addChild("X509Data", len(xml.X509Data))
+		xml.X509Data = append(xml.X509Data, x509Data)
+	}
+	return xml
+}
+
+func (xml *KeyInfo) WithSecurityTokenReference(securityTokenReference *SecurityTokenReference) *KeyInfo {
+	if securityTokenReference != nil {
+		securityTokenReference.keyInfo = xml
+		xml.addChild("SecurityTokenReference", len(xml.SecurityTokenReferences))
+		xml.SecurityTokenReferences = append(xml.SecurityTokenReferences, securityTokenReference)
+	}
+	return xml
+}
+
+// WithElement adds a child element that is not modelled, such as a
+// DEREncodedKeyValue or an extension element.
+func (xml *KeyInfo) WithElement(el *etree.Element) *KeyInfo {
+	if el != nil {
+		xml.addChild("", len(xml.Elements))
+		xml.Elements = append(xml.Elements, el)
+	}
+	return xml
+}
+
+func (xml *KeyInfo) GetCertificate() (*x509.Certificate, error) {
+	if len(xml.X509Data) > 0 {
+		certificates, err := xml.X509Data[0].GetCertificates()
+		if err != nil {
+			return nil, err
+		}
+		if len(certificates) != 1 {
+			return nil, errors.New("key info does not contain a single X509Certificate element")
+		}
+		return certificates[0], nil
+	}
+
+	if len(xml.SecurityTokenReferences) > 0 {
+		return xml.SecurityTokenReferences[0].GetCertificate()
+	}
+
+	return nil, errors.New("certificate not found")
+}
+
+func (xml *KeyInfo) root() *SignedXml {
+	if xml.signature == nil {
+		return nil
+	}
+	return xml.signature.root()
+}
+
+func (xml *KeyInfo) bind(signature *Signature) {
+	xml.signature = signature
+	for _, keyValue := range xml.KeyValues {
+		keyValue.keyInfo = xml
+	}
+	for _, retrievalMethod := range xml.RetrievalMethods {
+		retrievalMethod.bind(xml)
+	}
+	for _, x509Data := range xml.X509Data {
+		x509Data.keyInfo = xml
+	}
+	for _, securityTokenReference := range xml.SecurityTokenReferences {
+		securityTokenReference.keyInfo = xml
+	}
+}
+
+func (xml *KeyInfo) addChild(tag string, index int) {
+	xml.children = append(xml.children, keyInfoChild{tag: tag, index: index})
+}
+
+func (xml *KeyInfo) loadXml(el *etree.Element) error {
+	err := validateElement(el, "KeyInfo", XmlDSigNamespaceUri)
+	if err != nil {
+		return err
+	}
+
+	xml.Id = el.SelectAttrValue("Id", "")
+
+	for _, childElement := range el.ChildElements() {
+		switch {
+		case childElement.Tag == "KeyName" && childElement.NamespaceURI() == XmlDSigNamespaceUri:
+			xml.WithKeyName(childElement.Text())
+		case childElement.Tag == "KeyValue" && childElement.NamespaceURI() == XmlDSigNamespaceUri:
+			keyValue := newKeyValue(xml)
+			err := keyValue.loadXml(childElement)
+			if err != nil {
+				return err
+			}
+			xml.addChild("KeyValue", len(xml.KeyValues))
+			xml.KeyValues = append(xml.KeyValues, keyValue)
+		case childElement.Tag == "RetrievalMethod" && childElement.NamespaceURI() == XmlDSigNamespaceUri:
+			retrievalMethod := newRetrievalMethod(xml)
+			err := retrievalMethod.loadXml(childElement)
+			if err != nil {
+				return err
+			}
+			xml.addChild("RetrievalMethod", len(xml.RetrievalMethods))
+			xml.RetrievalMethods = append(xml.RetrievalMethods, retrievalMethod)
+		case childElement.Tag == "X509Data" && childElement.NamespaceURI() == XmlDSigNamespaceUri:
+			x509Data := newX509Data(xml)
+			err := x509Data.loadXml(childElement)
+			if err != nil {
+				return err
+			}
+			xml.addChild("X509Data", len(xml.X509Data))
+			xml.X509Data = append(xml.X509Data, x509Data)
+		case childElement.Tag == "SecurityTokenReference" && childElement.NamespaceURI() == WsseNamespaceUri:
+			securityTokenReference := newSecurityTokenReference(xml)
+			err := securityTokenReference.loadXml(childElement)
+			if err != nil {
+				return err
+			}
+			xml.addChild("SecurityTokenReference", len(xml.SecurityTokenReferences))
+			xml.SecurityTokenReferences = append(xml.SecurityTokenReferences, securityTokenReference)
+		default:
+			// Children that are not modelled are kept, so they are serialized again
+			xml.WithElement(canonicalizer.DetachElement(childElement))
+		}
+	}
+
+	xml.cachedXml = el
+	return nil
+}
+
+func (xml *KeyInfo) getXml() (*etree.Element, error) {
+	el := etree.NewElement("KeyInfo")
+	el.Space = xml.root().getElementSpace(XmlDSigNamespaceUri)
+
+	if xml.Id != "" {
+		el.CreateAttr("Id", xml.Id)
+	}
+
+	// Children are written in the order they were loaded or added, followed by
+	// the children that were added to the slices directly
+	written := map[keyInfoChild]bool{}
+	for _, child := range xml.children {
+		if written[child] {
+			continue
+		}
+		childElement, err := xml.getChildXml(child)
+		if err != nil {
+			return nil, err
+		}
+		if childElement != nil {
+			el.AddChild(childElement)
+			written[child] = true
+		}
+	}
+	for _, tag := range []string{"KeyName", "KeyValue", "RetrievalMethod", "X509Data", "SecurityTokenReference", ""} {
+		for index := 0; index < xml.countChildren(tag); index++ {
+			child := keyInfoChild{tag: tag, index: index}
+			if written[child] {
+				continue
+			}
+			childElement, err := xml.getChildXml(child)
+			if err != nil {
+				return nil, err
+			}
+			el.AddChild(childElement)
+		}
+	}
+
+	return el, nil
+}
+
+func (xml *KeyInfo) countChildren(tag string) int {
+	switch tag {
+	case "KeyName":
+		return len(xml.KeyNames)
+	case "KeyValue":
+		return len(xml.KeyValues)
+	case "RetrievalMethod":
+		return len(xml.RetrievalMethods)
+	case "X509Data":
+		return len(xml.X509Data)
+	case "SecurityTokenReference":
+		return len(xml.SecurityTokenReferences)
+	}
+	return len(xml.Elements)
+}
+
+// getChildXml returns the element of a child, or nil when the child was
+// removed from its slice.
+func (xml *KeyInfo) getChildXml(child keyInfoChild) (*etree.Element, error) {
+	if child.index >= xml.countChildren(child.tag) {
+		return nil, nil
+	}
+	switch child.tag {
+	case "KeyName":
+		keyNameElement := etree.NewElement("KeyName")
+		keyNameElement.Space = xml.root().getElementSpace(XmlDSigNamespaceUri)
+		keyNameElement.SetText(xml.KeyNames[child.index])
+		return keyNameElement, nil
+	case "KeyValue":
+		return xml.KeyValues[child.index].getXml()
+	case "RetrievalMethod":
+		return xml.RetrievalMethods[child.index].getXml()
+	case "X509Data":
+		return xml.X509Data[child.index].getXml()
+	case "SecurityTokenReference":
+		return xml.SecurityTokenReferences[child.index].getXml()
+	}
+	return xml.Elements[child.index].Copy(), nil
+}
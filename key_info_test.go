@@ -0,0 +1,107 @@
+package xmldsig
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"errors"
+	"testing"
+)
+
+func Test_KeyInfo_RoundTrip(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyValue, err := NewKeyValue(key.Public())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Build the key info, serialize it as part of a signature and load it again
+	doc := parseTestDocument(t, `<Document xmlns:dsig11="http://www.w3.org/2009/xmldsig11#"><ds:KeyInfo xmlns:ds="http://www.w3.org/2000/09/xmldsig#">`+
+		`<ds:KeyName>first</ds:KeyName>`+
+		`<dsig11:DEREncodedKeyValue>AAAA</dsig11:DEREncodedKeyValue>`+
+		`<ds:KeyValue><ds:RSAKeyValue><ds:Modulus>`+keyValue.RSAKeyValue.Modulus+`</ds:Modulus><ds:Exponent>`+keyValue.RSAKeyValue.Exponent+`</ds:Exponent></ds:RSAKeyValue></ds:KeyValue>`+
+		`<ext:Extension xmlns:ext="urn:ext" a="1"><ext:Value>x</ext:Value></ext:Extension>`+
+		`<ds:KeyName>second</ds:KeyName>`+
+		`</ds:KeyInfo></Document>`)
+	keyInfo := newKeyInfo(nil)
+	err = keyInfo.loadXml(doc.Root().SelectElement("KeyInfo"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(keyInfo.KeyNames) != 2 || len(keyInfo.KeyValues) != 1 || len(keyInfo.Elements) != 2 {
+		t.Fatalf("unexpected key info children: %d key names, %d key values, %d elements", len(keyInfo.KeyNames), len(keyInfo.KeyValues), len(keyInfo.Elements))
+	}
+
+	el, err := keyInfo.getXml()
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []struct {
+		tag   string
+		space string
+	}{
+		{"KeyName", ""},
+		{"DEREncodedKeyValue", "dsig11"},
+		{"KeyValue", ""},
+		{"Extension", "ext"},
+		{"KeyName", ""},
+	}
+	children := el.ChildElements()
+	if len(children) != len(expected) {
+		t.Fatalf("expected %d children, got %d", len(expected), len(children))
+	}
+	for i, child := range children {
+		if child.Tag != expected[i].tag || child.Space != expected[i].space {
+			t.Errorf("child %d: expected %s:%s, got %s:%s", i, expected[i].space, expected[i].tag, child.Space, child.Tag)
+		}
+	}
+
+	// Unmodelled children keep the namespaces declared on their ancestors
+	if children[1].NamespaceURI() != XmlDSig11NamespaceUri {
+		t.Errorf("expected the DEREncodedKeyValue namespace to be declared, got %q", children[1].NamespaceURI())
+	}
+	if children[3].SelectAttrValue("a", "") != "1" || children[3].SelectElement("Value") == nil {
+		t.Error("expected the extension element to be serialized unchanged")
+	}
+	if children[4].Text() != "second" {
+		t.Errorf("expected the second key name, got %q", children[4].Text())
+	}
+}
+
+func Test_KeyInfo_ChildrenAddedToSlices(t *testing.T) {
+	keyInfo := NewKeyInfo().WithKeyName("added")
+	keyInfo.KeyNames = append(keyInfo.KeyNames, "appended")
+
+	el, err := keyInfo.getXml()
+	if err != nil {
+		t.Fatal(err)
+	}
+	children := el.ChildElements()
+	if len(children) != 2 || children[0].Text() != "added" || children[1].Text() != "appended" {
+		t.Errorf("expected the added and appended key names, got %d children", len(children))
+	}
+}
+
+func Test_KeyValue_DSAKeyValue(t *testing.T) {
+	doc := parseTestDocument(t, `<ds:KeyValue xmlns:ds="http://www.w3.org/2000/09/xmldsig#"><ds:DSAKeyValue><ds:P>AA==</ds:P><ds:Q>AA==</ds:Q><ds:G>AA==</ds:G><ds:Y>AA==</ds:Y></ds:DSAKeyValue></ds:KeyValue>`)
+	keyValue := newKeyValue(nil)
+	err := keyValue.loadXml(doc.Root())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = keyValue.GetPublicKey()
+	if !errors.Is(err, ErrUnsupportedDSAKeyValue) {
+		t.Errorf("expected ErrUnsupportedDSAKeyValue, got %v", err)
+	}
+
+	el, err := keyValue.getXml()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if el.SelectElement("DSAKeyValue") == nil {
+		t.Error("expected the DSAKeyValue to be serialized again")
+	}
+}
@@ -0,0 +1,149 @@
+package xmldsig
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/x509"
+	"encoding/base64"
+	"strings"
+)
+
+type KeySelector interface {
+	SelectKey(ctx context.Context, keyInfo *KeyInfo, signatureMethod *SignatureMethod) (crypto.PublicKey, error)
+}
+
+type KeySelectorFunc func(ctx context.Context, keyInfo *KeyInfo, signatureMethod *SignatureMethod) (crypto.PublicKey, error)
+
+func (f KeySelectorFunc) SelectKey(ctx context.Context, keyInfo *KeyInfo, signatureMethod *SignatureMethod) (crypto.PublicKey, error) {
+	return f(ctx, keyInfo, signatureMethod)
+}
+
+type fixedKeySelector struct {
+	key crypto.PublicKey
+}
+
+func NewFixedKeySelector(key crypto.PublicKey) KeySelector {
+	return &fixedKeySelector{
+		key: key,
+	}
+}
+
+func (s *fixedKeySelector) SelectKey(ctx context.Context, keyInfo *KeyInfo, signatureMethod *SignatureMethod) (crypto.PublicKey, error) {
+	if s.key == nil {
+		return nil, ErrKeyNotFound
+	}
+	return s.key, nil
+}
+
+type fixedCertificateSelector struct {
+	cert *x509.Certificate
+}
+
+func NewFixedCertificateSelector(cert *x509.Certificate) KeySelector {
+	return &fixedCertificateSelector{
+		cert: cert,
+	}
+}
+
+func (s *fixedCertificateSelector) SelectKey(ctx context.Context, keyInfo *KeyInfo, signatureMethod *SignatureMethod) (crypto.PublicKey, error) {
+	if s.cert == nil {
+		return nil, ErrKeyNotFound
+	}
+	return s.cert.PublicKey, nil
+}
+
+type keyInfoCertificateSelector struct {
+}
+
+// NewKeyInfoCertificateSelector selects the certificate embedded in or referenced
+// by the KeyInfo element. The certificate is not validated against a trust store,
+// the caller must verify it after the signature has been validated.
+func NewKeyInfoCertificateSelector() KeySelector {
+	return &keyInfoCertificateSelector{}
+}
+
+func (s *keyInfoCertificateSelector) SelectKey(ctx context.Context, keyInfo *KeyInfo, signatureMethod *SignatureMethod) (crypto.PublicKey, error) {
+	if keyInfo == nil {
+		return nil, ErrKeyNotFound
+	}
+	cert, err := keyInfo.GetCertificate()
+	if err != nil {
+		return nil, err
+	}
+	return cert.PublicKey, nil
+}
+
+type certificateSetSelector struct {
+	certs []*x509.Certificate
+}
+
+func NewCertificateSetSelector(certs ...*x509.Certificate) KeySelector {
+	return &certificateSetSelector{
+		certs: certs,
+	}
+}
+
+func (s *certificateSetSelector) SelectKey(ctx context.Context, keyInfo *KeyInfo, signatureMethod *SignatureMethod) (crypto.PublicKey, error) {
+	if keyInfo == nil {
+		if len(s.certs) == 1 {
+			return s.certs[0].PublicKey, nil
+		}
+		return nil, ErrKeyNotFound
+	}
+
+	for _, cert := range s.certs {
+		if s.matchCertificate(keyInfo, cert) {
+			return cert.PublicKey, nil
+		}
+	}
+	return nil, ErrKeyNotFound
+}
+
+func (s *certificateSetSelector) matchCertificate(keyInfo *KeyInfo, cert *x509.Certificate) bool {
+	for _, x509Data := range keyInfo.X509Data {
+		certificates, err := x509Data.GetCertificates()
+		if err == nil {
+			for _, certificate := range certificates {
+				if certificate.Equal(cert) {
+					return true
+				}
+			}
+		}
+		for _, issuerSerial := range x509Data.IssuerSerials {
+			if strings.TrimSpace(issuerSerial.SerialNumber) == cert.SerialNumber.String() && strings.TrimSpace(issuerSerial.IssuerName) == cert.Issuer.String() {
+				return true
+			}
+		}
+		for _, ski := range x509Data.SKIs {
+			skiData, err := base64.StdEncoding.DecodeString(strings.TrimSpace(ski))
+			if err == nil && len(cert.SubjectKeyId) > 0 && bytes.Equal(skiData, cert.SubjectKeyId) {
+				return true
+			}
+		}
+		for _, subjectName := range x509Data.SubjectNames {
+			if strings.TrimSpace(subjectName) == cert.Subject.String() {
+				return true
+			}
+		}
+	}
+
+	for _, securityTokenReference := range keyInfo.SecurityTokenReferences {
+		certificate, err := securityTokenReference.GetCertificate()
+		if err == nil && certificate.Equal(cert) {
+			return true
+		}
+	}
+
+	for _, keyValue := range keyInfo.KeyValues {
+		publicKey, err := keyValue.GetPublicKey()
+		if err != nil {
+			continue
+		}
+		if comparable, ok := publicKey.(interface{ Equal(crypto.PublicKey) bool }); ok && comparable.Equal(cert.PublicKey) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,157 @@
+package xmldsig
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/beevik/etree"
+	"github.com/deb-ict/go-xmldsig/transform"
+)
+
+func createTestCertificate(t *testing.T, commonName string, serialNumber int64) (*x509.Certificate, *rsa.PrivateKey) {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(serialNumber),
+		Subject:      pkix.Name{CommonName: commonName, Organization: []string{"Test"}},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		SubjectKeyId: []byte(commonName),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, key.Public(), key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cert, key
+}
+
+func Test_CertificateSetSelector(t *testing.T) {
+	cert, _ := createTestCertificate(t, "signer", 1001)
+	otherCert, _ := createTestCertificate(t, "other", 1002)
+	unknownCert, _ := createTestCertificate(t, "unknown", 1003)
+	keyValue, err := NewKeyValue(cert.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		keyInfo *KeyInfo
+		found   bool
+	}{
+		{"Certificate", NewKeyInfo().WithX509Data(NewX509Data().WithCertificate(cert)), true},
+		{"IssuerSerial", NewKeyInfo().WithX509Data(NewX509Data().WithIssuerSerial(cert)), true},
+		{"SKI", NewKeyInfo().WithX509Data(NewX509Data().WithSKI(cert)), true},
+		{"SubjectName", NewKeyInfo().WithX509Data(NewX509Data().WithSubjectName(cert)), true},
+		{"KeyValue", NewKeyInfo().WithKeyValue(keyValue), true},
+		{"NoMatch", NewKeyInfo().WithX509Data(NewX509Data().WithIssuerSerial(unknownCert).WithSKI(unknownCert).WithSubjectName(unknownCert)), false},
+		{"KeyName", NewKeyInfo().WithKeyName("signer"), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// The KeyInfo is read from its XML as it would be when validating
+			NewSignedXml(parseTestDocument(t, testDocument)).GetSignature().WithKeyInfo(tt.keyInfo)
+			el, err := tt.keyInfo.getXml()
+			if err != nil {
+				t.Fatal(err)
+			}
+			el.CreateAttr("xmlns:"+el.Space, XmlDSigNamespaceUri)
+			doc := etree.NewDocument()
+			doc.SetRoot(el)
+			serialized, err := doc.WriteToString()
+			if err != nil {
+				t.Fatal(err)
+			}
+			keyInfo := newKeyInfo(nil)
+			err = keyInfo.loadXml(parseTestDocument(t, serialized).Root())
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			key, err := NewCertificateSetSelector(otherCert, cert).SelectKey(context.Background(), keyInfo, nil)
+			if !tt.found {
+				if err != ErrKeyNotFound {
+					t.Errorf("expected ErrKeyNotFound, got %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !cert.PublicKey.(*rsa.PublicKey).Equal(key) {
+				t.Error("expected the key of the matching certificate")
+			}
+		})
+	}
+}
+
+func Test_CertificateSetSelector_WithoutKeyInfo(t *testing.T) {
+	cert, _ := createTestCertificate(t, "signer", 1001)
+	otherCert, _ := createTestCertificate(t, "other", 1002)
+	ctx := context.Background()
+
+	// A single certificate is used when the signature does not identify its key
+	key, err := NewCertificateSetSelector(cert).SelectKey(ctx, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !cert.PublicKey.(*rsa.PublicKey).Equal(key) {
+		t.Error("expected the key of the certificate")
+	}
+	_, err = NewCertificateSetSelector(cert, otherCert).SelectKey(ctx, nil, nil)
+	if err != ErrKeyNotFound {
+		t.Errorf("expected ErrKeyNotFound for multiple certificates, got %v", err)
+	}
+	_, err = NewCertificateSetSelector().SelectKey(ctx, nil, nil)
+	if err != ErrKeyNotFound {
+		t.Errorf("expected ErrKeyNotFound without certificates, got %v", err)
+	}
+}
+
+func Test_CertificateSetSelector_ValidateSignature(t *testing.T) {
+	ctx := context.Background()
+	cert, key := createTestCertificate(t, "signer", 1001)
+	otherCert, _ := createTestCertificate(t, "other", 1002)
+
+	doc := parseTestDocument(t, testDocument)
+	signedXml := NewSignedXml(doc)
+	signedXml.GetSignature().WithKeyInfo(NewKeyInfo().WithX509Data(NewX509Data().WithIssuerSerial(cert)))
+	_, err := signedXml.AddReference("", DigestMethod_SHA256, transform.EnvelopedSignatureTransform)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = signedXml.ComputeSignature(ctx, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signed, err := doc.WriteToString()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	loadedXml, err := LoadSignedXml(parseTestDocument(t, signed))
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = loadedXml.ValidateSignatureWithKeySelector(ctx, NewCertificateSetSelector(otherCert, cert))
+	if err != nil {
+		t.Errorf("validate: %v", err)
+	}
+	_, err = loadedXml.ValidateSignatureWithKeySelector(ctx, NewCertificateSetSelector(otherCert))
+	if err != ErrKeyNotFound {
+		t.Errorf("expected ErrKeyNotFound, got %v", err)
+	}
+}
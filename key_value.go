@@ -0,0 +1,225 @@
+package xmldsig
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"errors"
+	"math/big"
+	"strings"
+
+	"github.com/beevik/etree"
+	"github.com/deb-ict/go-xmldsig/canonicalizer"
+)
+
+var (
+	namedCurves map[string]elliptic.Curve = map[string]elliptic.Curve{
+		"urn:oid:1.2.840.10045.3.1.7": elliptic.P256(),
+		"urn:oid:1.3.132.0.34":        elliptic.P384(),
+		"urn:oid:1.3.132.0.35":        elliptic.P521(),
+	}
+)
+
+type RSAKeyValue struct {
+	Modulus  string
+	Exponent string
+}
+
+type ECKeyValue struct {
+	Id         string
+	NamedCurve string
+	PublicKey  string
+}
+
+type KeyValue struct {
+	RSAKeyValue *RSAKeyValue
+	ECKeyValue  *ECKeyValue
+	Element     *etree.Element
+	keyInfo     *KeyInfo
+	cachedXml   *etree.Element
+}
+
+func newKeyValue(keyInfo *KeyInfo) *KeyValue {
+	return &KeyValue{
+		keyInfo: keyInfo,
+	}
+}
+
+func NewKeyValue(publicKey crypto.PublicKey) (*KeyValue, error) {
+	keyValue := newKeyValue(nil)
+	switch key := publicKey.(type) {
+	case *rsa.PublicKey:
+		keyValue.RSAKeyValue = &RSAKeyValue{
+			Modulus:  base64.StdEncoding.EncodeToString(key.N.Bytes()),
+			Exponent: base64.StdEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+		}
+	case *ecdsa.PublicKey:
+		namedCurve := ""
+		for uri, curve := range namedCurves {
+			if curve == key.Curve {
+				namedCurve = uri
+			}
+		}
+		if namedCurve == "" {
+			return nil, errors.New("unsupported elliptic curve")
+		}
+		keyValue.ECKeyValue = &ECKeyValue{
+			NamedCurve: namedCurve,
+			PublicKey:  base64.StdEncoding.EncodeToString(elliptic.Marshal(key.Curve, key.X, key.Y)),
+		}
+	default:
+		return nil, errors.New("unsupported public key type")
+	}
+	return keyValue, nil
+}
+
+func (xml *KeyValue) GetPublicKey() (crypto.PublicKey, error) {
+	if xml.RSAKeyValue != nil {
+		modulus, err := base64.StdEncoding.DecodeString(strings.TrimSpace(xml.RSAKeyValue.Modulus))
+		if err != nil {
+			return nil, err
+		}
+		exponent, err := base64.StdEncoding.DecodeString(strings.TrimSpace(xml.RSAKeyValue.Exponent))
+		if err != nil {
+			return nil, err
+		}
+		e := new(big.Int).SetBytes(exponent)
+		if !e.IsInt64() || e.Int64() > int64(^uint32(0)>>1) {
+			return nil, errors.New("rsa exponent is too large")
+		}
+		return &rsa.PublicKey{
+			N: new(big.Int).SetBytes(modulus),
+			E: int(e.Int64()),
+		}, nil
+	}
+
+	if xml.ECKeyValue != nil {
+		curve, ok := namedCurves[xml.ECKeyValue.NamedCurve]
+		if !ok {
+			return nil, errors.New("unsupported elliptic curve: " + xml.ECKeyValue.NamedCurve)
+		}
+		point, err := base64.StdEncoding.DecodeString(strings.TrimSpace(xml.ECKeyValue.PublicKey))
+		if err != nil {
+			return nil, err
+		}
+		x, y := elliptic.Unmarshal(curve, point)
+		if x == nil {
+			return nil, errors.New("invalid elliptic curve public key")
+		}
+		return &ecdsa.PublicKey{
+			Curve: curve,
+			X:     x,
+			Y:     y,
+		}, nil
+	}
+
+	if xml.Element != nil && xml.Element.Tag == "DSAKeyValue" && xml.Element.NamespaceURI() == XmlDSigNamespaceUri {
+		return nil, ErrUnsupportedDSAKeyValue
+	}
+
+	return nil, errors.New("key value does not contain a supported key")
+}
+
+func (xml *KeyValue) root() *SignedXml {
+	if xml.keyInfo == nil {
+		return nil
+	}
+	return xml.keyInfo.root()
+}
+
+func (xml *KeyValue) loadXml(el *etree.Element) error {
+	err := validateElement(el, "KeyValue", XmlDSigNamespaceUri)
+	if err != nil {
+		return err
+	}
+
+	// Get the rsa key value
+	rsaKeyValueElement, err := getOptionalSingleChildElement(el, "RSAKeyValue", XmlDSigNamespaceUri)
+	if err != nil {
+		return err
+	}
+	if rsaKeyValueElement != nil {
+		modulusElement, err := getSingleChildElement(rsaKeyValueElement, "Modulus", XmlDSigNamespaceUri)
+		if err != nil {
+			return err
+		}
+		exponentElement, err := getSingleChildElement(rsaKeyValueElement, "Exponent", XmlDSigNamespaceUri)
+		if err != nil {
+			return err
+		}
+		xml.RSAKeyValue = &RSAKeyValue{
+			Modulus:  modulusElement.Text(),
+			Exponent: exponentElement.Text(),
+		}
+	}
+
+	// Get the elliptic curve key value
+	ecKeyValueElement, err := getOptionalSingleChildElement(el, "ECKeyValue", XmlDSig11NamespaceUri)
+	if err != nil {
+		return err
+	}
+	if ecKeyValueElement != nil {
+		namedCurveElement, err := getSingleChildElement(ecKeyValueElement, "NamedCurve", XmlDSig11NamespaceUri)
+		if err != nil {
+			return err
+		}
+		publicKeyElement, err := getSingleChildElement(ecKeyValueElement, "PublicKey", XmlDSig11NamespaceUri)
+		if err != nil {
+			return err
+		}
+		xml.ECKeyValue = &ECKeyValue{
+			Id:         ecKeyValueElement.SelectAttrValue("Id", ""),
+			NamedCurve: namedCurveElement.SelectAttrValue("URI", ""),
+			PublicKey:  publicKeyElement.Text(),
+		}
+	}
+
+	// Other key values, such as a DSAKeyValue, are kept so they are serialized again
+	if xml.RSAKeyValue == nil && xml.ECKeyValue == nil {
+		childElements := el.ChildElements()
+		if len(childElements) != 1 {
+			return errors.New("key value does not contain a single key")
+		}
+		xml.Element = canonicalizer.DetachElement(childElements[0])
+	}
+
+	xml.cachedXml = el
+	return nil
+}
+
+func (xml *KeyValue) getXml() (*etree.Element, error) {
+	el := etree.NewElement("KeyValue")
+	el.Space = xml.root().getElementSpace(XmlDSigNamespaceUri)
+
+	switch {
+	case xml.RSAKeyValue != nil:
+		rsaKeyValueElement := el.CreateElement("RSAKeyValue")
+		rsaKeyValueElement.Space = xml.root().getElementSpace(XmlDSigNamespaceUri)
+		modulusElement := rsaKeyValueElement.CreateElement("Modulus")
+		modulusElement.Space = xml.root().getElementSpace(XmlDSigNamespaceUri)
+		modulusElement.SetText(xml.RSAKeyValue.Modulus)
+		exponentElement := rsaKeyValueElement.CreateElement("Exponent")
+		exponentElement.Space = xml.root().getElementSpace(XmlDSigNamespaceUri)
+		exponentElement.SetText(xml.RSAKeyValue.Exponent)
+	case xml.ECKeyValue != nil:
+		ecKeyValueElement := xml.root().createNamespacedElement("ECKeyValue", XmlDSig11NamespaceUri)
+		if xml.ECKeyValue.Id != "" {
+			ecKeyValueElement.CreateAttr("Id", xml.ECKeyValue.Id)
+		}
+		namedCurveElement := ecKeyValueElement.CreateElement("NamedCurve")
+		namedCurveElement.Space = ecKeyValueElement.Space
+		namedCurveElement.CreateAttr("URI", xml.ECKeyValue.NamedCurve)
+		publicKeyElement := ecKeyValueElement.CreateElement("PublicKey")
+		publicKeyElement.Space = ecKeyValueElement.Space
+		publicKeyElement.SetText(xml.ECKeyValue.PublicKey)
+		el.AddChild(ecKeyValueElement)
+	case xml.Element != nil:
+		el.AddChild(xml.Element.Copy())
+	default:
+		return nil, errors.New("key value does not contain a key")
+	}
+
+	return el, nil
+}
@@ -0,0 +1,128 @@
+package xmldsig
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/url"
+	"path"
+	"strings"
+
+	"github.com/beevik/etree"
+)
+
+const (
+	OpcContentTypesNamespaceUri string = "http://schemas.openxmlformats.org/package/2006/content-types"
+	opcContentTypesPartName     string = "[Content_Types].xml"
+)
+
+const (
+	DefaultPackagePartSizeLimit int64 = 64 << 20
+)
+
+// NewPackagePartResolver resolves the part names of an Open Packaging
+// Conventions package, such as an Office Open XML document, opened as a zip
+// archive. Set it for the "/" prefix on the SignedXml of the package to resolve
+// references like /word/document.xml?ContentType=... The content type of the
+// part must match the ContentType query of the reference.
+func NewPackagePartResolver(r *zip.Reader) ResolveReferenceMethod {
+	return NewPackagePartResolverWithSizeLimit(r, DefaultPackagePartSizeLimit)
+}
+
+// NewPackagePartResolverWithSizeLimit resolves package parts that are not larger
+// than limit bytes when they are decompressed.
+func NewPackagePartResolverWithSizeLimit(r *zip.Reader, limit int64) ResolveReferenceMethod {
+	return func(ctx context.Context, reference *Reference) (io.Reader, error) {
+		// The query is not form encoded, content types may contain a '+'
+		partUri, query, _ := strings.Cut(reference.Uri, "?")
+		partName, err := url.PathUnescape(partUri)
+		if err != nil {
+			return nil, err
+		}
+		if !strings.HasPrefix(partName, "/") {
+			return nil, errors.New("invalid package part name: " + partName)
+		}
+		contentType := ""
+		for _, param := range strings.Split(query, "&") {
+			if value, ok := strings.CutPrefix(param, "ContentType="); ok {
+				contentType, err = url.PathUnescape(value)
+				if err != nil {
+					return nil, err
+				}
+			}
+		}
+
+		if contentType != "" {
+			partContentType, err := getPackagePartContentType(r, partName, limit)
+			if err != nil {
+				return nil, err
+			}
+			if !strings.EqualFold(partContentType, contentType) {
+				return nil, errors.New("package part content type does not match the reference: " + partName)
+			}
+		}
+
+		data, err := readPackagePart(r, partName[1:], limit)
+		if err != nil {
+			return nil, err
+		}
+		return bytes.NewReader(data), nil
+	}
+}
+
+// readPackagePart reads a part, part names are compared case-insensitive. The
+// size in the archive is not trusted, the read stops after limit bytes.
+func readPackagePart(r *zip.Reader, name string, limit int64) ([]byte, error) {
+	for _, f := range r.File {
+		if !strings.EqualFold(f.Name, name) {
+			continue
+		}
+		rc, err := f.Open()
+		if err != nil {
+			return nil, err
+		}
+		defer rc.Close()
+		data, err := io.ReadAll(io.LimitReader(rc, limit+1))
+		if err != nil {
+			return nil, err
+		}
+		if int64(len(data)) > limit {
+			return nil, errors.New("package part exceeds the size limit: /" + name)
+		}
+		return data, nil
+	}
+	return nil, errors.New("package part not found: /" + name)
+}
+
+func getPackagePartContentType(r *zip.Reader, partName string, limit int64) (string, error) {
+	data, err := readPackagePart(r, opcContentTypesPartName, limit)
+	if err != nil {
+		return "", err
+	}
+	doc := etree.NewDocument()
+	err = doc.ReadFromBytes(data)
+	if err != nil {
+		return "", err
+	}
+	root := doc.Root()
+	if root == nil || root.Tag != "Types" || root.NamespaceURI() != OpcContentTypesNamespaceUri {
+		return "", errors.New("package does not contain a valid content types part")
+	}
+
+	// An override for the part takes precedence over the default for its extension
+	for _, override := range selectChildElements(root, "Override", OpcContentTypesNamespaceUri) {
+		overridePartName, err := url.PathUnescape(override.SelectAttrValue("PartName", ""))
+		if err == nil && strings.EqualFold(overridePartName, partName) {
+			return override.SelectAttrValue("ContentType", ""), nil
+		}
+	}
+	extension := strings.TrimPrefix(path.Ext(partName), ".")
+	for _, def := range selectChildElements(root, "Default", OpcContentTypesNamespaceUri) {
+		if strings.EqualFold(def.SelectAttrValue("Extension", ""), extension) {
+			return def.SelectAttrValue("ContentType", ""), nil
+		}
+	}
+	return "", errors.New("package part does not have a content type: " + partName)
+}
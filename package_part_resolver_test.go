@@ -0,0 +1,224 @@
+package xmldsig
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+)
+
+const testPackageContentTypes = `<?xml version="1.0" encoding="UTF-8"?>` +
+	`<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types">` +
+	`<Default Extension="xml" ContentType="application/xml"/>` +
+	`<Override PartName="/word/document.xml" ContentType="application/vnd.openxmlformats-officedocument.wordprocessingml.document.main+xml"/>` +
+	`</Types>`
+
+func newTestPackage(t *testing.T, parts map[string]string) *zip.Reader {
+	t.Helper()
+	var buffer bytes.Buffer
+	w := zip.NewWriter(&buffer)
+	for name, content := range parts {
+		f, err := w.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = io.WriteString(f, content)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	err := w.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	r, err := zip.NewReader(bytes.NewReader(buffer.Bytes()), int64(buffer.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return r
+}
+
+func Test_PackagePartResolver(t *testing.T) {
+	r := newTestPackage(t, map[string]string{
+		"[Content_Types].xml": testPackageContentTypes,
+		"word/document.xml":   `<document/>`,
+		"word/styles.xml":     `<styles/>`,
+	})
+
+	tests := []struct {
+		name     string
+		uri      string
+		limit    int64
+		expected string
+		fails    bool
+	}{
+		{"Override", "/word/document.xml?ContentType=application/vnd.openxmlformats-officedocument.wordprocessingml.document.main+xml", DefaultPackagePartSizeLimit, `<document/>`, false},
+		{"Default", "/word/styles.xml?ContentType=application/xml", DefaultPackagePartSizeLimit, `<styles/>`, false},
+		{"CaseInsensitive", "/Word/Styles.xml", DefaultPackagePartSizeLimit, `<styles/>`, false},
+		{"ContentTypeMismatch", "/word/document.xml?ContentType=application/xml", DefaultPackagePartSizeLimit, "", true},
+		{"NotFound", "/word/missing.xml", DefaultPackagePartSizeLimit, "", true},
+		{"Relative", "word/styles.xml", DefaultPackagePartSizeLimit, "", true},
+		{"AtSizeLimit", "/word/styles.xml", 9, `<styles/>`, false},
+		{"ExceedsSizeLimit", "/word/styles.xml", 8, "", true},
+		{"ContentTypesExceedSizeLimit", "/word/styles.xml?ContentType=application/xml", 16, "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resolver := NewPackagePartResolverWithSizeLimit(r, tt.limit)
+			reader, err := resolver(context.Background(), NewReference(tt.uri))
+			if tt.fails {
+				if err == nil {
+					t.Error("expected the reference to be rejected")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			data, err := io.ReadAll(reader)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(data) != tt.expected {
+				t.Errorf("expected %q, got %q", tt.expected, string(data))
+			}
+		})
+	}
+}
+
+func Test_SignedXml_SetReferenceResolver(t *testing.T) {
+	ctx := context.Background()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signingPackage := newTestPackage(t, map[string]string{
+		"[Content_Types].xml": testPackageContentTypes,
+		"word/styles.xml":     `<styles/>`,
+	})
+	otherPackage := newTestPackage(t, map[string]string{
+		"[Content_Types].xml": testPackageContentTypes,
+		"word/styles.xml":     `<styles changed="true"/>`,
+	})
+
+	doc := parseTestDocument(t, testDocument)
+	signedXml := NewSignedXml(doc)
+	signedXml.SetReferenceResolver("/", NewPackagePartResolver(signingPackage))
+	_, err = signedXml.AddReference("/word/styles.xml?ContentType=application/xml", DigestMethod_SHA256)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = signedXml.ComputeSignature(ctx, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The resolver is not registered for other signatures
+	if _, ok := GetReferenceElementResolver("/"); ok {
+		t.Fatal("expected the resolver not to be registered globally")
+	}
+	signed, err := doc.WriteToString()
+	if err != nil {
+		t.Fatal(err)
+	}
+	loadedXml, err := LoadSignedXml(parseTestDocument(t, signed))
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = loadedXml.ValidateSignatureWithKey(ctx, key.Public())
+	if err == nil || !strings.Contains(err.Error(), "no reference resolver found") {
+		t.Errorf("expected no resolver to be found, got %v", err)
+	}
+
+	// Each signature resolves the parts of its own package
+	loadedXml.SetReferenceResolver("/", NewPackagePartResolver(signingPackage))
+	_, err = loadedXml.ValidateSignatureWithKey(ctx, key.Public())
+	if err != nil {
+		t.Errorf("validate: %v", err)
+	}
+	otherXml, err := LoadSignedXml(parseTestDocument(t, signed))
+	if err != nil {
+		t.Fatal(err)
+	}
+	otherXml.SetReferenceResolver("/", NewPackagePartResolver(otherPackage))
+	_, err = otherXml.ValidateSignatureWithKey(ctx, key.Public())
+	if err == nil {
+		t.Error("expected the modified package to fail validation")
+	}
+}
+
+func Test_FindReferenceResolver(t *testing.T) {
+	resolve := func(name string) ResolveReferenceMethod {
+		return func(ctx context.Context, reference *Reference) (io.Reader, error) {
+			return strings.NewReader(name), nil
+		}
+	}
+	resolvers := map[string]ResolveReferenceMethod{
+		"cid:":      resolve("cid"),
+		"cid:part1": resolve("part1"),
+		"/":         resolve("package"),
+	}
+
+	tests := []struct {
+		uri      string
+		expected string
+	}{
+		{"cid:part1@example.com", "part1"},
+		{"cid:part2@example.com", "cid"},
+		{"/word/document.xml", "package"},
+		{"https://example.com", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.uri, func(t *testing.T) {
+			method, ok := findReferenceResolver(resolvers, tt.uri)
+			if tt.expected == "" {
+				if ok {
+					t.Error("expected no resolver to be found")
+				}
+				return
+			}
+			if !ok {
+				t.Fatal("expected a resolver to be found")
+			}
+			reader, _ := method(context.Background(), nil)
+			data, _ := io.ReadAll(reader)
+			if string(data) != tt.expected {
+				t.Errorf("expected the %s resolver, got %s", tt.expected, string(data))
+			}
+		})
+	}
+}
+
+func Test_RegisterReferenceElementResolver_Concurrent(t *testing.T) {
+	method := func(ctx context.Context, reference *Reference) (io.Reader, error) {
+		return strings.NewReader(""), nil
+	}
+	defer func() {
+		referenceElementResolversLock.Lock()
+		delete(referenceElementResolvers, "test:")
+		referenceElementResolversLock.Unlock()
+	}()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			RegisterReferenceElementResolver("test:", method)
+		}()
+		go func() {
+			defer wg.Done()
+			findRegisteredReferenceResolver("test:value")
+			GetReferenceResolverPrefixes()
+		}()
+	}
+	wg.Wait()
+	if _, ok := GetReferenceElementResolver("test:"); !ok {
+		t.Error("expected the resolver to be registered")
+	}
+}
@@ -4,10 +4,11 @@ import (
 	"context"
 	"encoding/base64"
 	"errors"
-	"io"
 	"strings"
 
 	"github.com/beevik/etree"
+	"github.com/deb-ict/go-xmldsig/canonicalizer"
+	"github.com/deb-ict/go-xmldsig/transform"
 )
 
 type Reference struct {
@@ -17,6 +18,7 @@ type Reference struct {
 	Transforms   *Transforms
 	DigestMethod *DigestMethod
 	DigestValue  string
+	hasUri       bool
 	signedInfo   *SignedInfo
 	cachedXml    *etree.Element
 }
@@ -27,6 +29,39 @@ func newReference(signedInfo *SignedInfo) *Reference {
 	}
 }
 
+func NewReference(uri string) *Reference {
+	reference := newReference(nil)
+	reference.Uri = uri
+	reference.hasUri = true
+	return reference
+}
+
+func (xml *Reference) WithId(id string) *Reference {
+	xml.Id = id
+	return xml
+}
+
+func (xml *Reference) WithType(referenceType string) *Reference {
+	xml.Type = referenceType
+	return xml
+}
+
+func (xml *Reference) WithTransforms(transforms ...*Transform) *Reference {
+	if xml.Transforms == nil {
+		xml.Transforms = newTransforms(xml)
+	}
+	for _, transform := range transforms {
+		xml.Transforms.AddTransform(transform)
+	}
+	return xml
+}
+
+func (xml *Reference) WithDigest(uri string) *Reference {
+	xml.DigestMethod = newDigestMethod(xml)
+	xml.DigestMethod.Algorithm = uri
+	return xml
+}
+
 func (xml *Reference) GetUriWithoutPrefix(prefix string) string {
 	if strings.HasPrefix(xml.Uri, prefix) {
 		return xml.Uri[len(prefix):]
@@ -35,15 +70,108 @@ func (xml *Reference) GetUriWithoutPrefix(prefix string) string {
 }
 
 func (xml *Reference) root() *SignedXml {
+	if xml.signedInfo == nil {
+		return nil
+	}
 	return xml.signedInfo.root()
 }
 
+func (xml *Reference) bind(signedInfo *SignedInfo) {
+	xml.signedInfo = signedInfo
+	if xml.Transforms != nil {
+		xml.Transforms.bind(xml)
+	}
+	if xml.DigestMethod != nil {
+		xml.DigestMethod.reference = xml
+	}
+}
+
+func (xml *Reference) validate() error {
+	if xml.DigestMethod == nil {
+		return errors.New("reference does not contain a DigestMethod element")
+	}
+	_, err := GetDigestMethod(xml.DigestMethod.Algorithm)
+	if err != nil {
+		return err
+	}
+	if xml.Transforms != nil {
+		return xml.Transforms.validate()
+	}
+	return nil
+}
+
 func (xml *Reference) validateDigest(ctx context.Context) error {
 	digestBytes, err := base64.StdEncoding.DecodeString(xml.DigestValue)
 	if err != nil {
 		return err
 	}
 
+	digestValue, err := xml.calculateDigest(ctx)
+	if err != nil {
+		return err
+	}
+	if !CryptographicEquals(digestValue, digestBytes) {
+		return errors.New("digest validation failed")
+	}
+
+	return nil
+}
+
+func (xml *Reference) computeDigest(ctx context.Context) error {
+	digestValue, err := xml.calculateDigest(ctx)
+	if err != nil {
+		return err
+	}
+	xml.DigestValue = base64.StdEncoding.EncodeToString(digestValue)
+
+	return nil
+}
+
+func (xml *Reference) calculateDigest(ctx context.Context) ([]byte, error) {
+	if xml.DigestMethod == nil {
+		return nil, errors.New("reference does not contain a DigestMethod element")
+	}
+
+	// Apply the transforms
+	data, err := xml.getTransformedData(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// The transformed octets are written to the digest, without buffering them
+	createHash, err := GetDigestMethod(xml.DigestMethod.Algorithm)
+	if err != nil {
+		return nil, err
+	}
+	digestAlgorithm := createHash()
+	err = data.WriteOctets(ctx, digestAlgorithm)
+	if err != nil {
+		return nil, err
+	}
+
+	return digestAlgorithm.Sum(nil), nil
+}
+
+// getTransformedData returns the result of the transforms, a resulting node-set
+// is converted to octets using C14N 1.0 when it is digested.
+func (xml *Reference) getTransformedData(ctx context.Context) (*transform.Data, error) {
+	ctx = transform.WithSignatureElement(ctx, xml.getSignatureElement())
+
+	data, err := xml.dereference(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if xml.Transforms != nil {
+		data, err = xml.Transforms.transform(ctx, data)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return data, nil
+}
+
+func (xml *Reference) dereference(ctx context.Context) (*transform.Data, error) {
 	if xml.Uri == "" || strings.HasPrefix(xml.Uri, "#") {
 		var element *etree.Element
 		if xml.Uri == "" {
@@ -53,69 +181,33 @@ func (xml *Reference) validateDigest(ctx context.Context) error {
 			element = xml.root().document.FindElement("//*[@Id='" + elementId + "']")
 		}
 		if element == nil {
-			return errors.New("element not found")
+			return nil, errors.New("element not found")
 		}
 
-		// Apply the transforms
-		data, err := xml.Transforms.transformXmlElement(ctx, element)
-		if err != nil {
-			return err
-		}
+		// Same document references do not include comment nodes
+		return transform.NewNodeSetData(canonicalizer.NewNodeSet(element).WithoutComments()), nil
+	}
 
-		// Calculate the digest
-		digestMethod, err := GetDigestMethod(xml.DigestMethod.Algorithm)
-		if err != nil {
-			return err
-		}
-		digestAlgorithm, err := digestMethod.CreateHashAlgorithm()
-		if err != nil {
-			return err
-		}
-		digestAlgorithm.Write(data)
-		digestValue := digestAlgorithm.Sum(nil)
-		if CryptographicEquals(digestValue, digestBytes) {
-			return nil
-		}
-	} else {
-		prefixes := GetReferenceResolverPrefixes()
-		for _, prefix := range prefixes {
-			if strings.HasPrefix(xml.Uri, prefix) {
-				if method, ok := GetReferenceElementResolver(prefix); ok {
-					reader, err := method(ctx, xml)
-					if err != nil {
-						return err
-					}
-
-					// Apply the transforms
-					data, err := io.ReadAll(reader)
-					if err != nil {
-						return err
-					}
-					data, err = xml.Transforms.transformData(ctx, data)
-					if err != nil {
-						return err
-					}
-
-					// Calculate the digest
-					digestMethod, err := GetDigestMethod(xml.DigestMethod.Algorithm)
-					if err != nil {
-						return err
-					}
-					digestAlgorithm, err := digestMethod.CreateHashAlgorithm()
-					if err != nil {
-						return err
-					}
-					digestAlgorithm.Write(data)
-					digestValue := digestAlgorithm.Sum(nil)
-					if CryptographicEquals(digestValue, digestBytes) {
-						return nil
-					}
-				}
-			}
-		}
+	method, ok := xml.root().getReferenceResolver(xml.Uri)
+	if !ok {
+		return nil, errors.New("no reference resolver found for uri: " + xml.Uri)
 	}
+	reader, err := method(ctx, xml)
+	if err != nil {
+		return nil, err
+	}
+	// The content is only read when it is digested or transformed
+	if attachment, ok := reader.(*Attachment); ok {
+		return transform.NewAttachmentStreamData(attachment.Header, attachment), nil
+	}
+	return transform.NewOctetStreamData(reader), nil
+}
 
-	return errors.New("digest validation failed")
+func (xml *Reference) getSignatureElement() *etree.Element {
+	if xml.signedInfo == nil || xml.signedInfo.signature == nil {
+		return nil
+	}
+	return xml.signedInfo.signature.cachedXml
 }
 
 func (xml *Reference) loadXml(el *etree.Element) error {
@@ -126,7 +218,11 @@ func (xml *Reference) loadXml(el *etree.Element) error {
 
 	// Get the reference attributes
 	xml.Id = el.SelectAttrValue("Id", "")
-	xml.Uri = el.SelectAttrValue("URI", "")
+	uriAttr := el.SelectAttr("URI")
+	xml.hasUri = uriAttr != nil
+	if uriAttr != nil {
+		xml.Uri = uriAttr.Value
+	}
 	xml.Type = el.SelectAttrValue("Type", "")
 
 	// Get the transform list element
@@ -134,10 +230,12 @@ func (xml *Reference) loadXml(el *etree.Element) error {
 	if err != nil {
 		return err
 	}
-	xml.Transforms = newTransforms(xml)
-	err = xml.Transforms.loadXml(transformsElement)
-	if err != nil {
-		return err
+	if transformsElement != nil {
+		xml.Transforms = newTransforms(xml)
+		err = xml.Transforms.loadXml(transformsElement)
+		if err != nil {
+			return err
+		}
 	}
 
 	// Get the digest method
@@ -170,7 +268,8 @@ func (xml *Reference) getXml() (*etree.Element, error) {
 	if xml.Id != "" {
 		el.CreateAttr("Id", xml.Id)
 	}
-	if xml.Uri != "" {
+	// An empty URI references the whole document, an absent URI does not
+	if xml.hasUri || xml.Uri != "" {
 		el.CreateAttr("URI", xml.Uri)
 	}
 	if xml.Type != "" {
@@ -201,6 +300,7 @@ func (xml *Reference) getXml() (*etree.Element, error) {
 		return nil, errors.New("reference does not contain a DigestValue element")
 	}
 	digestValueElement := etree.NewElement("DigestValue")
+	digestValueElement.Space = xml.root().getElementSpace(XmlDSigNamespaceUri)
 	digestValueElement.SetText(xml.DigestValue)
 	el.AddChild(digestValueElement)
 
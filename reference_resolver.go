@@ -3,23 +3,73 @@ package xmldsig
 import (
 	"context"
 	"io"
+	"net/textproto"
+	"strings"
 )
 
+// ResolveReferenceMethod returns the data of an external reference. A resolver
+// for MIME parts can return an *Attachment to provide the part headers.
 type ResolveReferenceMethod func(ctx context.Context, reference *Reference) (io.Reader, error)
 
+// Attachment is a MIME part, the reader returns the body of the part as it was
+// transmitted. The attachment transforms remove the Content-Transfer-Encoding of
+// the header, a resolver that returns the decoded body must remove that header.
+type Attachment struct {
+	Header textproto.MIMEHeader
+	Reader io.Reader
+}
+
+func NewAttachment(header textproto.MIMEHeader, reader io.Reader) *Attachment {
+	return &Attachment{
+		Header: header,
+		Reader: reader,
+	}
+}
+
+func (a *Attachment) Read(p []byte) (int, error) {
+	return a.Reader.Read(p)
+}
+
+// RegisterReferenceElementResolver registers a resolver for all signatures, use
+// SignedXml.SetReferenceResolver to resolve references of a single signature.
 func RegisterReferenceElementResolver(prefix string, method ResolveReferenceMethod) {
+	referenceElementResolversLock.Lock()
+	defer referenceElementResolversLock.Unlock()
 	referenceElementResolvers[prefix] = method
 }
 
 func GetReferenceElementResolver(prefix string) (ResolveReferenceMethod, bool) {
+	referenceElementResolversLock.RLock()
+	defer referenceElementResolversLock.RUnlock()
 	method, ok := referenceElementResolvers[prefix]
 	return method, ok
 }
 
 func GetReferenceResolverPrefixes() []string {
+	referenceElementResolversLock.RLock()
+	defer referenceElementResolversLock.RUnlock()
 	prefixes := make([]string, 0, len(referenceElementResolvers))
 	for prefix := range referenceElementResolvers {
 		prefixes = append(prefixes, prefix)
 	}
 	return prefixes
 }
+
+func findRegisteredReferenceResolver(uri string) (ResolveReferenceMethod, bool) {
+	referenceElementResolversLock.RLock()
+	defer referenceElementResolversLock.RUnlock()
+	return findReferenceResolver(referenceElementResolvers, uri)
+}
+
+// findReferenceResolver returns the resolver with the longest prefix of the uri
+func findReferenceResolver(resolvers map[string]ResolveReferenceMethod, uri string) (ResolveReferenceMethod, bool) {
+	var method ResolveReferenceMethod
+	length := -1
+	for prefix, m := range resolvers {
+		if strings.HasPrefix(uri, prefix) && len(prefix) > length {
+			method = m
+			length = len(prefix)
+		}
+	}
+	return method, method != nil
+}
@@ -0,0 +1,99 @@
+package xmldsig
+
+import (
+	"github.com/beevik/etree"
+)
+
+type RetrievalMethod struct {
+	Uri        string
+	Type       string
+	Transforms *Transforms
+	keyInfo    *KeyInfo
+	cachedXml  *etree.Element
+}
+
+func newRetrievalMethod(keyInfo *KeyInfo) *RetrievalMethod {
+	return &RetrievalMethod{
+		keyInfo: keyInfo,
+	}
+}
+
+func NewRetrievalMethod(uri string, retrievalType string) *RetrievalMethod {
+	retrievalMethod := newRetrievalMethod(nil)
+	retrievalMethod.Uri = uri
+	retrievalMethod.Type = retrievalType
+	return retrievalMethod
+}
+
+func (xml *RetrievalMethod) WithTransforms(transforms ...*Transform) *RetrievalMethod {
+	if xml.Transforms == nil {
+		xml.Transforms = newTransforms(nil)
+		xml.Transforms.retrievalMethod = xml
+	}
+	for _, transform := range transforms {
+		xml.Transforms.AddTransform(transform)
+	}
+	return xml
+}
+
+func (xml *RetrievalMethod) root() *SignedXml {
+	if xml.keyInfo == nil {
+		return nil
+	}
+	return xml.keyInfo.root()
+}
+
+func (xml *RetrievalMethod) bind(keyInfo *KeyInfo) {
+	xml.keyInfo = keyInfo
+	if xml.Transforms != nil {
+		xml.Transforms.bind(nil)
+		xml.Transforms.retrievalMethod = xml
+	}
+}
+
+func (xml *RetrievalMethod) loadXml(el *etree.Element) error {
+	err := validateElement(el, "RetrievalMethod", XmlDSigNamespaceUri)
+	if err != nil {
+		return err
+	}
+
+	xml.Uri = el.SelectAttrValue("URI", "")
+	xml.Type = el.SelectAttrValue("Type", "")
+
+	// Get the optional transform list element
+	transformsElement, err := getOptionalSingleChildElement(el, "Transforms", XmlDSigNamespaceUri)
+	if err != nil {
+		return err
+	}
+	if transformsElement != nil {
+		xml.Transforms = newTransforms(nil)
+		xml.Transforms.retrievalMethod = xml
+		err = xml.Transforms.loadXml(transformsElement)
+		if err != nil {
+			return err
+		}
+	}
+
+	xml.cachedXml = el
+	return nil
+}
+
+func (xml *RetrievalMethod) getXml() (*etree.Element, error) {
+	el := etree.NewElement("RetrievalMethod")
+	el.Space = xml.root().getElementSpace(XmlDSigNamespaceUri)
+
+	el.CreateAttr("URI", xml.Uri)
+	if xml.Type != "" {
+		el.CreateAttr("Type", xml.Type)
+	}
+
+	if xml.Transforms != nil {
+		transformsElement, err := xml.Transforms.getXml()
+		if err != nil {
+			return nil, err
+		}
+		el.AddChild(transformsElement)
+	}
+
+	return el, nil
+}
@@ -0,0 +1,166 @@
+package xmldsig
+
+import (
+	"crypto/x509"
+	"encoding/base64"
+	"errors"
+	"strings"
+
+	"github.com/beevik/etree"
+)
+
+type SecurityTokenReferenceReference struct {
+	Uri       string
+	ValueType string
+}
+
+type KeyIdentifier struct {
+	ValueType    string
+	EncodingType string
+	Value        string
+}
+
+type SecurityTokenReference struct {
+	Id            string
+	Reference     *SecurityTokenReferenceReference
+	KeyIdentifier *KeyIdentifier
+	keyInfo       *KeyInfo
+	cachedXml     *etree.Element
+}
+
+func newSecurityTokenReference(keyInfo *KeyInfo) *SecurityTokenReference {
+	return &SecurityTokenReference{
+		keyInfo: keyInfo,
+	}
+}
+
+func NewSecurityTokenReference(uri string, valueType string) *SecurityTokenReference {
+	securityTokenReference := newSecurityTokenReference(nil)
+	securityTokenReference.Reference = &SecurityTokenReferenceReference{
+		Uri:       uri,
+		ValueType: valueType,
+	}
+	return securityTokenReference
+}
+
+func (xml *SecurityTokenReference) GetCertificate() (*x509.Certificate, error) {
+	if xml.Reference != nil {
+		tokenElement, err := xml.GetReferencedToken()
+		if err != nil {
+			return nil, err
+		}
+		return parseBase64Certificate(tokenElement.Text())
+	}
+
+	if xml.KeyIdentifier != nil && strings.HasSuffix(xml.KeyIdentifier.ValueType, "#X509v3") {
+		return parseBase64Certificate(xml.KeyIdentifier.Value)
+	}
+
+	return nil, errors.New("security token reference does not reference a certificate")
+}
+
+func (xml *SecurityTokenReference) GetReferencedToken() (*etree.Element, error) {
+	if xml.Reference == nil {
+		return nil, errors.New("security token reference does not contain a Reference element")
+	}
+
+	uri := xml.Reference.Uri
+	if !strings.HasPrefix(uri, "#") || len(uri) < 2 {
+		return nil, errors.New("security token reference does not contain a local URI")
+	}
+
+	signedXml := xml.root()
+	if signedXml == nil || signedXml.document == nil {
+		return nil, errors.New("security token reference is not attached to a document")
+	}
+	tokenElements := signedXml.document.FindElements("//BinarySecurityToken[@Id='" + uri[1:] + "']")
+	if len(tokenElements) != 1 {
+		return nil, errors.New("document does not contain a single BinarySecurityToken element")
+	}
+
+	return tokenElements[0], nil
+}
+
+func (xml *SecurityTokenReference) root() *SignedXml {
+	if xml.keyInfo == nil {
+		return nil
+	}
+	return xml.keyInfo.root()
+}
+
+func (xml *SecurityTokenReference) loadXml(el *etree.Element) error {
+	err := validateElement(el, "SecurityTokenReference", WsseNamespaceUri)
+	if err != nil {
+		return err
+	}
+
+	xml.Id = el.SelectAttrValue("Id", "")
+
+	// Get the token reference
+	referenceElement, err := getOptionalSingleChildElement(el, "Reference", WsseNamespaceUri)
+	if err != nil {
+		return err
+	}
+	if referenceElement != nil {
+		xml.Reference = &SecurityTokenReferenceReference{
+			Uri:       referenceElement.SelectAttrValue("URI", ""),
+			ValueType: referenceElement.SelectAttrValue("ValueType", ""),
+		}
+	}
+
+	// Get the key identifier
+	keyIdentifierElement, err := getOptionalSingleChildElement(el, "KeyIdentifier", WsseNamespaceUri)
+	if err != nil {
+		return err
+	}
+	if keyIdentifierElement != nil {
+		xml.KeyIdentifier = &KeyIdentifier{
+			ValueType:    keyIdentifierElement.SelectAttrValue("ValueType", ""),
+			EncodingType: keyIdentifierElement.SelectAttrValue("EncodingType", ""),
+			Value:        keyIdentifierElement.Text(),
+		}
+	}
+
+	xml.cachedXml = el
+	return nil
+}
+
+func (xml *SecurityTokenReference) getXml() (*etree.Element, error) {
+	el := xml.root().createNamespacedElement("SecurityTokenReference", WsseNamespaceUri)
+
+	if xml.Id != "" {
+		el.CreateAttr("Id", xml.Id)
+	}
+
+	switch {
+	case xml.Reference != nil:
+		referenceElement := el.CreateElement("Reference")
+		referenceElement.Space = el.Space
+		referenceElement.CreateAttr("URI", xml.Reference.Uri)
+		if xml.Reference.ValueType != "" {
+			referenceElement.CreateAttr("ValueType", xml.Reference.ValueType)
+		}
+	case xml.KeyIdentifier != nil:
+		keyIdentifierElement := el.CreateElement("KeyIdentifier")
+		keyIdentifierElement.Space = el.Space
+		if xml.KeyIdentifier.ValueType != "" {
+			keyIdentifierElement.CreateAttr("ValueType", xml.KeyIdentifier.ValueType)
+		}
+		if xml.KeyIdentifier.EncodingType != "" {
+			keyIdentifierElement.CreateAttr("EncodingType", xml.KeyIdentifier.EncodingType)
+		}
+		keyIdentifierElement.SetText(xml.KeyIdentifier.Value)
+	default:
+		return nil, errors.New("security token reference does not contain a Reference or KeyIdentifier element")
+	}
+
+	return el, nil
+}
+
+func parseBase64Certificate(value string) (*x509.Certificate, error) {
+	certificateData, err := base64.StdEncoding.DecodeString(strings.TrimSpace(value))
+	if err != nil {
+		return nil, err
+	}
+	return x509.ParseCertificate(certificateData)
+}
@@ -10,6 +10,7 @@ type Signature struct {
 	Id             string
 	SignedInfo     *SignedInfo
 	SignatureValue *SignatureValue
+	KeyInfo        *KeyInfo
 	signedXml      *SignedXml
 	cachedXml      *etree.Element
 }
@@ -20,10 +21,63 @@ func newSignature(signedXml *SignedXml) *Signature {
 	}
 }
 
+func NewSignature() *Signature {
+	return newSignature(nil)
+}
+
+func (xml *Signature) WithId(id string) *Signature {
+	xml.Id = id
+	return xml
+}
+
+func (xml *Signature) WithSignedInfo(signedInfo *SignedInfo) *Signature {
+	if signedInfo != nil {
+		signedInfo.signature = xml
+	}
+	xml.SignedInfo = signedInfo
+	return xml
+}
+
+func (xml *Signature) WithKeyInfo(keyInfo *KeyInfo) *Signature {
+	if keyInfo != nil {
+		keyInfo.bind(xml)
+	}
+	xml.KeyInfo = keyInfo
+	return xml
+}
+
+func (xml *Signature) GetXml() (*etree.Element, error) {
+	err := xml.validate()
+	if err != nil {
+		return nil, err
+	}
+	return xml.getXml()
+}
+
 func (xml *Signature) root() *SignedXml {
 	return xml.signedXml
 }
 
+func (xml *Signature) bind(signedXml *SignedXml) {
+	xml.signedXml = signedXml
+	if xml.SignedInfo != nil {
+		xml.SignedInfo.bind(xml)
+	}
+	if xml.SignatureValue != nil {
+		xml.SignatureValue.signature = xml
+	}
+	if xml.KeyInfo != nil {
+		xml.KeyInfo.bind(xml)
+	}
+}
+
+func (xml *Signature) validate() error {
+	if xml.SignedInfo == nil {
+		return errors.New("signature does not contain a SignedInfo element")
+	}
+	return xml.SignedInfo.validate()
+}
+
 func (xml *Signature) loadXml(el *etree.Element) error {
 	err := validateElement(el, "Signature", XmlDSigNamespaceUri)
 	if err != nil {
@@ -56,15 +110,24 @@ func (xml *Signature) loadXml(el *etree.Element) error {
 	}
 
 	// Get the key info
-	//TODO: keyInfoElements := el.SelectElements("KeyInfo[namespace-uri()='" + XmlDSigNamespaceUri + "']")
+	keyInfoElement, err := getOptionalSingleChildElement(el, "KeyInfo", XmlDSigNamespaceUri)
+	if err != nil {
+		return err
+	}
+	if keyInfoElement != nil {
+		xml.KeyInfo = newKeyInfo(xml)
+		err = xml.KeyInfo.loadXml(keyInfoElement)
+		if err != nil {
+			return err
+		}
+	}
 
 	xml.cachedXml = el
 	return nil
 }
 
 func (xml *Signature) getXml() (*etree.Element, error) {
-	el := etree.NewElement("Signature")
-	el.Space = xml.root().getElementSpace(XmlDSigNamespaceUri)
+	el := xml.root().createSignatureElement()
 
 	if xml.Id != "" {
 		el.CreateAttr("Id", xml.Id)
@@ -91,7 +154,13 @@ func (xml *Signature) getXml() (*etree.Element, error) {
 	el.AddChild(signatureValueElement)
 
 	// Add the key info
-	//TODO: KeyInfo
+	if xml.KeyInfo != nil {
+		keyInfoElement, err := xml.KeyInfo.getXml()
+		if err != nil {
+			return nil, err
+		}
+		el.AddChild(keyInfoElement)
+	}
 
 	return el, nil
 }
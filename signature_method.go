@@ -6,18 +6,68 @@ import (
 	"github.com/beevik/etree"
 )
 
+type RSAPSSParams struct {
+	DigestMethod               string
+	MaskGenerationFunction     string
+	MaskGenerationDigestMethod string
+	SaltLength                 int
+	TrailerField               int
+}
+
 type SignatureMethod struct {
 	Algorithm        string
 	HMACOutputLength int
+	RSAPSSParams     *RSAPSSParams
 	signedInfo       *SignedInfo
 	cachedXml        *etree.Element
 }
 
 func newSignatureMethod(signedInfo *SignedInfo) *SignatureMethod {
-	return &SignatureMethod{}
+	return &SignatureMethod{
+		signedInfo: signedInfo,
+	}
+}
+
+func NewSignatureMethod(uri string) *SignatureMethod {
+	signatureMethod := newSignatureMethod(nil)
+	signatureMethod.Algorithm = uri
+	return signatureMethod
+}
+
+func (xml *SignatureMethod) WithRSAPSSParams(digestMethod string, saltLength int) *SignatureMethod {
+	xml.RSAPSSParams = &RSAPSSParams{
+		DigestMethod:               digestMethod,
+		MaskGenerationFunction:     MaskGenerationFunction_MGF1,
+		MaskGenerationDigestMethod: digestMethod,
+		SaltLength:                 saltLength,
+		TrailerField:               1,
+	}
+	return xml
+}
+
+func (xml *SignatureMethod) WithHMACOutputLength(outputLength int) *SignatureMethod {
+	xml.HMACOutputLength = outputLength
+	return xml
+}
+
+func (xml *SignatureMethod) getRSAPSSParams() *RSAPSSParams {
+	if xml == nil {
+		return nil
+	}
+	return xml.RSAPSSParams
+}
+
+func (xml *SignatureMethod) getHMACOutputLength() int {
+	if xml == nil {
+		return 0
+	}
+	return xml.HMACOutputLength
 }
 
 func (xml *SignatureMethod) root() *SignedXml {
+	if xml.signedInfo == nil {
+		return nil
+	}
 	return xml.signedInfo.root()
 }
 
@@ -41,10 +91,72 @@ func (xml *SignatureMethod) loadXml(el *etree.Element) error {
 		xml.HMACOutputLength = hmacOutputLengthValue
 	}
 
+	rsaPssParamsElement, err := getOptionalSingleChildElement(el, "RSAPSSParams", XmlDSigMoreNamespaceUri)
+	if err != nil {
+		return err
+	}
+	if rsaPssParamsElement != nil {
+		xml.RSAPSSParams, err = xml.loadRSAPSSParams(rsaPssParamsElement)
+		if err != nil {
+			return err
+		}
+	}
+
 	xml.cachedXml = el
 	return nil
 }
 
+func (xml *SignatureMethod) loadRSAPSSParams(el *etree.Element) (*RSAPSSParams, error) {
+	params := &RSAPSSParams{}
+
+	digestMethodElement, err := getOptionalSingleChildElement(el, "DigestMethod", XmlDSigNamespaceUri)
+	if err != nil {
+		return nil, err
+	}
+	if digestMethodElement != nil {
+		params.DigestMethod = digestMethodElement.SelectAttrValue("Algorithm", "")
+	}
+
+	maskGenerationFunctionElement, err := getOptionalSingleChildElement(el, "MaskGenerationFunction", XmlDSigMoreNamespaceUri)
+	if err != nil {
+		return nil, err
+	}
+	if maskGenerationFunctionElement != nil {
+		params.MaskGenerationFunction = maskGenerationFunctionElement.SelectAttrValue("Algorithm", "")
+		mgfDigestMethodElement, err := getOptionalSingleChildElement(maskGenerationFunctionElement, "DigestMethod", XmlDSigNamespaceUri)
+		if err != nil {
+			return nil, err
+		}
+		if mgfDigestMethodElement != nil {
+			params.MaskGenerationDigestMethod = mgfDigestMethodElement.SelectAttrValue("Algorithm", "")
+		}
+	}
+
+	saltLengthElement, err := getOptionalSingleChildElement(el, "SaltLength", XmlDSigMoreNamespaceUri)
+	if err != nil {
+		return nil, err
+	}
+	if saltLengthElement != nil {
+		params.SaltLength, err = strconv.Atoi(saltLengthElement.Text())
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	trailerFieldElement, err := getOptionalSingleChildElement(el, "TrailerField", XmlDSigMoreNamespaceUri)
+	if err != nil {
+		return nil, err
+	}
+	if trailerFieldElement != nil {
+		params.TrailerField, err = strconv.Atoi(trailerFieldElement.Text())
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return params, nil
+}
+
 func (xml *SignatureMethod) getXml() (*etree.Element, error) {
 	el := etree.NewElement("SignatureMethod")
 	el.Space = xml.root().getElementSpace(XmlDSigNamespaceUri)
@@ -57,5 +169,41 @@ func (xml *SignatureMethod) getXml() (*etree.Element, error) {
 		hmacOutputLengthElement.SetText(strconv.Itoa(xml.HMACOutputLength))
 	}
 
+	if xml.RSAPSSParams != nil {
+		el.AddChild(xml.getRSAPSSParamsXml())
+	}
+
 	return el, nil
 }
+
+func (xml *SignatureMethod) getRSAPSSParamsXml() *etree.Element {
+	params := xml.RSAPSSParams
+
+	el := xml.root().createNamespacedElement("RSAPSSParams", XmlDSigMoreNamespaceUri)
+	if params.DigestMethod != "" {
+		digestMethodElement := el.CreateElement("DigestMethod")
+		digestMethodElement.Space = xml.root().getElementSpace(XmlDSigNamespaceUri)
+		digestMethodElement.CreateAttr("Algorithm", params.DigestMethod)
+	}
+	if params.MaskGenerationFunction != "" {
+		maskGenerationFunctionElement := el.CreateElement("MaskGenerationFunction")
+		maskGenerationFunctionElement.Space = el.Space
+		maskGenerationFunctionElement.CreateAttr("Algorithm", params.MaskGenerationFunction)
+		if params.MaskGenerationDigestMethod != "" {
+			mgfDigestMethodElement := maskGenerationFunctionElement.CreateElement("DigestMethod")
+			mgfDigestMethodElement.Space = xml.root().getElementSpace(XmlDSigNamespaceUri)
+			mgfDigestMethodElement.CreateAttr("Algorithm", params.MaskGenerationDigestMethod)
+		}
+	}
+	if params.SaltLength != 0 {
+		saltLengthElement := el.CreateElement("SaltLength")
+		saltLengthElement.Space = el.Space
+		saltLengthElement.SetText(strconv.Itoa(params.SaltLength))
+	}
+	if params.TrailerField != 0 {
+		trailerFieldElement := el.CreateElement("TrailerField")
+		trailerFieldElement.Space = el.Space
+		trailerFieldElement.SetText(strconv.Itoa(params.TrailerField))
+	}
+	return el
+}
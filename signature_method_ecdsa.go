@@ -0,0 +1,92 @@
+package xmldsig
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/rand"
+	"encoding/asn1"
+	"errors"
+	"math/big"
+)
+
+type ecdsaSignature struct {
+	R *big.Int
+	S *big.Int
+}
+
+func newECDSASignatureAlgorithm(hash crypto.Hash) *SignatureAlgorithm {
+	return &SignatureAlgorithm{
+		Sign: func(ctx context.Context, key crypto.PrivateKey, data []byte, method *SignatureMethod) ([]byte, error) {
+			signer, ok := key.(crypto.Signer)
+			if !ok {
+				return nil, ErrInvalidSigningKey
+			}
+			publicKey, ok := signer.Public().(*ecdsa.PublicKey)
+			if !ok {
+				return nil, ErrInvalidSigningKey
+			}
+			signature, err := signer.Sign(rand.Reader, hashData(hash, data), hash)
+			if err != nil {
+				return nil, err
+			}
+			return ecdsaASN1ToRaw(signature, ecdsaKeySize(publicKey))
+		},
+		Verify: func(ctx context.Context, key crypto.PublicKey, data []byte, signature []byte, method *SignatureMethod) error {
+			publicKey, ok := key.(*ecdsa.PublicKey)
+			if !ok {
+				return ErrInvalidVerificationKey
+			}
+			if len(signature) != 2*ecdsaKeySize(publicKey) {
+				return errors.New("ecdsa signature has an invalid length")
+			}
+			asn1Signature, err := ecdsaRawToASN1(signature)
+			if err != nil {
+				return err
+			}
+			if !ecdsa.VerifyASN1(publicKey, hashData(hash, data), asn1Signature) {
+				return errors.New("ecdsa signature verification failed")
+			}
+			return nil
+		},
+	}
+}
+
+func ecdsaKeySize(publicKey *ecdsa.PublicKey) int {
+	return (publicKey.Curve.Params().BitSize + 7) / 8
+}
+
+// XMLDSig encodes ecdsa signatures as the raw concatenation r || s, where both
+// integers are left padded to the size of the curve order.
+func ecdsaRawToASN1(signature []byte) ([]byte, error) {
+	if len(signature) == 0 || len(signature)%2 != 0 {
+		return nil, errors.New("ecdsa signature has an invalid length")
+	}
+	size := len(signature) / 2
+	return asn1.Marshal(ecdsaSignature{
+		R: new(big.Int).SetBytes(signature[:size]),
+		S: new(big.Int).SetBytes(signature[size:]),
+	})
+}
+
+func ecdsaASN1ToRaw(signature []byte, size int) ([]byte, error) {
+	var parsed ecdsaSignature
+	rest, err := asn1.Unmarshal(signature, &parsed)
+	if err != nil {
+		return nil, err
+	}
+	if len(rest) > 0 {
+		return nil, errors.New("ecdsa signature contains trailing data")
+	}
+	if parsed.R == nil || parsed.S == nil || parsed.R.Sign() <= 0 || parsed.S.Sign() <= 0 {
+		return nil, errors.New("ecdsa signature contains invalid integers")
+	}
+	if parsed.R.BitLen() > size*8 || parsed.S.BitLen() > size*8 {
+		return nil, errors.New("ecdsa signature integers exceed the key size")
+	}
+
+	raw := make([]byte, 2*size)
+	parsed.R.FillBytes(raw[:size])
+	parsed.S.FillBytes(raw[size:])
+	return raw, nil
+}
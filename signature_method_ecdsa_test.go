@@ -0,0 +1,167 @@
+package xmldsig
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"encoding/asn1"
+	"math/big"
+	"testing"
+)
+
+func Test_ECDSA_SignVerify(t *testing.T) {
+	tests := []struct {
+		name  string
+		curve elliptic.Curve
+		size  int
+	}{
+		{"P-256", elliptic.P256(), 32},
+		{"P-384", elliptic.P384(), 48},
+		{"P-521", elliptic.P521(), 66},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			key, err := ecdsa.GenerateKey(tt.curve, rand.Reader)
+			if err != nil {
+				t.Fatal(err)
+			}
+			algorithm := newECDSASignatureAlgorithm(crypto.SHA256)
+			data := []byte("signed info")
+
+			// Signatures are always twice the key size, also when r or s is short
+			for i := 0; i < 16; i++ {
+				signature, err := algorithm.Sign(ctx, key, data, nil)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if len(signature) != 2*tt.size {
+					t.Fatalf("expected a %d byte signature, got %d", 2*tt.size, len(signature))
+				}
+				err = algorithm.Verify(ctx, key.Public(), data, signature, nil)
+				if err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			err = algorithm.Verify(ctx, key.Public(), []byte("other data"), mustSignECDSA(t, algorithm, key, data), nil)
+			if err == nil {
+				t.Error("expected verification of other data to fail")
+			}
+		})
+	}
+}
+
+func Test_ECDSA_VerifyInvalidLength(t *testing.T) {
+	ctx := context.Background()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	algorithm := newECDSASignatureAlgorithm(crypto.SHA256)
+	data := []byte("signed info")
+	signature := mustSignECDSA(t, algorithm, key, data)
+
+	tests := []struct {
+		name      string
+		signature []byte
+	}{
+		{"Empty", []byte{}},
+		{"Truncated", signature[:len(signature)-1]},
+		{"Extended", append(append([]byte{}, signature...), 0)},
+		{"ASN.1", mustMarshalECDSA(t, signature)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := algorithm.Verify(ctx, key.Public(), data, tt.signature, nil)
+			if err == nil {
+				t.Error("expected verification to fail")
+			}
+		})
+	}
+}
+
+func Test_ECDSA_ASN1ToRaw(t *testing.T) {
+	// Short integers are left padded to the key size
+	signature, err := asn1.Marshal(ecdsaSignature{R: big.NewInt(1), S: big.NewInt(0x0102)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	raw, err := ecdsaASN1ToRaw(signature, 66)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := make([]byte, 132)
+	expected[65] = 0x01
+	expected[130] = 0x01
+	expected[131] = 0x02
+	if !bytes.Equal(raw, expected) {
+		t.Errorf("unexpected raw signature: %x", raw)
+	}
+
+	// The raw form converts back to the same integers
+	converted, err := ecdsaRawToASN1(raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(converted, signature) {
+		t.Errorf("expected %x, got %x", signature, converted)
+	}
+
+	tooLarge := new(big.Int).Lsh(big.NewInt(1), 256)
+	tests := []struct {
+		name      string
+		signature ecdsaSignature
+	}{
+		{"Zero", ecdsaSignature{R: big.NewInt(0), S: big.NewInt(1)}},
+		{"Negative", ecdsaSignature{R: big.NewInt(1), S: big.NewInt(-1)}},
+		{"TooLarge", ecdsaSignature{R: tooLarge, S: big.NewInt(1)}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			signature, err := asn1.Marshal(tt.signature)
+			if err != nil {
+				t.Fatal(err)
+			}
+			_, err = ecdsaASN1ToRaw(signature, 32)
+			if err == nil {
+				t.Error("expected the conversion to fail")
+			}
+		})
+	}
+
+	_, err = ecdsaASN1ToRaw(append(signature, 0), 66)
+	if err == nil {
+		t.Error("expected trailing data to be rejected")
+	}
+}
+
+func Test_ECDSA_RawToASN1InvalidLength(t *testing.T) {
+	for _, length := range []int{0, 1, 63} {
+		_, err := ecdsaRawToASN1(make([]byte, length))
+		if err == nil {
+			t.Errorf("expected a %d byte signature to be rejected", length)
+		}
+	}
+}
+
+func mustSignECDSA(t *testing.T, algorithm *SignatureAlgorithm, key *ecdsa.PrivateKey, data []byte) []byte {
+	t.Helper()
+	signature, err := algorithm.Sign(context.Background(), key, data, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return signature
+}
+
+func mustMarshalECDSA(t *testing.T, raw []byte) []byte {
+	t.Helper()
+	signature, err := ecdsaRawToASN1(raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return signature
+}
@@ -0,0 +1,35 @@
+package xmldsig
+
+import (
+	"context"
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"errors"
+)
+
+// Pure EdDSA signs and verifies the message itself, without a pre-hash.
+func newEd25519SignatureAlgorithm() *SignatureAlgorithm {
+	return &SignatureAlgorithm{
+		Sign: func(ctx context.Context, key crypto.PrivateKey, data []byte, method *SignatureMethod) ([]byte, error) {
+			signer, ok := key.(crypto.Signer)
+			if !ok {
+				return nil, ErrInvalidSigningKey
+			}
+			if _, ok := signer.Public().(ed25519.PublicKey); !ok {
+				return nil, ErrInvalidSigningKey
+			}
+			return signer.Sign(rand.Reader, data, crypto.Hash(0))
+		},
+		Verify: func(ctx context.Context, key crypto.PublicKey, data []byte, signature []byte, method *SignatureMethod) error {
+			publicKey, ok := key.(ed25519.PublicKey)
+			if !ok {
+				return ErrInvalidVerificationKey
+			}
+			if !ed25519.Verify(publicKey, data, signature) {
+				return errors.New("ed25519 signature verification failed")
+			}
+			return nil
+		},
+	}
+}
@@ -0,0 +1,102 @@
+package xmldsig
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"strings"
+	"testing"
+
+	"github.com/deb-ict/go-xmldsig/transform"
+)
+
+func Test_Ed25519_SignVerify(t *testing.T) {
+	ctx := context.Background()
+	publicKey, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	doc := parseTestDocument(t, testDocument)
+	signedXml := NewSignedXml(doc)
+	signedXml.SetSignatureMethod(SignatureMethod_EdDSA_Ed25519)
+	_, err = signedXml.AddReference("", DigestMethod_SHA256, transform.EnvelopedSignatureTransform)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = signedXml.ComputeSignature(ctx, privateKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signed, err := doc.WriteToString()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	loadedXml, err := LoadSignedXml(parseTestDocument(t, signed))
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = loadedXml.ValidateSignatureWithKey(ctx, publicKey)
+	if err != nil {
+		t.Fatalf("validate: %v", err)
+	}
+
+	tamperedXml, err := LoadSignedXml(parseTestDocument(t, strings.Replace(signed, "10.00", "11.00", 1)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = tamperedXml.ValidateSignatureWithKey(ctx, publicKey)
+	if err == nil {
+		t.Error("expected a modified document to fail validation")
+	}
+}
+
+func Test_Ed25519_RejectsOtherKeys(t *testing.T) {
+	ctx := context.Background()
+	ecdsaKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	algorithm := newEd25519SignatureAlgorithm()
+	data := []byte("signed info")
+
+	_, err = algorithm.Sign(ctx, ecdsaKey, data, nil)
+	if err != ErrInvalidSigningKey {
+		t.Errorf("expected ErrInvalidSigningKey for an ecdsa key, got %v", err)
+	}
+	_, err = algorithm.Sign(ctx, []byte("secret"), data, nil)
+	if err != ErrInvalidSigningKey {
+		t.Errorf("expected ErrInvalidSigningKey for an hmac key, got %v", err)
+	}
+	err = algorithm.Verify(ctx, ecdsaKey.Public(), data, make([]byte, ed25519.SignatureSize), nil)
+	if err != ErrInvalidVerificationKey {
+		t.Errorf("expected ErrInvalidVerificationKey for an ecdsa key, got %v", err)
+	}
+}
+
+func Test_Ed448_NotSupported(t *testing.T) {
+	ed448 := "http://www.w3.org/2021/04/xmldsig-more#eddsa-ed448"
+	_, err := GetSignatureMethod(ed448)
+	if err != ErrInvalidSignatureMethod {
+		t.Errorf("expected ErrInvalidSignatureMethod, got %v", err)
+	}
+
+	// Signing fails up front, before any digest is computed
+	_, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signedXml := NewSignedXml(parseTestDocument(t, testDocument))
+	signedXml.SetSignatureMethod(ed448)
+	_, err = signedXml.AddReference("", DigestMethod_SHA256, transform.EnvelopedSignatureTransform)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = signedXml.ComputeSignature(context.Background(), privateKey)
+	if err != ErrInvalidSignatureMethod {
+		t.Errorf("expected ErrInvalidSignatureMethod, got %v", err)
+	}
+}
@@ -0,0 +1,52 @@
+package xmldsig
+
+import (
+	"context"
+	"crypto"
+	"crypto/hmac"
+	"errors"
+)
+
+func newHMACSignatureAlgorithm(hash crypto.Hash) *SignatureAlgorithm {
+	return &SignatureAlgorithm{
+		Sign: func(ctx context.Context, key crypto.PrivateKey, data []byte, method *SignatureMethod) ([]byte, error) {
+			hmacKey, ok := key.([]byte)
+			if !ok || len(hmacKey) == 0 {
+				return nil, ErrInvalidSigningKey
+			}
+			return computeHmac(hash, hmacKey, data, method.getHMACOutputLength())
+		},
+		Verify: func(ctx context.Context, key crypto.PublicKey, data []byte, signature []byte, method *SignatureMethod) error {
+			hmacKey, ok := key.([]byte)
+			if !ok || len(hmacKey) == 0 {
+				return ErrInvalidVerificationKey
+			}
+			expected, err := computeHmac(hash, hmacKey, data, method.getHMACOutputLength())
+			if err != nil {
+				return err
+			}
+			if !hmac.Equal(expected, signature) {
+				return errors.New("hmac signature verification failed")
+			}
+			return nil
+		},
+	}
+}
+
+func computeHmac(hash crypto.Hash, key []byte, data []byte, outputLength int) ([]byte, error) {
+	mac := hmac.New(hash.New, key)
+	mac.Write(data)
+	signature := mac.Sum(nil)
+
+	// Reject truncated outputs below half the hash length or 80 bits (CVE-2009-0217)
+	if outputLength == 0 {
+		return signature, nil
+	}
+	if outputLength%8 != 0 || outputLength > hash.Size()*8 {
+		return nil, errors.New("invalid hmac output length")
+	}
+	if outputLength < 80 || outputLength < hash.Size()*4 {
+		return nil, errors.New("hmac output length is too short")
+	}
+	return signature[:outputLength/8], nil
+}
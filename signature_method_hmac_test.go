@@ -0,0 +1,104 @@
+package xmldsig
+
+import (
+	"context"
+	"crypto"
+	"encoding/base64"
+	"strings"
+	"testing"
+
+	"github.com/deb-ict/go-xmldsig/transform"
+)
+
+func Test_HMAC_OutputLength(t *testing.T) {
+	key := []byte("secret")
+	data := []byte("signed info")
+
+	tests := []struct {
+		name         string
+		hash         crypto.Hash
+		outputLength int
+		length       int
+		fails        bool
+	}{
+		{"Untruncated", crypto.SHA256, 0, 32, false},
+		{"Full", crypto.SHA256, 256, 32, false},
+		{"Half", crypto.SHA256, 128, 16, false},
+		{"SHA1Minimum", crypto.SHA1, 80, 10, false},
+		{"Below80", crypto.SHA1, 72, 0, true},
+		{"BelowHalf", crypto.SHA256, 120, 0, true},
+		{"BelowHalfSHA512", crypto.SHA512, 248, 0, true},
+		{"NotMultipleOf8", crypto.SHA256, 130, 0, true},
+		{"AboveHashSize", crypto.SHA256, 264, 0, true},
+		{"Negative", crypto.SHA256, -8, 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			signature, err := computeHmac(tt.hash, key, data, tt.outputLength)
+			if tt.fails {
+				if err == nil {
+					t.Errorf("expected output length %d to be rejected", tt.outputLength)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(signature) != tt.length {
+				t.Errorf("expected a %d byte signature, got %d", tt.length, len(signature))
+			}
+		})
+	}
+}
+
+func Test_HMAC_VerifyTruncatedSignature(t *testing.T) {
+	ctx := context.Background()
+	key := []byte("secret")
+	doc := parseTestDocument(t, testDocument)
+	signedXml := NewSignedXml(doc)
+	signedXml.SetSignatureMethod(SignatureMethod_HMAC_SHA256)
+	signedXml.GetSignature().SignedInfo.SignatureMethod.WithHMACOutputLength(128)
+	_, err := signedXml.AddReference("", DigestMethod_SHA256, transform.EnvelopedSignatureTransform)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = signedXml.ComputeHmacSignature(ctx, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signatureValue, err := base64.StdEncoding.DecodeString(signedXml.GetSignature().SignatureValue.Value)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(signatureValue) != 16 {
+		t.Fatalf("expected a 16 byte signature, got %d", len(signatureValue))
+	}
+	signed, err := doc.WriteToString()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	loadedXml, err := LoadSignedXml(parseTestDocument(t, signed))
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = loadedXml.ValidateHmacSignature(ctx, key)
+	if err != nil {
+		t.Fatalf("validate: %v", err)
+	}
+
+	// A signature truncated by the attacker to a few bits is rejected (CVE-2009-0217)
+	tampered := strings.Replace(signed, "<ds:HMACOutputLength>128</ds:HMACOutputLength>", "<ds:HMACOutputLength>8</ds:HMACOutputLength>", 1)
+	tampered = strings.Replace(tampered, signedXml.GetSignature().SignatureValue.Value, base64.StdEncoding.EncodeToString(signatureValue[:1]), 1)
+	if tampered == signed {
+		t.Fatal("expected the HMACOutputLength to be replaced")
+	}
+	tamperedXml, err := LoadSignedXml(parseTestDocument(t, tampered))
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = tamperedXml.ValidateHmacSignature(ctx, key)
+	if err == nil || !strings.Contains(err.Error(), "too short") {
+		t.Errorf("expected a truncated signature to be rejected, got %v", err)
+	}
+}
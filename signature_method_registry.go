@@ -0,0 +1,108 @@
+package xmldsig
+
+import (
+	"context"
+	"crypto"
+	"sync"
+)
+
+type SignSignatureMethod func(ctx context.Context, key crypto.PrivateKey, data []byte, method *SignatureMethod) ([]byte, error)
+type VerifySignatureMethod func(ctx context.Context, key crypto.PublicKey, data []byte, signature []byte, method *SignatureMethod) error
+
+type SignatureAlgorithm struct {
+	Sign   SignSignatureMethod
+	Verify VerifySignatureMethod
+}
+
+const (
+	SignatureMethod_RSA_SHA1          string = "http://www.w3.org/2000/09/xmldsig#rsa-sha1"
+	SignatureMethod_RSA_SHA224        string = "http://www.w3.org/2001/04/xmldsig-more#rsa-sha224"
+	SignatureMethod_RSA_SHA256        string = "http://www.w3.org/2001/04/xmldsig-more#rsa-sha256"
+	SignatureMethod_RSA_SHA384        string = "http://www.w3.org/2001/04/xmldsig-more#rsa-sha384"
+	SignatureMethod_RSA_SHA512        string = "http://www.w3.org/2001/04/xmldsig-more#rsa-sha512"
+	SignatureMethod_ECDSA_SHA1        string = "http://www.w3.org/2001/04/xmldsig-more#ecdsa-sha1"
+	SignatureMethod_ECDSA_SHA224      string = "http://www.w3.org/2001/04/xmldsig-more#ecdsa-sha224"
+	SignatureMethod_ECDSA_SHA256      string = "http://www.w3.org/2001/04/xmldsig-more#ecdsa-sha256"
+	SignatureMethod_ECDSA_SHA384      string = "http://www.w3.org/2001/04/xmldsig-more#ecdsa-sha384"
+	SignatureMethod_ECDSA_SHA512      string = "http://www.w3.org/2001/04/xmldsig-more#ecdsa-sha512"
+	SignatureMethod_ECDSA_SHA3_224    string = "http://www.w3.org/2021/04/xmldsig-more#ecdsa-sha3-224"
+	SignatureMethod_ECDSA_SHA3_256    string = "http://www.w3.org/2021/04/xmldsig-more#ecdsa-sha3-256"
+	SignatureMethod_ECDSA_SHA3_384    string = "http://www.w3.org/2021/04/xmldsig-more#ecdsa-sha3-384"
+	SignatureMethod_ECDSA_SHA3_512    string = "http://www.w3.org/2021/04/xmldsig-more#ecdsa-sha3-512"
+	SignatureMethod_SHA1_RSA_MGF1     string = "http://www.w3.org/2007/05/xmldsig-more#sha1-rsa-MGF1"
+	SignatureMethod_SHA224_RSA_MGF1   string = "http://www.w3.org/2007/05/xmldsig-more#sha224-rsa-MGF1"
+	SignatureMethod_SHA256_RSA_MGF1   string = "http://www.w3.org/2007/05/xmldsig-more#sha256-rsa-MGF1"
+	SignatureMethod_SHA384_RSA_MGF1   string = "http://www.w3.org/2007/05/xmldsig-more#sha384-rsa-MGF1"
+	SignatureMethod_SHA512_RSA_MGF1   string = "http://www.w3.org/2007/05/xmldsig-more#sha512-rsa-MGF1"
+	SignatureMethod_SHA3_224_RSA_MGF1 string = "http://www.w3.org/2007/05/xmldsig-more#sha3-224-rsa-MGF1"
+	SignatureMethod_SHA3_256_RSA_MGF1 string = "http://www.w3.org/2007/05/xmldsig-more#sha3-256-rsa-MGF1"
+	SignatureMethod_SHA3_384_RSA_MGF1 string = "http://www.w3.org/2007/05/xmldsig-more#sha3-384-rsa-MGF1"
+	SignatureMethod_SHA3_512_RSA_MGF1 string = "http://www.w3.org/2007/05/xmldsig-more#sha3-512-rsa-MGF1"
+	SignatureMethod_RSA_PSS           string = "http://www.w3.org/2007/05/xmldsig-more#rsa-pss"
+	SignatureMethod_EdDSA_Ed25519     string = "http://www.w3.org/2021/04/xmldsig-more#eddsa-ed25519"
+	SignatureMethod_HMAC_SHA1         string = "http://www.w3.org/2000/09/xmldsig#hmac-sha1"
+	SignatureMethod_HMAC_SHA224       string = "http://www.w3.org/2001/04/xmldsig-more#hmac-sha224"
+	SignatureMethod_HMAC_SHA256       string = "http://www.w3.org/2001/04/xmldsig-more#hmac-sha256"
+	SignatureMethod_HMAC_SHA384       string = "http://www.w3.org/2001/04/xmldsig-more#hmac-sha384"
+	SignatureMethod_HMAC_SHA512       string = "http://www.w3.org/2001/04/xmldsig-more#hmac-sha512"
+)
+
+var (
+	registeredSignatureMethods map[string]*SignatureAlgorithm = map[string]*SignatureAlgorithm{
+		SignatureMethod_RSA_SHA1:          newRSASignatureAlgorithm(crypto.SHA1),
+		SignatureMethod_RSA_SHA224:        newRSASignatureAlgorithm(crypto.SHA224),
+		SignatureMethod_RSA_SHA256:        newRSASignatureAlgorithm(crypto.SHA256),
+		SignatureMethod_RSA_SHA384:        newRSASignatureAlgorithm(crypto.SHA384),
+		SignatureMethod_RSA_SHA512:        newRSASignatureAlgorithm(crypto.SHA512),
+		SignatureMethod_ECDSA_SHA1:        newECDSASignatureAlgorithm(crypto.SHA1),
+		SignatureMethod_ECDSA_SHA224:      newECDSASignatureAlgorithm(crypto.SHA224),
+		SignatureMethod_ECDSA_SHA256:      newECDSASignatureAlgorithm(crypto.SHA256),
+		SignatureMethod_ECDSA_SHA384:      newECDSASignatureAlgorithm(crypto.SHA384),
+		SignatureMethod_ECDSA_SHA512:      newECDSASignatureAlgorithm(crypto.SHA512),
+		SignatureMethod_ECDSA_SHA3_224:    newECDSASignatureAlgorithm(crypto.SHA3_224),
+		SignatureMethod_ECDSA_SHA3_256:    newECDSASignatureAlgorithm(crypto.SHA3_256),
+		SignatureMethod_ECDSA_SHA3_384:    newECDSASignatureAlgorithm(crypto.SHA3_384),
+		SignatureMethod_ECDSA_SHA3_512:    newECDSASignatureAlgorithm(crypto.SHA3_512),
+		SignatureMethod_SHA1_RSA_MGF1:     newRSAPSSSignatureAlgorithm(crypto.SHA1, false),
+		SignatureMethod_SHA224_RSA_MGF1:   newRSAPSSSignatureAlgorithm(crypto.SHA224, false),
+		SignatureMethod_SHA256_RSA_MGF1:   newRSAPSSSignatureAlgorithm(crypto.SHA256, false),
+		SignatureMethod_SHA384_RSA_MGF1:   newRSAPSSSignatureAlgorithm(crypto.SHA384, false),
+		SignatureMethod_SHA512_RSA_MGF1:   newRSAPSSSignatureAlgorithm(crypto.SHA512, false),
+		SignatureMethod_SHA3_224_RSA_MGF1: newRSAPSSSignatureAlgorithm(crypto.SHA3_224, false),
+		SignatureMethod_SHA3_256_RSA_MGF1: newRSAPSSSignatureAlgorithm(crypto.SHA3_256, false),
+		SignatureMethod_SHA3_384_RSA_MGF1: newRSAPSSSignatureAlgorithm(crypto.SHA3_384, false),
+		SignatureMethod_SHA3_512_RSA_MGF1: newRSAPSSSignatureAlgorithm(crypto.SHA3_512, false),
+		SignatureMethod_RSA_PSS:           newRSAPSSSignatureAlgorithm(crypto.SHA256, true),
+		SignatureMethod_EdDSA_Ed25519:     newEd25519SignatureAlgorithm(),
+		SignatureMethod_HMAC_SHA1:         newHMACSignatureAlgorithm(crypto.SHA1),
+		SignatureMethod_HMAC_SHA224:       newHMACSignatureAlgorithm(crypto.SHA224),
+		SignatureMethod_HMAC_SHA256:       newHMACSignatureAlgorithm(crypto.SHA256),
+		SignatureMethod_HMAC_SHA384:       newHMACSignatureAlgorithm(crypto.SHA384),
+		SignatureMethod_HMAC_SHA512:       newHMACSignatureAlgorithm(crypto.SHA512),
+	}
+	registeredSignatureMethodsLock sync.RWMutex
+)
+
+func RegisterSignatureMethod(uri string, sign SignSignatureMethod, verify VerifySignatureMethod) {
+	registeredSignatureMethodsLock.Lock()
+	defer registeredSignatureMethodsLock.Unlock()
+	registeredSignatureMethods[uri] = &SignatureAlgorithm{
+		Sign:   sign,
+		Verify: verify,
+	}
+}
+
+func GetSignatureMethod(uri string) (*SignatureAlgorithm, error) {
+	registeredSignatureMethodsLock.RLock()
+	defer registeredSignatureMethodsLock.RUnlock()
+	if algorithm, ok := registeredSignatureMethods[uri]; ok {
+		return algorithm, nil
+	}
+	return nil, ErrInvalidSignatureMethod
+}
+
+func hashData(hash crypto.Hash, data []byte) []byte {
+	hashAlgorithm := hash.New()
+	hashAlgorithm.Write(data)
+	return hashAlgorithm.Sum(nil)
+}
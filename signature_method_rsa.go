@@ -0,0 +1,114 @@
+package xmldsig
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"errors"
+)
+
+func newRSASignatureAlgorithm(hash crypto.Hash) *SignatureAlgorithm {
+	return &SignatureAlgorithm{
+		Sign: func(ctx context.Context, key crypto.PrivateKey, data []byte, method *SignatureMethod) ([]byte, error) {
+			signer, err := getRSASigner(key)
+			if err != nil {
+				return nil, err
+			}
+			return signer.Sign(rand.Reader, hashData(hash, data), hash)
+		},
+		Verify: func(ctx context.Context, key crypto.PublicKey, data []byte, signature []byte, method *SignatureMethod) error {
+			publicKey, ok := key.(*rsa.PublicKey)
+			if !ok {
+				return ErrInvalidVerificationKey
+			}
+			return rsa.VerifyPKCS1v15(publicKey, hash, hashData(hash, data), signature)
+		},
+	}
+}
+
+func newRSAPSSSignatureAlgorithm(hash crypto.Hash, parameterized bool) *SignatureAlgorithm {
+	getOptions := func(method *SignatureMethod) (*rsa.PSSOptions, error) {
+		if !parameterized {
+			return &rsa.PSSOptions{SaltLength: hash.Size(), Hash: hash}, nil
+		}
+		return getRSAPSSOptions(hash, method.getRSAPSSParams())
+	}
+
+	return &SignatureAlgorithm{
+		Sign: func(ctx context.Context, key crypto.PrivateKey, data []byte, method *SignatureMethod) ([]byte, error) {
+			signer, err := getRSASigner(key)
+			if err != nil {
+				return nil, err
+			}
+			options, err := getOptions(method)
+			if err != nil {
+				return nil, err
+			}
+			return signer.Sign(rand.Reader, hashData(options.Hash, data), options)
+		},
+		Verify: func(ctx context.Context, key crypto.PublicKey, data []byte, signature []byte, method *SignatureMethod) error {
+			publicKey, ok := key.(*rsa.PublicKey)
+			if !ok {
+				return ErrInvalidVerificationKey
+			}
+			options, err := getOptions(method)
+			if err != nil {
+				return err
+			}
+			return rsa.VerifyPSS(publicKey, options.Hash, hashData(options.Hash, data), signature, options)
+		},
+	}
+}
+
+func getRSASigner(key crypto.PrivateKey) (crypto.Signer, error) {
+	signer, ok := key.(crypto.Signer)
+	if !ok {
+		return nil, ErrInvalidSigningKey
+	}
+	if _, ok := signer.Public().(*rsa.PublicKey); !ok {
+		return nil, ErrInvalidSigningKey
+	}
+	return signer, nil
+}
+
+func getRSAPSSOptions(hash crypto.Hash, params *RSAPSSParams) (*rsa.PSSOptions, error) {
+	if params == nil {
+		return &rsa.PSSOptions{SaltLength: hash.Size(), Hash: hash}, nil
+	}
+
+	if params.DigestMethod != "" {
+		digestHash, err := getDigestHash(params.DigestMethod)
+		if err != nil {
+			return nil, err
+		}
+		hash = digestHash
+	}
+	options := &rsa.PSSOptions{
+		SaltLength: hash.Size(),
+		Hash:       hash,
+	}
+
+	if params.MaskGenerationFunction != "" && params.MaskGenerationFunction != MaskGenerationFunction_MGF1 {
+		return nil, errors.New("unsupported mask generation function: " + params.MaskGenerationFunction)
+	}
+	if params.MaskGenerationDigestMethod != "" {
+		mgfHash, err := getDigestHash(params.MaskGenerationDigestMethod)
+		if err != nil {
+			return nil, err
+		}
+		if mgfHash != hash {
+			return nil, errors.New("mask generation function digest must match the signature digest")
+		}
+	}
+	if params.SaltLength < 0 {
+		return nil, errors.New("rsa-pss salt length is negative")
+	}
+	if params.SaltLength > 0 {
+		options.SaltLength = params.SaltLength
+	}
+	if params.TrailerField != 0 && params.TrailerField != 1 {
+		return nil, errors.New("unsupported rsa-pss trailer field")
+	}
+	return options, nil
+}
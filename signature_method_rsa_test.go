@@ -0,0 +1,166 @@
+package xmldsig
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"strings"
+	"testing"
+
+	"github.com/deb-ict/go-xmldsig/transform"
+)
+
+func Test_RSAPSS_SignatureRoundTrip(t *testing.T) {
+	ctx := context.Background()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	doc := parseTestDocument(t, testDocument)
+	signedXml := NewSignedXml(doc)
+	signedXml.GetSignature().SignedInfo.WithSignatureMethod(NewSignatureMethod(SignatureMethod_RSA_PSS).WithRSAPSSParams(DigestMethod_SHA384, 20))
+	_, err = signedXml.AddReference("", DigestMethod_SHA256, transform.EnvelopedSignatureTransform)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = signedXml.ComputeSignature(ctx, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signed, err := doc.WriteToString()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The parameters are written and read again
+	loadedXml, err := LoadSignedXml(parseTestDocument(t, signed))
+	if err != nil {
+		t.Fatal(err)
+	}
+	params := loadedXml.GetSignature().SignedInfo.SignatureMethod.RSAPSSParams
+	expected := RSAPSSParams{
+		DigestMethod:               DigestMethod_SHA384,
+		MaskGenerationFunction:     MaskGenerationFunction_MGF1,
+		MaskGenerationDigestMethod: DigestMethod_SHA384,
+		SaltLength:                 20,
+		TrailerField:               1,
+	}
+	if params == nil || *params != expected {
+		t.Fatalf("expected the parameters %+v, got %+v", expected, params)
+	}
+	_, err = loadedXml.ValidateSignatureWithKey(ctx, key.Public())
+	if err != nil {
+		t.Errorf("validate: %v", err)
+	}
+}
+
+func Test_RSAPSS_SignatureParameters(t *testing.T) {
+	ctx := context.Background()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data := []byte("signed info")
+	algorithm, err := GetSignatureMethod(SignatureMethod_RSA_PSS)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	method := NewSignatureMethod(SignatureMethod_RSA_PSS).WithRSAPSSParams(DigestMethod_SHA512, 24)
+	signature, err := algorithm.Sign(ctx, key, data, method)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The signature uses the digest and salt length of the parameters
+	err = rsa.VerifyPSS(&key.PublicKey, crypto.SHA512, hashData(crypto.SHA512, data), signature, &rsa.PSSOptions{SaltLength: 24})
+	if err != nil {
+		t.Errorf("expected a SHA-512 signature with a 24 byte salt: %v", err)
+	}
+	err = rsa.VerifyPSS(&key.PublicKey, crypto.SHA512, hashData(crypto.SHA512, data), signature, &rsa.PSSOptions{SaltLength: 64})
+	if err == nil {
+		t.Error("expected the signature not to use the default salt length")
+	}
+	err = algorithm.Verify(ctx, key.Public(), data, signature, method)
+	if err != nil {
+		t.Errorf("verify: %v", err)
+	}
+
+	// Without parameters SHA-256 is used with a salt of the digest size
+	signature, err = algorithm.Sign(ctx, key, data, NewSignatureMethod(SignatureMethod_RSA_PSS))
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = rsa.VerifyPSS(&key.PublicKey, crypto.SHA256, hashData(crypto.SHA256, data), signature, &rsa.PSSOptions{SaltLength: 32})
+	if err != nil {
+		t.Errorf("expected a SHA-256 signature with a 32 byte salt: %v", err)
+	}
+}
+
+func Test_RSAPSS_InvalidParameters(t *testing.T) {
+	tests := []struct {
+		name   string
+		params string
+		err    string
+	}{
+		{
+			name:   "TrailerField",
+			params: `<pss:TrailerField>2</pss:TrailerField>`,
+			err:    "trailer field",
+		},
+		{
+			name: "MaskGenerationDigestMismatch",
+			params: `<ds:DigestMethod Algorithm="http://www.w3.org/2001/04/xmlenc#sha256"/>` +
+				`<pss:MaskGenerationFunction Algorithm="http://www.w3.org/2007/05/xmldsig-more#MGF1"><ds:DigestMethod Algorithm="http://www.w3.org/2001/04/xmlenc#sha512"/></pss:MaskGenerationFunction>`,
+			err: "mask generation function digest",
+		},
+		{
+			name:   "UnsupportedMaskGenerationFunction",
+			params: `<pss:MaskGenerationFunction Algorithm="urn:mgf2"/>`,
+			err:    "unsupported mask generation function",
+		},
+		{
+			name:   "UnsupportedDigestMethod",
+			params: `<ds:DigestMethod Algorithm="urn:digest"/>`,
+			err:    ErrInvalidDigestMethod.Error(),
+		},
+		{
+			name:   "NegativeSaltLength",
+			params: `<pss:SaltLength>-1</pss:SaltLength>`,
+			err:    "salt length",
+		},
+	}
+
+	ctx := context.Background()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	algorithm, err := GetSignatureMethod(SignatureMethod_RSA_PSS)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc := parseTestDocument(t, `<ds:SignatureMethod xmlns:ds="http://www.w3.org/2000/09/xmldsig#" Algorithm="http://www.w3.org/2007/05/xmldsig-more#rsa-pss">`+
+				`<pss:RSAPSSParams xmlns:pss="http://www.w3.org/2007/05/xmldsig-more#">`+tt.params+`</pss:RSAPSSParams>`+
+				`</ds:SignatureMethod>`)
+			method := newSignatureMethod(nil)
+			err := method.loadXml(doc.Root())
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			_, err = algorithm.Sign(ctx, key, []byte("data"), method)
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Errorf("expected signing to fail with %q, got %v", tt.err, err)
+			}
+			err = algorithm.Verify(ctx, key.Public(), []byte("data"), make([]byte, 256), method)
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Errorf("expected verifying to fail with %q, got %v", tt.err, err)
+			}
+		})
+	}
+}
@@ -18,6 +18,9 @@ func newSignatureValue(signature *Signature) *SignatureValue {
 }
 
 func (xml *SignatureValue) root() *SignedXml {
+	if xml.signature == nil {
+		return nil
+	}
 	return xml.signature.root()
 }
 
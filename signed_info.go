@@ -2,12 +2,11 @@ package xmldsig
 
 import (
 	"context"
-	"crypto/x509"
+	"crypto"
 	"encoding/base64"
 	"errors"
 
 	"github.com/beevik/etree"
-	rhtree "github.com/russellhaering/goxmldsig/etreeutils"
 )
 
 type SignedInfo struct {
@@ -25,10 +24,93 @@ func newSignedInfo(signature *Signature) *SignedInfo {
 	}
 }
 
+func NewSignedInfo() *SignedInfo {
+	return newSignedInfo(nil)
+}
+
+func (xml *SignedInfo) WithId(id string) *SignedInfo {
+	xml.Id = id
+	return xml
+}
+
+func (xml *SignedInfo) WithCanonicalizationMethod(canonicalizationMethod *CanonicalizationMethod) *SignedInfo {
+	if canonicalizationMethod != nil {
+		canonicalizationMethod.signedInfo = xml
+	}
+	xml.CanonicalizationMethod = canonicalizationMethod
+	return xml
+}
+
+func (xml *SignedInfo) WithSignatureMethod(signatureMethod *SignatureMethod) *SignedInfo {
+	if signatureMethod != nil {
+		signatureMethod.signedInfo = xml
+	}
+	xml.SignatureMethod = signatureMethod
+	return xml
+}
+
+func (xml *SignedInfo) WithReferences(references ...*Reference) *SignedInfo {
+	for _, reference := range references {
+		xml.AddReference(reference)
+	}
+	return xml
+}
+
+func (xml *SignedInfo) AddReference(reference *Reference) {
+	if reference == nil {
+		return
+	}
+	reference.signedInfo = xml
+	xml.References = append(xml.References, reference)
+}
+
 func (xml *SignedInfo) root() *SignedXml {
+	if xml.signature == nil {
+		return nil
+	}
 	return xml.signature.root()
 }
 
+func (xml *SignedInfo) bind(signature *Signature) {
+	xml.signature = signature
+	if xml.CanonicalizationMethod != nil {
+		xml.CanonicalizationMethod.signedInfo = xml
+	}
+	if xml.SignatureMethod != nil {
+		xml.SignatureMethod.signedInfo = xml
+	}
+	for _, reference := range xml.References {
+		reference.bind(xml)
+	}
+}
+
+func (xml *SignedInfo) validate() error {
+	if xml.CanonicalizationMethod == nil {
+		return errors.New("signed info does not contain a CanonicalizationMethod element")
+	}
+	err := xml.CanonicalizationMethod.ensureCanonicalizer()
+	if err != nil {
+		return err
+	}
+	if xml.SignatureMethod == nil {
+		return errors.New("signed info does not contain a SignatureMethod element")
+	}
+	_, err = GetSignatureMethod(xml.SignatureMethod.Algorithm)
+	if err != nil {
+		return err
+	}
+	if len(xml.References) == 0 {
+		return errors.New("signed info does not contain a Reference element")
+	}
+	for _, reference := range xml.References {
+		err := reference.validate()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 func (xml *SignedInfo) validateDigests(ctx context.Context) ([]*etree.Element, error) {
 	validated := make([]*etree.Element, 0)
 	for _, reference := range xml.References {
@@ -41,26 +123,46 @@ func (xml *SignedInfo) validateDigests(ctx context.Context) ([]*etree.Element, e
 	return validated, nil
 }
 
-func (xml *SignedInfo) validateSignature(ctx context.Context, cert *x509.Certificate) error {
-	elementNsContext, err := rhtree.NSBuildParentContext(xml.cachedXml)
-	if err != nil {
-		return err
+// bindTransforms binds the transforms of the references to their elements in
+// the signed info element.
+func (xml *SignedInfo) bindTransforms(el *etree.Element) error {
+	referenceElements := selectChildElements(el, "Reference", XmlDSigNamespaceUri)
+	if len(referenceElements) != len(xml.References) {
+		return errors.New("signed info element does not match the references")
 	}
-	detachtedElement, err := rhtree.NSDetatch(elementNsContext, xml.cachedXml)
-	if err != nil {
-		return err
+	for i, reference := range xml.References {
+		if reference.Transforms == nil {
+			continue
+		}
+		transformsElement, err := getSingleChildElement(referenceElements[i], "Transforms", XmlDSigNamespaceUri)
+		if err != nil {
+			return err
+		}
+		err = reference.Transforms.bindXml(transformsElement)
+		if err != nil {
+			return err
+		}
 	}
+	return nil
+}
 
-	canonicalizedData, err := xml.CanonicalizationMethod.canonicalizer.Canonicalize(ctx, detachtedElement)
-	if err != nil {
-		return err
+func (xml *SignedInfo) computeDigests(ctx context.Context) error {
+	for _, reference := range xml.References {
+		err := reference.computeDigest(ctx)
+		if err != nil {
+			return err
+		}
 	}
+	return nil
+}
 
-	signatureMethod, err := GetSignatureMethod(xml.SignatureMethod.Algorithm)
+func (xml *SignedInfo) validateSignature(ctx context.Context, key crypto.PublicKey) error {
+	canonicalizedData, err := xml.canonicalize(ctx)
 	if err != nil {
 		return err
 	}
-	signatureAlgorithm, err := signatureMethod.GetSignatureAlgorithm()
+
+	signatureMethod, err := GetSignatureMethod(xml.SignatureMethod.Algorithm)
 	if err != nil {
 		return err
 	}
@@ -69,7 +171,10 @@ func (xml *SignedInfo) validateSignature(ctx context.Context, cert *x509.Certifi
 	if err != nil {
 		return err
 	}
-	err = cert.CheckSignature(signatureAlgorithm, canonicalizedData, signatureValue)
+	if signatureMethod.Verify == nil {
+		return errors.New("signature method does not support verification")
+	}
+	err = signatureMethod.Verify(ctx, key, canonicalizedData, signatureValue, xml.SignatureMethod)
 	if err != nil {
 		return err
 	}
@@ -77,6 +182,44 @@ func (xml *SignedInfo) validateSignature(ctx context.Context, cert *x509.Certifi
 	return nil
 }
 
+func (xml *SignedInfo) computeSignature(ctx context.Context, key crypto.PrivateKey) (string, error) {
+	canonicalizedData, err := xml.canonicalize(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	signatureMethod, err := GetSignatureMethod(xml.SignatureMethod.Algorithm)
+	if err != nil {
+		return "", err
+	}
+	if signatureMethod.Sign == nil {
+		return "", errors.New("signature method does not support signing")
+	}
+	signatureValue, err := signatureMethod.Sign(ctx, key, canonicalizedData, xml.SignatureMethod)
+	if err != nil {
+		return "", err
+	}
+
+	return base64.StdEncoding.EncodeToString(signatureValue), nil
+}
+
+func (xml *SignedInfo) canonicalize(ctx context.Context) ([]byte, error) {
+	if xml.cachedXml == nil {
+		return nil, errors.New("signed info element is nil")
+	}
+	if xml.CanonicalizationMethod == nil {
+		return nil, errors.New("signed info does not contain a CanonicalizationMethod element")
+	}
+	err := xml.CanonicalizationMethod.ensureCanonicalizer()
+	if err != nil {
+		return nil, err
+	}
+
+	// The element is canonicalized as a subset of its document, so the
+	// namespaces in scope of the signature are taken into account
+	return xml.CanonicalizationMethod.canonicalizer.Canonicalize(ctx, xml.cachedXml)
+}
+
 func (xml *SignedInfo) loadXml(el *etree.Element) error {
 	err := validateElement(el, "SignedInfo", XmlDSigNamespaceUri)
 	if err != nil {
@@ -106,7 +249,7 @@ func (xml *SignedInfo) loadXml(el *etree.Element) error {
 	}
 
 	// Get the references
-	referenceElements := el.SelectElements("Reference")
+	referenceElements := selectChildElements(el, "Reference", XmlDSigNamespaceUri)
 	for _, referenceElement := range referenceElements {
 		reference := newReference(xml)
 		err := reference.loadXml(referenceElement)
@@ -2,23 +2,47 @@ package xmldsig
 
 import (
 	"context"
+	"crypto"
 	"crypto/x509"
-	"encoding/base64"
 	"errors"
 
 	"github.com/beevik/etree"
+	"github.com/deb-ict/go-xmldsig/canonicalizer"
 )
 
 type SignedXml struct {
-	document   *etree.Document
-	signature  *Signature
-	nsUris     map[string]string
-	nsPrefixes map[string]string
+	document        *etree.Document
+	signature       *Signature
+	signatureParent *etree.Element
+	nsUris          map[string]string
+	nsPrefixes      map[string]string
+	resolvers       map[string]ResolveReferenceMethod
+}
+
+func NewSignedXml(doc *etree.Document) *SignedXml {
+	xml := &SignedXml{
+		document:   doc,
+		nsUris:     map[string]string{},
+		nsPrefixes: map[string]string{},
+	}
+	xml.SetNamespacePrefix("ds", XmlDSigNamespaceUri)
+	xml.SetNamespacePrefix("dsig11", XmlDSig11NamespaceUri)
+	xml.SetNamespacePrefix("pss", XmlDSigMoreNamespaceUri)
+	xml.SetNamespacePrefix("wsse", WsseNamespaceUri)
+
+	xml.SetSignature(NewSignature().WithSignedInfo(NewSignedInfo().
+		WithCanonicalizationMethod(NewCanonicalizationMethod(canonicalizer.C14N10ExcNamespaceUri)).
+		WithSignatureMethod(NewSignatureMethod(SignatureMethod_RSA_SHA256)),
+	))
+
+	return xml
 }
 
 func LoadSignedXml(doc *etree.Document) (*SignedXml, error) {
 	xml := &SignedXml{
-		document: doc,
+		document:   doc,
+		nsUris:     map[string]string{},
+		nsPrefixes: map[string]string{},
 	}
 	err := xml.loadXml(doc)
 	if err != nil {
@@ -28,87 +52,224 @@ func LoadSignedXml(doc *etree.Document) (*SignedXml, error) {
 	return xml, nil
 }
 
+func LoadSignedXmlSignature(doc *etree.Document, signatureElement *etree.Element) (*SignedXml, error) {
+	xml := &SignedXml{
+		document:   doc,
+		nsUris:     map[string]string{},
+		nsPrefixes: map[string]string{},
+	}
+	xml.signature = newSignature(xml)
+	err := xml.signature.loadXml(signatureElement)
+	if err != nil {
+		return nil, err
+	}
+
+	return xml, nil
+}
+
+func (xml *SignedXml) GetSignature() *Signature {
+	return xml.signature
+}
+
+func (xml *SignedXml) SetSignature(signature *Signature) {
+	if signature != nil {
+		signature.bind(xml)
+	}
+	xml.signature = signature
+}
+
+func (xml *SignedXml) SetCanonicalizationMethod(uri string) error {
+	canonicalizationMethod := NewCanonicalizationMethod(uri)
+	err := canonicalizationMethod.ensureCanonicalizer()
+	if err != nil {
+		return err
+	}
+	xml.signature.SignedInfo.WithCanonicalizationMethod(canonicalizationMethod)
+	return nil
+}
+
+func (xml *SignedXml) SetSignatureMethod(uri string) {
+	xml.signature.SignedInfo.WithSignatureMethod(NewSignatureMethod(uri))
+}
+
+func (xml *SignedXml) SetSignatureParent(el *etree.Element) {
+	xml.signatureParent = el
+}
+
+func (xml *SignedXml) AddReference(uri string, digestMethod string, transforms ...string) (*Reference, error) {
+	reference := NewReference(uri).WithDigest(digestMethod)
+	for _, algorithm := range transforms {
+		transform := NewTransform(algorithm)
+		err := transform.ensureTransform()
+		if err != nil {
+			return nil, err
+		}
+		reference.WithTransforms(transform)
+	}
+
+	xml.signature.SignedInfo.AddReference(reference)
+	return reference, nil
+}
+
+func (xml *SignedXml) ComputeSignature(ctx context.Context, signer crypto.Signer) error {
+	if signer == nil {
+		return ErrInvalidSigningKey
+	}
+	return xml.computeSignature(ctx, signer)
+}
+
+func (xml *SignedXml) ComputeHmacSignature(ctx context.Context, key []byte) error {
+	if len(key) == 0 {
+		return ErrInvalidSigningKey
+	}
+	return xml.computeSignature(ctx, key)
+}
+
 func (xml *SignedXml) ValidateSignature(ctx context.Context, cert *x509.Certificate) ([]*etree.Element, error) {
+	if cert == nil {
+		return nil, ErrInvalidVerificationKey
+	}
+	return xml.ValidateSignatureWithKeySelector(ctx, NewFixedCertificateSelector(cert))
+}
+
+func (xml *SignedXml) ValidateSignatureWithKey(ctx context.Context, key crypto.PublicKey) ([]*etree.Element, error) {
+	if key == nil {
+		return nil, ErrInvalidVerificationKey
+	}
+	return xml.ValidateSignatureWithKeySelector(ctx, NewFixedKeySelector(key))
+}
+
+func (xml *SignedXml) ValidateHmacSignature(ctx context.Context, key []byte) ([]*etree.Element, error) {
+	if len(key) == 0 {
+		return nil, ErrInvalidVerificationKey
+	}
+	return xml.ValidateSignatureWithKeySelector(ctx, NewFixedKeySelector(key))
+}
+
+func (xml *SignedXml) ValidateSignatureWithKeySelector(ctx context.Context, selector KeySelector) ([]*etree.Element, error) {
 	if xml.signature == nil || xml.signature.SignedInfo == nil {
 		return nil, errors.New("signature or signed info is nil")
 	}
-	validated, err := xml.signature.SignedInfo.validateDigests(ctx)
-	if err != nil {
-		return nil, err
+	if selector == nil {
+		return nil, errors.New("key selector is nil")
 	}
 
-	err = xml.signature.SignedInfo.validateSignature(ctx, cert)
+	key, err := selector.SelectKey(ctx, xml.signature.KeyInfo, xml.signature.SignedInfo.SignatureMethod)
 	if err != nil {
 		return nil, err
 	}
+	if key == nil {
+		return nil, ErrKeyNotFound
+	}
 
-	return validated, nil
+	return xml.validateSignature(ctx, key)
 }
 
-func (xml *SignedXml) GetCertificate() (*x509.Certificate, error) {
+func (xml *SignedXml) computeSignature(ctx context.Context, key crypto.PrivateKey) error {
 	if xml.signature == nil {
-		return nil, errors.New("signature or signed info is nil")
+		return errors.New("signature is nil")
+	}
+	xml.signature.bind(xml)
+	err := xml.signature.validate()
+	if err != nil {
+		return err
 	}
 
-	signatureXml := xml.signature.cachedXml
-	if signatureXml == nil {
-		return nil, errors.New("signature xml is nil")
+	parent := xml.signatureParent
+	if parent == nil {
+		parent = xml.document.Root()
+	}
+	if parent == nil {
+		return errors.New("document does not contain a root element")
 	}
 
-	keyInfoElements := signatureXml.SelectElements("KeyInfo")
-	if len(keyInfoElements) != 1 {
-		return nil, errors.New("signature does not contain a single KeyInfo element")
+	// Remove a previously computed signature
+	if xml.signature.cachedXml != nil && xml.signature.cachedXml.Parent() != nil {
+		xml.signature.cachedXml.Parent().RemoveChild(xml.signature.cachedXml)
+		xml.signature.cachedXml = nil
 	}
 
-	x509DataElements := keyInfoElements[0].SelectElements("X509Data")
-	if len(x509DataElements) > 0 {
-		x509CertificateElements := x509DataElements[0].SelectElements("X509Certificate")
-		if len(x509CertificateElements) != 1 {
-			return nil, errors.New("signature does not contain a single X509Certificate element")
-		}
+	// Insert a placeholder, so enveloped references resolve the signature location
+	// and the transforms resolve here() in the signature being created
+	placeholder, err := xml.createPlaceholderElement()
+	if err != nil {
+		return err
+	}
+	parent.AddChild(placeholder)
+	xml.signature.cachedXml = placeholder
 
-		x509Data, err := base64.StdEncoding.DecodeString(x509CertificateElements[0].Text())
-		if err != nil {
-			return nil, err
-		}
-		x509Cert, err := x509.ParseCertificate(x509Data)
-		if err != nil {
-			return nil, err
-		}
+	// Compute the reference digests
+	err = xml.signature.SignedInfo.bindTransforms(placeholder.SelectElement("SignedInfo"))
+	if err == nil {
+		err = xml.signature.SignedInfo.computeDigests(ctx)
+	}
+	if err != nil {
+		parent.RemoveChild(placeholder)
+		xml.signature.cachedXml = nil
+		return err
+	}
 
-		return x509Cert, nil
+	// Replace the placeholder with the signature element
+	xml.signature.SignatureValue = newSignatureValue(xml.signature)
+	signatureElement, err := xml.signature.getXml()
+	if err != nil {
+		parent.RemoveChild(placeholder)
+		xml.signature.cachedXml = nil
+		return err
+	}
+	index := placeholder.Index()
+	parent.RemoveChildAt(index)
+	parent.InsertChildAt(index, signatureElement)
+	xml.signature.cachedXml = signatureElement
+	xml.signature.SignedInfo.cachedXml = signatureElement.SelectElement("SignedInfo")
+	err = xml.signature.SignedInfo.bindTransforms(xml.signature.SignedInfo.cachedXml)
+	if err != nil {
+		parent.RemoveChild(signatureElement)
+		xml.signature.cachedXml = nil
+		return err
 	}
 
-	tokenReferenceElements := keyInfoElements[0].SelectElements("SecurityTokenReference")
-	if len(tokenReferenceElements) > 0 {
-		referenceElements := tokenReferenceElements[0].SelectElements("Reference")
-		if len(referenceElements) != 1 {
-			return nil, errors.New("signature does not contain a single Reference element")
-		}
+	// Sign the canonicalized signed info
+	signatureValue, err := xml.signature.SignedInfo.computeSignature(ctx, key)
+	if err != nil {
+		parent.RemoveChild(signatureElement)
+		xml.signature.cachedXml = nil
+		return err
+	}
+	xml.signature.SignatureValue.Value = signatureValue
+	signatureValueElement := signatureElement.SelectElement("SignatureValue")
+	signatureValueElement.SetText(signatureValue)
+	xml.signature.SignatureValue.cachedXml = signatureValueElement
 
-		uri := referenceElements[0].SelectAttrValue("URI", "")
-		if uri == "" {
-			return nil, errors.New("signature does not contain a URI")
-		}
+	return nil
+}
 
-		securityTokenElements := xml.document.FindElements("//BinarySecurityToken[@Id='" + uri[1:] + "']")
-		if len(securityTokenElements) != 1 {
-			return nil, errors.New("document does not contain a single BinarySecurityToken element")
-		}
+func (xml *SignedXml) validateSignature(ctx context.Context, key crypto.PublicKey) ([]*etree.Element, error) {
+	if xml.signature == nil || xml.signature.SignedInfo == nil {
+		return nil, errors.New("signature or signed info is nil")
+	}
+	validated, err := xml.signature.SignedInfo.validateDigests(ctx)
+	if err != nil {
+		return nil, err
+	}
 
-		x509Data, err := base64.StdEncoding.DecodeString(securityTokenElements[0].Text())
-		if err != nil {
-			return nil, err
-		}
-		x509Cert, err := x509.ParseCertificate(x509Data)
-		if err != nil {
-			return nil, err
-		}
+	err = xml.signature.SignedInfo.validateSignature(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+
+	return validated, nil
+}
 
-		return x509Cert, nil
+func (xml *SignedXml) GetCertificate() (*x509.Certificate, error) {
+	if xml.signature == nil {
+		return nil, errors.New("signature is nil")
+	}
+	if xml.signature.KeyInfo == nil {
+		return nil, errors.New("signature does not contain a KeyInfo element")
 	}
 
-	return nil, errors.New("certificate not found")
+	return xml.signature.KeyInfo.GetCertificate()
 }
 
 func (xml *SignedXml) SetNamespacePrefix(prefix string, uri string) {
@@ -116,12 +277,64 @@ func (xml *SignedXml) SetNamespacePrefix(prefix string, uri string) {
 	xml.nsUris[prefix] = uri
 }
 
+// SetReferenceResolver resolves the external references of this signature that
+// start with the prefix, it takes precedence over the registered resolvers.
+func (xml *SignedXml) SetReferenceResolver(prefix string, method ResolveReferenceMethod) {
+	if xml.resolvers == nil {
+		xml.resolvers = map[string]ResolveReferenceMethod{}
+	}
+	xml.resolvers[prefix] = method
+}
+
+func (xml *SignedXml) getReferenceResolver(uri string) (ResolveReferenceMethod, bool) {
+	if xml != nil {
+		if method, ok := findReferenceResolver(xml.resolvers, uri); ok {
+			return method, true
+		}
+	}
+	return findRegisteredReferenceResolver(uri)
+}
+
 func (xml *SignedXml) getElementSpace(uri string) string {
-	prefix, found := xml.nsPrefixes[uri]
-	if !found {
-		return uri
+	if xml == nil {
+		return ""
+	}
+	return xml.nsPrefixes[uri]
+}
+
+func (xml *SignedXml) createSignatureElement() *etree.Element {
+	return xml.createNamespacedElement("Signature", XmlDSigNamespaceUri)
+}
+
+// createPlaceholderElement returns the signature element that is in place while
+// the digests are computed, it only contains the transforms of the references.
+func (xml *SignedXml) createPlaceholderElement() (*etree.Element, error) {
+	placeholder := xml.createSignatureElement()
+	signedInfoElement := placeholder.CreateElement("SignedInfo")
+	signedInfoElement.Space = xml.getElementSpace(XmlDSigNamespaceUri)
+	for _, reference := range xml.signature.SignedInfo.References {
+		referenceElement := signedInfoElement.CreateElement("Reference")
+		referenceElement.Space = xml.getElementSpace(XmlDSigNamespaceUri)
+		if reference.Transforms != nil {
+			transformsElement, err := reference.Transforms.getXml()
+			if err != nil {
+				return nil, err
+			}
+			referenceElement.AddChild(transformsElement)
+		}
+	}
+	return placeholder, nil
+}
+
+func (xml *SignedXml) createNamespacedElement(tag string, uri string) *etree.Element {
+	el := etree.NewElement(tag)
+	el.Space = xml.getElementSpace(uri)
+	if el.Space == "" {
+		el.CreateAttr("xmlns", uri)
+	} else {
+		el.CreateAttr("xmlns:"+el.Space, uri)
 	}
-	return prefix
+	return el
 }
 
 func (xml *SignedXml) loadXml(doc *etree.Document) error {
@@ -0,0 +1,333 @@
+package xmldsig
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"hash"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/beevik/etree"
+	"github.com/deb-ict/go-xmldsig/canonicalizer"
+	"github.com/deb-ict/go-xmldsig/transform"
+)
+
+const testDocument = `<Invoice xmlns="urn:test"><Id>1</Id><Line Id="line1"><Amount>10.00</Amount></Line></Invoice>`
+
+func parseTestDocument(t *testing.T, s string) *etree.Document {
+	t.Helper()
+	doc := etree.NewDocument()
+	err := doc.ReadFromString(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return doc
+}
+
+func Test_SignedXml_RoundTrip(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ecdsaKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	hmacKey := []byte("0123456789abcdef0123456789abcdef")
+
+	tests := []struct {
+		name            string
+		signatureMethod string
+		sign            func(ctx context.Context, xml *SignedXml) error
+		validate        func(ctx context.Context, xml *SignedXml) ([]*etree.Element, error)
+	}{
+		{
+			name:            "RSA",
+			signatureMethod: SignatureMethod_RSA_SHA256,
+			sign: func(ctx context.Context, xml *SignedXml) error {
+				return xml.ComputeSignature(ctx, rsaKey)
+			},
+			validate: func(ctx context.Context, xml *SignedXml) ([]*etree.Element, error) {
+				return xml.ValidateSignatureWithKey(ctx, rsaKey.Public())
+			},
+		},
+		{
+			name:            "ECDSA",
+			signatureMethod: SignatureMethod_ECDSA_SHA256,
+			sign: func(ctx context.Context, xml *SignedXml) error {
+				return xml.ComputeSignature(ctx, ecdsaKey)
+			},
+			validate: func(ctx context.Context, xml *SignedXml) ([]*etree.Element, error) {
+				return xml.ValidateSignatureWithKey(ctx, ecdsaKey.Public())
+			},
+		},
+		{
+			name:            "HMAC",
+			signatureMethod: SignatureMethod_HMAC_SHA256,
+			sign: func(ctx context.Context, xml *SignedXml) error {
+				return xml.ComputeHmacSignature(ctx, hmacKey)
+			},
+			validate: func(ctx context.Context, xml *SignedXml) ([]*etree.Element, error) {
+				return xml.ValidateHmacSignature(ctx, hmacKey)
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			doc := parseTestDocument(t, testDocument)
+			signedXml := NewSignedXml(doc)
+			signedXml.SetSignatureMethod(tt.signatureMethod)
+			_, err := signedXml.AddReference("", DigestMethod_SHA256, transform.EnvelopedSignatureTransform, canonicalizer.C14N10ExcNamespaceUri)
+			if err != nil {
+				t.Fatal(err)
+			}
+			_, err = signedXml.AddReference("#line1", DigestMethod_SHA256, canonicalizer.C14N10ExcNamespaceUri)
+			if err != nil {
+				t.Fatal(err)
+			}
+			err = tt.sign(ctx, signedXml)
+			if err != nil {
+				t.Fatalf("sign: %v", err)
+			}
+			signed, err := doc.WriteToString()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			// The whole document reference keeps its empty URI attribute
+			loaded := parseTestDocument(t, signed)
+			references := loaded.FindElements("//Reference")
+			if len(references) != 2 {
+				t.Fatalf("expected 2 references, got %d", len(references))
+			}
+			if attr := references[0].SelectAttr("URI"); attr == nil || attr.Value != "" {
+				t.Errorf("expected URI=\"\" on the whole document reference, got %v", attr)
+			}
+			if attr := references[1].SelectAttr("URI"); attr == nil || attr.Value != "#line1" {
+				t.Errorf("expected URI=\"#line1\", got %v", attr)
+			}
+			if references[0].SelectAttr("Id") != nil || references[0].SelectAttr("Type") != nil {
+				t.Errorf("unexpected reference attributes: %v", references[0].Attr)
+			}
+
+			loadedXml, err := LoadSignedXml(loaded)
+			if err != nil {
+				t.Fatal(err)
+			}
+			validated, err := tt.validate(ctx, loadedXml)
+			if err != nil {
+				t.Fatalf("validate: %v", err)
+			}
+			if len(validated) != 2 {
+				t.Errorf("expected 2 validated references, got %d", len(validated))
+			}
+
+			// A modified document fails to validate
+			tampered := parseTestDocument(t, strings.Replace(signed, "10.00", "11.00", 1))
+			tamperedXml, err := LoadSignedXml(tampered)
+			if err != nil {
+				t.Fatal(err)
+			}
+			_, err = tt.validate(ctx, tamperedXml)
+			if err == nil {
+				t.Error("expected a modified document to fail validation")
+			}
+		})
+	}
+}
+
+func Test_SignedXml_ValidateSignatureWithWrongKey(t *testing.T) {
+	ctx := context.Background()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	otherKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	doc := parseTestDocument(t, testDocument)
+	signedXml := NewSignedXml(doc)
+	_, err = signedXml.AddReference("", DigestMethod_SHA256, transform.EnvelopedSignatureTransform)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = signedXml.ComputeSignature(ctx, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = signedXml.ValidateSignatureWithKey(ctx, otherKey.Public())
+	if err == nil {
+		t.Error("expected validation with another key to fail")
+	}
+	_, err = signedXml.ValidateSignatureWithKey(ctx, crypto.PublicKey(nil))
+	if err != ErrInvalidVerificationKey {
+		t.Errorf("expected ErrInvalidVerificationKey, got %v", err)
+	}
+}
+
+func Test_Reference_Uri(t *testing.T) {
+	tests := []struct {
+		name     string
+		xml      string
+		expected *string
+	}{
+		{
+			name:     "Empty",
+			xml:      `<ds:Reference xmlns:ds="http://www.w3.org/2000/09/xmldsig#" URI=""><ds:DigestMethod Algorithm="http://www.w3.org/2001/04/xmlenc#sha256"/><ds:DigestValue>AA==</ds:DigestValue></ds:Reference>`,
+			expected: new(string),
+		},
+		{
+			name:     "Absent",
+			xml:      `<ds:Reference xmlns:ds="http://www.w3.org/2000/09/xmldsig#"><ds:DigestMethod Algorithm="http://www.w3.org/2001/04/xmlenc#sha256"/><ds:DigestValue>AA==</ds:DigestValue></ds:Reference>`,
+			expected: nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc := parseTestDocument(t, tt.xml)
+			reference := newReference(nil)
+			err := reference.loadXml(doc.Root())
+			if err != nil {
+				t.Fatal(err)
+			}
+			el, err := reference.getXml()
+			if err != nil {
+				t.Fatal(err)
+			}
+			attr := el.SelectAttr("URI")
+			if tt.expected == nil && attr != nil {
+				t.Errorf("expected no URI attribute, got %q", attr.Value)
+			}
+			if tt.expected != nil && (attr == nil || attr.Value != *tt.expected) {
+				t.Errorf("expected URI=%q, got %v", *tt.expected, attr)
+			}
+		})
+	}
+}
+
+func Test_SignedXml_XPathHere(t *testing.T) {
+	ctx := context.Background()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	doc := parseTestDocument(t, testDocument)
+	signedXml := NewSignedXml(doc)
+	xpathTransform := NewTransform(transform.XPathTransform).WithXPath(
+		"count(ancestor-or-self::dsig:Signature | here()/ancestor::dsig:Signature[1]) > count(ancestor-or-self::dsig:Signature)",
+		map[string]string{"dsig": XmlDSigNamespaceUri},
+	)
+	signedXml.GetSignature().SignedInfo.AddReference(NewReference("").WithDigest(DigestMethod_SHA256).WithTransforms(xpathTransform))
+
+	// A failing transform does not leave the placeholder in the document
+	failing := NewSignedXml(parseTestDocument(t, testDocument))
+	failing.GetSignature().SignedInfo.AddReference(NewReference("#missing").WithDigest(DigestMethod_SHA256).WithTransforms(NewTransform(transform.XPathTransform).WithXPath("true()", nil)))
+	err = failing.ComputeSignature(ctx, key)
+	if err == nil {
+		t.Fatal("expected signing a missing element to fail")
+	}
+	if len(failing.document.Root().ChildElements()) != 2 {
+		t.Errorf("expected the document to be unchanged, got %d children", len(failing.document.Root().ChildElements()))
+	}
+
+	err = signedXml.ComputeSignature(ctx, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signatureElements := doc.FindElements("//Signature")
+	if len(signatureElements) != 1 || len(signatureElements[0].ChildElements()) != 2 {
+		t.Fatal("expected a single signature with a SignedInfo and SignatureValue")
+	}
+
+	// The signature validates on the signed instance and when loaded again
+	_, err = signedXml.ValidateSignatureWithKey(ctx, key.Public())
+	if err != nil {
+		t.Errorf("validate signed: %v", err)
+	}
+	signed, err := doc.WriteToString()
+	if err != nil {
+		t.Fatal(err)
+	}
+	loadedXml, err := LoadSignedXml(parseTestDocument(t, signed))
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = loadedXml.ValidateSignatureWithKey(ctx, key.Public())
+	if err != nil {
+		t.Errorf("validate loaded: %v", err)
+	}
+}
+
+// trackingReader records the writer it was copied to
+type trackingReader struct {
+	r         io.Reader
+	writtenTo io.Writer
+}
+
+func (tr *trackingReader) Read(p []byte) (int, error) {
+	return tr.r.Read(p)
+}
+
+func (tr *trackingReader) WriteTo(w io.Writer) (int64, error) {
+	tr.writtenTo = w
+	return io.Copy(w, tr.r)
+}
+
+func Test_SignedXml_ExternalReferenceStreams(t *testing.T) {
+	ctx := context.Background()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resolve := func(readers *[]*trackingReader) ResolveReferenceMethod {
+		return func(ctx context.Context, reference *Reference) (io.Reader, error) {
+			reader := &trackingReader{r: strings.NewReader("external content")}
+			*readers = append(*readers, reader)
+			return reader, nil
+		}
+	}
+
+	var signed []*trackingReader
+	doc := parseTestDocument(t, testDocument)
+	signedXml := NewSignedXml(doc)
+	signedXml.SetReferenceResolver("https:", resolve(&signed))
+	_, err = signedXml.AddReference("https://example.com/content", DigestMethod_SHA256)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = signedXml.ComputeSignature(ctx, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Without transforms the content is copied to the digest without buffering
+	if len(signed) != 1 {
+		t.Fatalf("expected the reference to be resolved once, got %d", len(signed))
+	}
+	if _, ok := signed[0].writtenTo.(hash.Hash); !ok {
+		t.Errorf("expected the content to be copied to the digest, got %T", signed[0].writtenTo)
+	}
+
+	var validated []*trackingReader
+	signedXml.SetReferenceResolver("https:", resolve(&validated))
+	_, err = signedXml.ValidateSignatureWithKey(ctx, key.Public())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(validated) != 1 {
+		t.Fatalf("expected the reference to be resolved once, got %d", len(validated))
+	}
+	if _, ok := validated[0].writtenTo.(hash.Hash); !ok {
+		t.Errorf("expected the content to be copied to the digest, got %T", validated[0].writtenTo)
+	}
+}
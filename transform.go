@@ -2,17 +2,19 @@ package xmldsig
 
 import (
 	"context"
+	"sort"
 
 	"github.com/beevik/etree"
 	"github.com/deb-ict/go-xmldsig/transform"
 )
 
 type Transform struct {
-	Algorithm  string
-	XPath      string
-	transforms *Transforms
-	cachedXml  *etree.Element
-	Transform  transform.Transform
+	Algorithm       string
+	XPath           string
+	XPathNamespaces map[string]string
+	transforms      *Transforms
+	cachedXml       *etree.Element
+	Transform       transform.Transform
 }
 
 func newTransform(transforms *Transforms) *Transform {
@@ -21,23 +23,70 @@ func newTransform(transforms *Transforms) *Transform {
 	}
 }
 
-func (xml *Transform) transformXmlElement(ctx context.Context, el *etree.Element) ([]byte, error) {
+func NewTransform(algorithm string) *Transform {
+	transform := newTransform(nil)
+	transform.Algorithm = algorithm
+	return transform
+}
+
+func NewTransformFrom(t transform.Transform) *Transform {
+	transform := newTransform(nil)
+	transform.Algorithm = t.GetAlgorithm()
+	transform.Transform = t
+	return transform
+}
+
+func (xml *Transform) WithXPath(expression string, namespaces map[string]string) *Transform {
+	xml.XPath = expression
+	xml.XPathNamespaces = namespaces
+	return xml
+}
+
+func (xml *Transform) transform(ctx context.Context, data *transform.Data) (*transform.Data, error) {
 	err := xml.ensureTransform()
 	if err != nil {
 		return nil, err
 	}
-	return xml.Transform.TransformXmlElement(ctx, el)
+
+	// A transform that is not bound to an element reads its parameters from the
+	// element it is written as, detached from the document
+	if xml.cachedXml == nil {
+		el, err := xml.getXml()
+		if err != nil {
+			return nil, err
+		}
+		if el.Space == "" {
+			el.CreateAttr("xmlns", XmlDSigNamespaceUri)
+		} else {
+			el.CreateAttr("xmlns:"+el.Space, XmlDSigNamespaceUri)
+		}
+		err = xml.Transform.ReadXml(el)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return xml.Transform.Transform(ctx, data)
 }
 
-func (xml *Transform) transformData(ctx context.Context, data []byte) ([]byte, error) {
+// bindXml reads the parameters of the transform from its element in the
+// signature, so here() and the namespaces in scope resolve in the signature.
+func (xml *Transform) bindXml(el *etree.Element) error {
 	err := xml.ensureTransform()
 	if err != nil {
-		return nil, err
+		return err
+	}
+	err = xml.Transform.ReadXml(el)
+	if err != nil {
+		return err
 	}
-	return xml.Transform.TransformData(ctx, data)
+	xml.cachedXml = el
+	return nil
 }
 
 func (xml *Transform) root() *SignedXml {
+	if xml.transforms == nil {
+		return nil
+	}
 	return xml.transforms.root()
 }
 
@@ -55,6 +104,14 @@ func (xml *Transform) loadXml(el *etree.Element) error {
 	}
 	if xpathElement != nil {
 		xml.XPath = xpathElement.Text()
+		for _, attr := range xpathElement.Attr {
+			if attr.Space == "xmlns" {
+				if xml.XPathNamespaces == nil {
+					xml.XPathNamespaces = map[string]string{}
+				}
+				xml.XPathNamespaces[attr.Key] = attr.Value
+			}
+		}
 	}
 
 	err = xml.ensureTransform()
@@ -79,6 +136,14 @@ func (xml *Transform) getXml() (*etree.Element, error) {
 	if xml.XPath != "" {
 		xpathEl := el.CreateElement("XPath")
 		xpathEl.Space = xml.root().getElementSpace(XmlDSigNamespaceUri)
+		prefixes := make([]string, 0, len(xml.XPathNamespaces))
+		for prefix := range xml.XPathNamespaces {
+			prefixes = append(prefixes, prefix)
+		}
+		sort.Strings(prefixes)
+		for _, prefix := range prefixes {
+			xpathEl.CreateAttr("xmlns:"+prefix, xml.XPathNamespaces[prefix])
+		}
 		xpathEl.SetText(xml.XPath)
 	}
 
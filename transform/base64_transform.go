@@ -0,0 +1,76 @@
+package transform
+
+import (
+	"context"
+	"encoding/base64"
+	"strings"
+	"unicode"
+
+	"github.com/beevik/etree"
+	"github.com/deb-ict/go-xmldsig/canonicalizer"
+)
+
+type base64Transform struct {
+}
+
+func NewBase64Transform() Transform {
+	return &base64Transform{}
+}
+
+func (t *base64Transform) GetAlgorithm() string {
+	return Base64Transform
+}
+
+func (t *base64Transform) Transform(ctx context.Context, data *Data) (*Data, error) {
+	var encoded string
+	if data.IsNodeSet() {
+		// A node-set is reduced to the string value of its text nodes
+		nodeSet, err := data.NodeSet(ctx)
+		if err != nil {
+			return nil, err
+		}
+		var sb strings.Builder
+		t.writeText(&sb, nodeSet, nodeSet.Root())
+		encoded = sb.String()
+	} else {
+		octets, err := data.Octets(ctx)
+		if err != nil {
+			return nil, err
+		}
+		encoded = string(octets)
+	}
+
+	// Whitespace is not part of the encoded data
+	encoded = strings.Map(func(r rune) rune {
+		if unicode.IsSpace(r) {
+			return -1
+		}
+		return r
+	}, encoded)
+	decoded, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, err
+	}
+	return NewOctetData(decoded), nil
+}
+
+func (t *base64Transform) ReadXml(el *etree.Element) error {
+	return nil
+}
+
+func (t *base64Transform) WriteXml(el *etree.Element) error {
+	return nil
+}
+
+func (t *base64Transform) writeText(sb *strings.Builder, nodeSet *canonicalizer.NodeSet, el *etree.Element) {
+	for _, child := range el.Child {
+		switch token := child.(type) {
+		case *etree.CharData:
+			if nodeSet.ContainsToken(token) {
+				sb.WriteString(token.Data)
+			}
+		case *etree.Element:
+			t.writeText(sb, nodeSet, token)
+		}
+	}
+}
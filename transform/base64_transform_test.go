@@ -0,0 +1,82 @@
+package transform
+
+import (
+	"context"
+	"testing"
+
+	"github.com/beevik/etree"
+	"github.com/deb-ict/go-xmldsig/canonicalizer"
+)
+
+func Test_Base64Transform_Octets(t *testing.T) {
+	tests := []struct {
+		name     string
+		encoded  string
+		expected string
+	}{
+		{"Encoded", "aGVsbG8=", "hello"},
+		{"Whitespace", " aGVs\r\n\tbG8=\n", "hello"},
+		{"Empty", "", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			actual := transformToString(t, context.Background(), NewBase64Transform(), NewOctetData([]byte(tt.encoded)))
+			if actual != tt.expected {
+				t.Errorf("expected %q, got %q", tt.expected, actual)
+			}
+		})
+	}
+}
+
+func Test_Base64Transform_NodeSet(t *testing.T) {
+	tests := []struct {
+		name     string
+		document string
+		expected string
+	}{
+		{"Text", `<doc>aGVsbG8=</doc>`, "hello"},
+		{"Descendants", `<doc>aGVs<!--AAAA--><p>bG8</p><?pi AAAA?>=</doc>`, "hello"},
+		{"CData", `<doc><![CDATA[aGVs]]>bG8=</doc>`, "hello"},
+		{"Attributes", `<doc a="AAAA">aGVsbG8=</doc>`, "hello"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc := parseTestDocument(t, tt.document)
+			actual := transformToString(t, context.Background(), NewBase64Transform(), NewNodeSetData(canonicalizer.NewNodeSet(doc.Root())))
+			if actual != tt.expected {
+				t.Errorf("expected %q, got %q", tt.expected, actual)
+			}
+		})
+	}
+
+	// Text nodes that are not in the node-set are not decoded
+	doc := parseTestDocument(t, `<doc>aGVs<skip>AAAA</skip>bG8=</doc>`)
+	skip := doc.FindElement("//skip")
+	nodeSet := canonicalizer.NewNodeSet(doc.Root()).Filter(func(token etree.Token) bool {
+		return !canonicalizer.IsDescendantOrSelf(token, skip)
+	})
+	actual := transformToString(t, context.Background(), NewBase64Transform(), NewNodeSetData(nodeSet))
+	if actual != "hello" {
+		t.Errorf("expected %q, got %q", "hello", actual)
+	}
+}
+
+func Test_Base64Transform_Invalid(t *testing.T) {
+	doc := parseTestDocument(t, `<doc>aGVs <b>bG8=</b>!</doc>`)
+	tests := []struct {
+		name string
+		data *Data
+	}{
+		{"InvalidCharacter", NewOctetData([]byte("aGVs*bG8="))},
+		{"Truncated", NewOctetData([]byte("aGVsbG8"))},
+		{"NodeSet", NewNodeSetData(canonicalizer.NewNodeSet(doc.Root()))},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewBase64Transform().Transform(context.Background(), tt.data)
+			if err == nil {
+				t.Error("expected invalid base64 to be rejected")
+			}
+		})
+	}
+}
@@ -2,11 +2,9 @@ package transform
 
 import (
 	"context"
-	"errors"
 
 	"github.com/beevik/etree"
 	"github.com/deb-ict/go-xmldsig/canonicalizer"
-	rhtree "github.com/russellhaering/goxmldsig/etreeutils"
 )
 
 type c14N10ExcTransform struct {
@@ -25,31 +23,30 @@ func NewC14N10ExcWithCommentsTransform() Transform {
 	}
 }
 
-func (t *c14N10ExcTransform) GetAlgorithm() string {
-	return t.canonicalizer.GetAlgorithm()
+// NewC14N10ExcTransformWithPrefixList returns an exclusive canonicalization
+// transform with the given InclusiveNamespaces prefixes.
+func NewC14N10ExcTransformWithPrefixList(prefixes ...string) Transform {
+	return &c14N10ExcTransform{
+		canonicalizer: canonicalizer.NewC14N10ExcCanonicalizerWithPrefixList(prefixes...),
+	}
 }
 
-func (t *c14N10ExcTransform) TransformXmlElement(ctx context.Context, el *etree.Element) ([]byte, error) {
-	elementNsContext, err := rhtree.NSBuildParentContext(el)
-	if err != nil {
-		return nil, err
-	}
-	detachtedElement, err := rhtree.NSDetatch(elementNsContext, el)
-	if err != nil {
-		return nil, err
+func NewC14N10ExcWithCommentsTransformWithPrefixList(prefixes ...string) Transform {
+	return &c14N10ExcTransform{
+		canonicalizer: canonicalizer.NewC14N10ExcWithCommentsCanonicalizerWithPrefixList(prefixes...),
 	}
+}
 
-	return t.canonicalizer.Canonicalize(ctx, detachtedElement)
+func (t *c14N10ExcTransform) GetAlgorithm() string {
+	return t.canonicalizer.GetAlgorithm()
 }
 
-func (t *c14N10ExcTransform) TransformData(ctx context.Context, data []byte) ([]byte, error) {
-	return nil, errors.New("exclusive c14n transform cannot be applied to data")
+func (t *c14N10ExcTransform) Transform(ctx context.Context, data *Data) (*Data, error) {
+	return canonicalizeData(ctx, t.canonicalizer, data)
 }
 
 func (t *c14N10ExcTransform) ReadXml(el *etree.Element) error {
-	t.canonicalizer.ReadXml(el)
-
-	return nil
+	return t.canonicalizer.ReadXml(el)
 }
 
 func (t *c14N10ExcTransform) WriteXml(el *etree.Element) error {
@@ -2,11 +2,9 @@ package transform
 
 import (
 	"context"
-	"errors"
 
 	"github.com/beevik/etree"
 	"github.com/deb-ict/go-xmldsig/canonicalizer"
-	rhtree "github.com/russellhaering/goxmldsig/etreeutils"
 )
 
 type c14N11Transform struct {
@@ -29,21 +27,8 @@ func (t *c14N11Transform) GetAlgorithm() string {
 	return t.canonicalizer.GetAlgorithm()
 }
 
-func (t *c14N11Transform) TransformXmlElement(ctx context.Context, el *etree.Element) ([]byte, error) {
-	elementNsContext, err := rhtree.NSBuildParentContext(el)
-	if err != nil {
-		return nil, err
-	}
-	detachtedElement, err := rhtree.NSDetatch(elementNsContext, el)
-	if err != nil {
-		return nil, err
-	}
-
-	return t.canonicalizer.Canonicalize(ctx, detachtedElement)
-}
-
-func (t *c14N11Transform) TransformData(ctx context.Context, data []byte) ([]byte, error) {
-	return nil, errors.New("exclusive c14n transform cannot be applied to data")
+func (t *c14N11Transform) Transform(ctx context.Context, data *Data) (*Data, error) {
+	return canonicalizeData(ctx, t.canonicalizer, data)
 }
 
 func (t *c14N11Transform) ReadXml(el *etree.Element) error {
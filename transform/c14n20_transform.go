@@ -0,0 +1,40 @@
+package transform
+
+import (
+	"context"
+
+	"github.com/beevik/etree"
+	"github.com/deb-ict/go-xmldsig/canonicalizer"
+)
+
+type c14N20Transform struct {
+	canonicalizer canonicalizer.Canonicalizer
+}
+
+func NewC14N20Transform() Transform {
+	return &c14N20Transform{
+		canonicalizer: canonicalizer.NewC14N20Canonicalizer(),
+	}
+}
+
+func NewC14N20TransformWithParameters(parameters *canonicalizer.C14N20Parameters) Transform {
+	return &c14N20Transform{
+		canonicalizer: canonicalizer.NewC14N20CanonicalizerWithParameters(parameters),
+	}
+}
+
+func (t *c14N20Transform) GetAlgorithm() string {
+	return t.canonicalizer.GetAlgorithm()
+}
+
+func (t *c14N20Transform) Transform(ctx context.Context, data *Data) (*Data, error) {
+	return canonicalizeData(ctx, t.canonicalizer, data)
+}
+
+func (t *c14N20Transform) ReadXml(el *etree.Element) error {
+	return t.canonicalizer.ReadXml(el)
+}
+
+func (t *c14N20Transform) WriteXml(el *etree.Element) error {
+	return t.canonicalizer.WriteXml(el)
+}
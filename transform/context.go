@@ -0,0 +1,46 @@
+package transform
+
+import (
+	"context"
+
+	"github.com/beevik/etree"
+)
+
+type signatureElementContextKey struct{}
+
+// WithSignatureElement returns a context that carries the Signature element
+// containing the Reference whose transforms are being applied.
+func WithSignatureElement(ctx context.Context, el *etree.Element) context.Context {
+	return context.WithValue(ctx, signatureElementContextKey{}, el)
+}
+
+func GetSignatureElement(ctx context.Context) *etree.Element {
+	el, _ := ctx.Value(signatureElementContextKey{}).(*etree.Element)
+	return el
+}
+
+type xsltProcessorContextKey struct{}
+
+// WithXsltProcessor returns a context that enables the XSLT transform using the
+// given processor. The XSLT transform is disabled unless a processor is set.
+func WithXsltProcessor(ctx context.Context, processor XsltProcessor) context.Context {
+	return context.WithValue(ctx, xsltProcessorContextKey{}, processor)
+}
+
+func GetXsltProcessor(ctx context.Context) XsltProcessor {
+	processor, _ := ctx.Value(xsltProcessorContextKey{}).(XsltProcessor)
+	return processor
+}
+
+type decryptionKeyResolverContextKey struct{}
+
+// WithDecryptionKeyResolver returns a context that provides the keys for the
+// decryption transform.
+func WithDecryptionKeyResolver(ctx context.Context, resolver DecryptionKeyResolver) context.Context {
+	return context.WithValue(ctx, decryptionKeyResolverContextKey{}, resolver)
+}
+
+func GetDecryptionKeyResolver(ctx context.Context) DecryptionKeyResolver {
+	resolver, _ := ctx.Value(decryptionKeyResolverContextKey{}).(DecryptionKeyResolver)
+	return resolver
+}
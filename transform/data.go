@@ -0,0 +1,194 @@
+package transform
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/textproto"
+
+	"github.com/beevik/etree"
+	"github.com/deb-ict/go-xmldsig/canonicalizer"
+)
+
+var (
+	errOctetStreamRead = errors.New("octet stream has already been read")
+)
+
+// Data is the input and output of a transform, either a node-set or an octet stream.
+// An octet stream can be backed by a reader or a pending canonicalization, it is
+// only buffered when a transform needs the octets.
+type Data struct {
+	nodeSet *canonicalizer.NodeSet
+	octets  []byte
+	reader  io.Reader
+	writer  func(ctx context.Context, w io.Writer) error
+	header  textproto.MIMEHeader
+}
+
+func NewNodeSetData(nodeSet *canonicalizer.NodeSet) *Data {
+	return &Data{
+		nodeSet: nodeSet,
+	}
+}
+
+func NewOctetData(octets []byte) *Data {
+	return &Data{
+		octets: octets,
+	}
+}
+
+// NewOctetStreamData returns an octet stream that is read once, when the data
+// is digested or transformed.
+func NewOctetStreamData(r io.Reader) *Data {
+	return &Data{
+		reader: r,
+	}
+}
+
+// NewAttachmentData returns the octet stream of a MIME part with its headers.
+func NewAttachmentData(header textproto.MIMEHeader, octets []byte) *Data {
+	return &Data{
+		octets: octets,
+		header: header,
+	}
+}
+
+// NewAttachmentStreamData returns the octet stream of a MIME part with its
+// headers, the content is read once.
+func NewAttachmentStreamData(header textproto.MIMEHeader, r io.Reader) *Data {
+	return &Data{
+		reader: r,
+		header: header,
+	}
+}
+
+// newCanonicalizedData returns the octet stream of a node-set canonicalization,
+// which is only performed when the octets are needed.
+func newCanonicalizedData(can canonicalizer.Canonicalizer, nodeSet *canonicalizer.NodeSet) *Data {
+	return newWriterData(func(ctx context.Context, w io.Writer) error {
+		return canonicalizeNodeSetTo(ctx, can, nodeSet, w)
+	})
+}
+
+// newWriterData returns an octet stream that is written by the function when the
+// data is digested or transformed.
+func newWriterData(writer func(ctx context.Context, w io.Writer) error) *Data {
+	return &Data{
+		writer: writer,
+	}
+}
+
+func (d *Data) Header() textproto.MIMEHeader {
+	return d.header
+}
+
+func (d *Data) IsNodeSet() bool {
+	return d.nodeSet != nil
+}
+
+// NodeSet returns the node-set, an octet stream is parsed as an XML document
+// and converted to the node-set of the document, including the comments and
+// processing instructions outside the document element.
+func (d *Data) NodeSet(ctx context.Context) (*canonicalizer.NodeSet, error) {
+	if d.nodeSet != nil {
+		return d.nodeSet, nil
+	}
+
+	octets, err := d.Octets(ctx)
+	if err != nil {
+		return nil, err
+	}
+	doc, err := parseXmlOctets(octets)
+	if err != nil {
+		return nil, err
+	}
+	return canonicalizer.NewNodeSet(&doc.Element), nil
+}
+
+// Octets returns the octet stream, a node-set is converted using C14N 1.0 as
+// required by the specification.
+func (d *Data) Octets(ctx context.Context) ([]byte, error) {
+	if d.nodeSet == nil && d.reader == nil && d.writer == nil {
+		return d.octets, nil
+	}
+
+	var buffer bytes.Buffer
+	err := d.WriteOctets(ctx, &buffer)
+	if err != nil {
+		return nil, err
+	}
+	if d.nodeSet == nil {
+		d.octets = buffer.Bytes()
+		d.reader = nil
+		d.writer = nil
+	}
+	return buffer.Bytes(), nil
+}
+
+// WriteOctets writes the octet stream without buffering it, a node-set is
+// converted using C14N 1.0. A reader backed octet stream can only be written
+// once, unless its octets were requested before.
+func (d *Data) WriteOctets(ctx context.Context, w io.Writer) error {
+	switch {
+	case d.nodeSet != nil:
+		return canonicalizeNodeSetTo(ctx, canonicalizer.NewC14N10RecCanonicalizer(), d.nodeSet, w)
+	case d.reader != nil:
+		_, err := io.Copy(w, d.takeReader())
+		return err
+	case d.writer != nil:
+		return d.writer(ctx, w)
+	}
+	_, err := w.Write(d.octets)
+	return err
+}
+
+// openReader returns a reader over the octet stream, a reader backed octet stream
+// is handed over and can not be read again.
+func (d *Data) openReader(ctx context.Context) (io.Reader, error) {
+	if d.nodeSet == nil && d.reader != nil {
+		return d.takeReader(), nil
+	}
+	octets, err := d.Octets(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return bytes.NewReader(octets), nil
+}
+
+func (d *Data) takeReader() io.Reader {
+	r := d.reader
+	d.reader = nil
+	d.writer = func(ctx context.Context, w io.Writer) error {
+		return errOctetStreamRead
+	}
+	return r
+}
+
+// parseXmlOctets parses an octet stream as an XML document. Document type
+// declarations are rejected, so no entities other than the predefined ones are
+// expanded.
+func parseXmlOctets(octets []byte) (*etree.Document, error) {
+	return parseXmlReader(bytes.NewReader(octets))
+}
+
+func parseXmlReader(r io.Reader) (*etree.Document, error) {
+	doc := etree.NewDocument()
+	_, err := doc.ReadFrom(r)
+	if err != nil {
+		return nil, err
+	}
+	for _, token := range doc.Child {
+		if _, ok := token.(*etree.Directive); ok {
+			return nil, errors.New("octet stream contains a document type declaration")
+		}
+	}
+	if doc.Root() == nil {
+		return nil, errors.New("octet stream does not contain an XML document")
+	}
+	return doc, nil
+}
+
+func isDocumentNode(el *etree.Element) bool {
+	return el != nil && el.Parent() == nil && el.Tag == "" && el.Space == ""
+}
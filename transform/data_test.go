@@ -0,0 +1,40 @@
+package transform
+
+import (
+	"context"
+	"testing"
+)
+
+func Test_Data_OctetsToNodeSet(t *testing.T) {
+	// The comments and processing instructions outside the document element are
+	// part of the node-set of an octet stream
+	tests := []struct {
+		name      string
+		transform Transform
+		expected  string
+	}{
+		{"WithComments", NewC14N10RecWithCommentsTransform(), "<?pi x?>\n<a></a>\n<!--c-->"},
+		{"WithoutComments", NewC14N10RecTransform(), "<?pi x?>\n<a></a>"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			actual := transformToString(t, context.Background(), tt.transform, NewOctetData([]byte("<?pi x?>\n<a/>\n<!--c-->")))
+			if actual != tt.expected {
+				t.Errorf("expected %q, got %q", tt.expected, actual)
+			}
+		})
+	}
+
+	// Transforms that work on the element subtree use the document element
+	nodeSet, err := NewOctetData([]byte("<?pi x?>\n<a><b/></a>\n<!--c-->")).NodeSet(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	el, err := nodeSet.Element()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if el.Tag != "a" || len(el.ChildElements()) != 1 {
+		t.Errorf("expected a copy of the document element, got %s", el.Tag)
+	}
+}
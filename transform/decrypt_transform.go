@@ -0,0 +1,324 @@
+package transform
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/des"
+	"errors"
+	"sort"
+	"strings"
+
+	"github.com/beevik/etree"
+	"github.com/deb-ict/go-xmldsig/canonicalizer"
+	"github.com/deb-ict/go-xmldsig/internal/xpath"
+)
+
+const (
+	DecryptNamespaceUri string = "http://www.w3.org/2002/07/decrypt#"
+
+	BlockEncryption_TripleDES_CBC string = "http://www.w3.org/2001/04/xmlenc#tripledes-cbc"
+	BlockEncryption_AES128_CBC    string = "http://www.w3.org/2001/04/xmlenc#aes128-cbc"
+	BlockEncryption_AES192_CBC    string = "http://www.w3.org/2001/04/xmlenc#aes192-cbc"
+	BlockEncryption_AES256_CBC    string = "http://www.w3.org/2001/04/xmlenc#aes256-cbc"
+	BlockEncryption_AES128_GCM    string = "http://www.w3.org/2009/xmlenc11#aes128-gcm"
+	BlockEncryption_AES192_GCM    string = "http://www.w3.org/2009/xmlenc11#aes192-gcm"
+	BlockEncryption_AES256_GCM    string = "http://www.w3.org/2009/xmlenc11#aes256-gcm"
+
+	EncryptionType_Element string = "http://www.w3.org/2001/04/xmlenc#Element"
+	EncryptionType_Content string = "http://www.w3.org/2001/04/xmlenc#Content"
+)
+
+var (
+	blockEncryptionKeySizes map[string]int = map[string]int{
+		BlockEncryption_TripleDES_CBC: 24,
+		BlockEncryption_AES128_CBC:    16,
+		BlockEncryption_AES192_CBC:    24,
+		BlockEncryption_AES256_CBC:    32,
+		BlockEncryption_AES128_GCM:    16,
+		BlockEncryption_AES192_GCM:    24,
+		BlockEncryption_AES256_GCM:    32,
+	}
+)
+
+type decryptTransform struct {
+	except []string
+}
+
+func NewDecryptTransform() Transform {
+	return &decryptTransform{}
+}
+
+// NewDecryptTransformWithExcept returns a decryption transform that leaves the
+// EncryptedData elements referenced by the given same-document URIs encrypted.
+func NewDecryptTransformWithExcept(uris ...string) Transform {
+	return &decryptTransform{
+		except: uris,
+	}
+}
+
+func (t *decryptTransform) GetAlgorithm() string {
+	return DecryptXmlTransform
+}
+
+func (t *decryptTransform) Transform(ctx context.Context, data *Data) (*Data, error) {
+	resolver := GetDecryptionKeyResolver(ctx)
+	if resolver == nil {
+		return nil, errors.New("decryption transform requires a key resolver, set it with WithDecryptionKeyResolver")
+	}
+	except := map[string]bool{}
+	for _, uri := range t.except {
+		if !strings.HasPrefix(uri, "#") {
+			return nil, errors.New("decryption transform only supports same-document Except references: " + uri)
+		}
+		except[uri[1:]] = true
+	}
+
+	nodeSet, err := data.NodeSet(ctx)
+	if err != nil {
+		return nil, err
+	}
+	el, err := nodeSet.Element()
+	if err != nil {
+		return nil, err
+	}
+
+	// The encrypted data is replaced in a copy, placed in a document so the
+	// root element can be replaced as well. The key resolver is given the
+	// original EncryptedData, so it can find keys elsewhere in its document.
+	doc := etree.NewDocument()
+	source := nodeSet.Root()
+	if isDocumentNode(source) {
+		// The comments and processing instructions of a document are copied as well
+		for _, token := range nodeSet.Root().Child {
+			switch t := token.(type) {
+			case *etree.Element:
+				source = t
+				doc.AddChild(el)
+			case *etree.Comment:
+				if nodeSet.ContainsToken(t) {
+					doc.CreateComment(t.Data)
+				}
+			case *etree.ProcInst:
+				if nodeSet.ContainsToken(t) {
+					doc.CreateProcInst(t.Target, t.Inst)
+				}
+			}
+		}
+	} else {
+		doc.SetRoot(el)
+	}
+	originals := t.mapEncryptedData(nodeSet, source, el, except)
+	for {
+		encryptedData := t.findEncryptedData(&doc.Element, except)
+		if encryptedData == nil {
+			break
+		}
+		original := originals[encryptedData]
+		if original == nil {
+			original = encryptedData
+		}
+		err := t.decrypt(ctx, resolver, encryptedData, original)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if len(doc.ChildElements()) != 1 {
+		return nil, errors.New("decryption transform did not result in a single root element")
+	}
+
+	return NewNodeSetData(canonicalizer.NewNodeSet(&doc.Element)), nil
+}
+
+func (t *decryptTransform) ReadXml(el *etree.Element) error {
+	t.except = make([]string, 0)
+	for _, exceptElement := range el.SelectElements("Except") {
+		if exceptElement.NamespaceURI() != DecryptNamespaceUri {
+			continue
+		}
+		t.except = append(t.except, exceptElement.SelectAttrValue("URI", ""))
+	}
+	return nil
+}
+
+func (t *decryptTransform) WriteXml(el *etree.Element) error {
+	for _, uri := range t.except {
+		exceptElement := el.CreateElement("Except")
+		exceptElement.Space = "dcrypt"
+		exceptElement.CreateAttr("xmlns:dcrypt", DecryptNamespaceUri)
+		exceptElement.CreateAttr("URI", uri)
+	}
+	return nil
+}
+
+func (t *decryptTransform) findEncryptedData(el *etree.Element, except map[string]bool) *etree.Element {
+	for _, child := range el.ChildElements() {
+		if child.Tag == "EncryptedData" && child.NamespaceURI() == XmlEncNamespaceUri {
+			if except[child.SelectAttrValue("Id", "")] {
+				continue
+			}
+			return child
+		}
+		if found := t.findEncryptedData(child, except); found != nil {
+			return found
+		}
+	}
+	return nil
+}
+
+// mapEncryptedData maps the EncryptedData elements in the copy of the node-set
+// to the elements of the source element they were copied from.
+func (t *decryptTransform) mapEncryptedData(nodeSet *canonicalizer.NodeSet, source *etree.Element, el *etree.Element, except map[string]bool) map[*etree.Element]*etree.Element {
+	originals := t.selectEncryptedData(source, except, nodeSet.ContainsToken)
+	copies := t.selectEncryptedData(el, except, func(token etree.Token) bool {
+		return true
+	})
+	result := map[*etree.Element]*etree.Element{}
+	if len(originals) != len(copies) {
+		return result
+	}
+	for i, encryptedData := range copies {
+		result[encryptedData] = originals[i]
+	}
+	return result
+}
+
+// selectEncryptedData returns the EncryptedData elements in document order
+func (t *decryptTransform) selectEncryptedData(el *etree.Element, except map[string]bool, contains func(token etree.Token) bool) []*etree.Element {
+	if !contains(el) {
+		return nil
+	}
+	if el.Tag == "EncryptedData" && el.NamespaceURI() == XmlEncNamespaceUri {
+		if except[el.SelectAttrValue("Id", "")] {
+			return nil
+		}
+		return []*etree.Element{el}
+	}
+	var result []*etree.Element
+	for _, child := range el.ChildElements() {
+		result = append(result, t.selectEncryptedData(child, except, contains)...)
+	}
+	return result
+}
+
+// decrypt replaces the EncryptedData element by the decrypted element or
+// content, the key is resolved for the original EncryptedData element.
+func (t *decryptTransform) decrypt(ctx context.Context, resolver DecryptionKeyResolver, encryptedData *etree.Element, original *etree.Element) error {
+	encryptionType := encryptedData.SelectAttrValue("Type", "")
+	if encryptionType != EncryptionType_Element && encryptionType != EncryptionType_Content {
+		return errors.New("decryption transform cannot replace encrypted data of type: " + encryptionType)
+	}
+	encryptionMethod := selectChildElement(encryptedData, "EncryptionMethod", XmlEncNamespaceUri)
+	if encryptionMethod == nil {
+		return errors.New("encrypted data does not contain an EncryptionMethod element")
+	}
+	cipherValue, err := getCipherValue(encryptedData)
+	if err != nil {
+		return err
+	}
+	key, err := resolver.ResolveKey(ctx, original)
+	if err != nil {
+		return err
+	}
+	plaintext, err := decryptBlock(encryptionMethod.SelectAttrValue("Algorithm", ""), key, cipherValue)
+	if err != nil {
+		return err
+	}
+
+	// The plaintext is parsed in the namespace context of the encrypted data
+	parent := encryptedData.Parent()
+	namespaces := xpath.InScopeNamespaces(parent)
+	prefixes := make([]string, 0, len(namespaces))
+	for prefix := range namespaces {
+		prefixes = append(prefixes, prefix)
+	}
+	sort.Strings(prefixes)
+	var sb strings.Builder
+	sb.WriteString("<dummy")
+	for _, prefix := range prefixes {
+		if prefix == "" {
+			sb.WriteString(" xmlns=\"")
+		} else {
+			sb.WriteString(" xmlns:" + prefix + "=\"")
+		}
+		sb.WriteString(escapeAttrValue(namespaces[prefix]))
+		sb.WriteString("\"")
+	}
+	sb.WriteString(">")
+	sb.Write(plaintext)
+	sb.WriteString("</dummy>")
+
+	// Invalid plaintext is reported as a decryption failure as well, so the
+	// errors do not reveal anything about the plaintext
+	decrypted := etree.NewDocument()
+	err = decrypted.ReadFromString(sb.String())
+	if err != nil {
+		return ErrDecryptionFailed
+	}
+	content := decrypted.Root().Child
+	if encryptionType == EncryptionType_Element && len(decrypted.Root().ChildElements()) != 1 {
+		return ErrDecryptionFailed
+	}
+
+	index := encryptedData.Index()
+	parent.RemoveChild(encryptedData)
+	for i, token := range append([]etree.Token(nil), content...) {
+		parent.InsertChildAt(index+i, token)
+	}
+	return nil
+}
+
+func decryptBlock(algorithm string, key []byte, ciphertext []byte) ([]byte, error) {
+	keySize, ok := blockEncryptionKeySizes[algorithm]
+	if !ok {
+		return nil, errors.New("unsupported block encryption algorithm: " + algorithm)
+	}
+	if len(key) != keySize {
+		return nil, ErrDecryptionFailed
+	}
+
+	var block cipher.Block
+	var err error
+	if algorithm == BlockEncryption_TripleDES_CBC {
+		block, err = des.NewTripleDESCipher(key)
+	} else {
+		block, err = aes.NewCipher(key)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	switch algorithm {
+	case BlockEncryption_AES128_GCM, BlockEncryption_AES192_GCM, BlockEncryption_AES256_GCM:
+		gcm, err := cipher.NewGCM(block)
+		if err != nil {
+			return nil, err
+		}
+		if len(ciphertext) < gcm.NonceSize()+gcm.Overhead() {
+			return nil, ErrDecryptionFailed
+		}
+		plaintext, err := gcm.Open(nil, ciphertext[:gcm.NonceSize()], ciphertext[gcm.NonceSize():], nil)
+		if err != nil {
+			return nil, ErrDecryptionFailed
+		}
+		return plaintext, nil
+	}
+
+	// CBC mode with the IV prepended and the padding length in the last octet.
+	// Invalid padding is not reported separately, as that would be a padding oracle.
+	blockSize := block.BlockSize()
+	if len(ciphertext) < 2*blockSize || len(ciphertext)%blockSize != 0 {
+		return nil, ErrDecryptionFailed
+	}
+	plaintext := make([]byte, len(ciphertext)-blockSize)
+	cipher.NewCBCDecrypter(block, ciphertext[:blockSize]).CryptBlocks(plaintext, ciphertext[blockSize:])
+	padding := int(plaintext[len(plaintext)-1])
+	if padding < 1 || padding > blockSize {
+		return nil, ErrDecryptionFailed
+	}
+	return plaintext[:len(plaintext)-padding], nil
+}
+
+func escapeAttrValue(value string) string {
+	return strings.NewReplacer("&", "&amp;", "<", "&lt;", "\"", "&quot;").Replace(value)
+}
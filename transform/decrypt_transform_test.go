@@ -0,0 +1,279 @@
+package transform
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"testing"
+
+	"github.com/deb-ict/go-xmldsig/canonicalizer"
+)
+
+const testDecryptPlaintext = `<p:Payment xmlns:p="urn:p">42</p:Payment>`
+
+func encryptTestData(t *testing.T, algorithm string, key []byte, plaintext []byte) string {
+	t.Helper()
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if algorithm == BlockEncryption_AES128_GCM || algorithm == BlockEncryption_AES256_GCM {
+		gcm, err := cipher.NewGCM(block)
+		if err != nil {
+			t.Fatal(err)
+		}
+		nonce := make([]byte, gcm.NonceSize())
+		rand.Read(nonce)
+		return base64.StdEncoding.EncodeToString(gcm.Seal(nonce, nonce, plaintext, nil))
+	}
+
+	padding := block.BlockSize() - len(plaintext)%block.BlockSize()
+	padded := append(append([]byte(nil), plaintext...), make([]byte, padding)...)
+	padded[len(padded)-1] = byte(padding)
+	ciphertext := make([]byte, block.BlockSize()+len(padded))
+	rand.Read(ciphertext[:block.BlockSize()])
+	cipher.NewCBCEncrypter(block, ciphertext[:block.BlockSize()]).CryptBlocks(ciphertext[block.BlockSize():], padded)
+	return base64.StdEncoding.EncodeToString(ciphertext)
+}
+
+func encryptTestKey(t *testing.T, algorithm string, publicKey *rsa.PublicKey, key []byte) string {
+	t.Helper()
+	var ciphertext []byte
+	var err error
+	switch algorithm {
+	case KeyTransport_RSA_1_5:
+		ciphertext, err = rsa.EncryptPKCS1v15(rand.Reader, publicKey, key)
+	case KeyTransport_RSA_OAEP_MGF1:
+		ciphertext, err = rsa.EncryptOAEP(sha1.New(), rand.Reader, publicKey, key, nil)
+	default:
+		ciphertext, err = rsa.EncryptOAEP(sha256.New(), rand.Reader, publicKey, key, nil)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	return base64.StdEncoding.EncodeToString(ciphertext)
+}
+
+func encryptedKeyXml(id string, algorithm string, cipherValue string, dataReference string) string {
+	xml := `<xenc:EncryptedKey xmlns:xenc="http://www.w3.org/2001/04/xmlenc#" Id="` + id + `">` +
+		`<xenc:EncryptionMethod Algorithm="` + algorithm + `">`
+	if algorithm == KeyTransport_RSA_OAEP {
+		xml += `<ds:DigestMethod xmlns:ds="http://www.w3.org/2000/09/xmldsig#" Algorithm="http://www.w3.org/2001/04/xmlenc#sha256"/>` +
+			`<xenc11:MGF xmlns:xenc11="http://www.w3.org/2009/xmlenc11#" Algorithm="http://www.w3.org/2009/xmlenc11#mgf1sha256"/>`
+	}
+	xml += `</xenc:EncryptionMethod>` +
+		`<xenc:CipherData><xenc:CipherValue>` + cipherValue + `</xenc:CipherValue></xenc:CipherData>`
+	if dataReference != "" {
+		xml += `<xenc:ReferenceList><xenc:DataReference URI="` + dataReference + `"/></xenc:ReferenceList>`
+	}
+	return xml + `</xenc:EncryptedKey>`
+}
+
+func encryptedDataXml(algorithm string, keyInfo string, cipherValue string) string {
+	return `<xenc:EncryptedData xmlns:xenc="http://www.w3.org/2001/04/xmlenc#" Id="data" Type="http://www.w3.org/2001/04/xmlenc#Element">` +
+		`<xenc:EncryptionMethod Algorithm="` + algorithm + `"/>` + keyInfo +
+		`<xenc:CipherData><xenc:CipherValue>` + cipherValue + `</xenc:CipherValue></xenc:CipherData>` +
+		`</xenc:EncryptedData>`
+}
+
+func encryptedTestMessage(header string, encryptedData string) string {
+	return `<soap:Envelope xmlns:soap="http://schemas.xmlsoap.org/soap/envelope/">` +
+		`<soap:Header><wsse:Security xmlns:wsse="http://docs.oasis-open.org/wss/2004/01/oasis-200401-wss-wssecurity-secext-1.0.xsd">` + header + `</wsse:Security></soap:Header>` +
+		`<soap:Body>` + encryptedData + `</soap:Body>` +
+		`</soap:Envelope>`
+}
+
+func Test_DecryptTransform(t *testing.T) {
+	privateKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	aes128Key := make([]byte, 16)
+	rand.Read(aes128Key)
+	aes256Key := make([]byte, 32)
+	rand.Read(aes256Key)
+	aes128CipherValue := encryptTestData(t, BlockEncryption_AES128_CBC, aes128Key, []byte(testDecryptPlaintext))
+	aes256CipherValue := encryptTestData(t, BlockEncryption_AES256_GCM, aes256Key, []byte(testDecryptPlaintext))
+
+	tests := []struct {
+		name     string
+		document string
+	}{
+		{
+			name: "EmbeddedEncryptedKey",
+			document: encryptedTestMessage("", encryptedDataXml(BlockEncryption_AES128_CBC,
+				`<ds:KeyInfo xmlns:ds="http://www.w3.org/2000/09/xmldsig#">`+
+					encryptedKeyXml("key", KeyTransport_RSA_OAEP_MGF1, encryptTestKey(t, KeyTransport_RSA_OAEP_MGF1, &privateKey.PublicKey, aes128Key), "")+
+					`</ds:KeyInfo>`,
+				aes128CipherValue)),
+		},
+		{
+			name: "RetrievalMethod",
+			document: encryptedTestMessage(
+				encryptedKeyXml("key", KeyTransport_RSA_OAEP_MGF1, encryptTestKey(t, KeyTransport_RSA_OAEP_MGF1, &privateKey.PublicKey, aes128Key), ""),
+				encryptedDataXml(BlockEncryption_AES128_CBC,
+					`<ds:KeyInfo xmlns:ds="http://www.w3.org/2000/09/xmldsig#">`+
+						`<ds:RetrievalMethod URI="#key" Type="http://www.w3.org/2001/04/xmlenc#EncryptedKey"/>`+
+						`</ds:KeyInfo>`,
+					aes128CipherValue)),
+		},
+		{
+			name: "ReferenceList",
+			document: encryptedTestMessage(
+				encryptedKeyXml("key", KeyTransport_RSA_OAEP_MGF1, encryptTestKey(t, KeyTransport_RSA_OAEP_MGF1, &privateKey.PublicKey, aes128Key), "#data"),
+				encryptedDataXml(BlockEncryption_AES128_CBC, "", aes128CipherValue)),
+		},
+		{
+			name: "OAEP",
+			document: encryptedTestMessage(
+				encryptedKeyXml("key", KeyTransport_RSA_OAEP, encryptTestKey(t, KeyTransport_RSA_OAEP, &privateKey.PublicKey, aes256Key), "#data"),
+				encryptedDataXml(BlockEncryption_AES256_GCM, "", aes256CipherValue)),
+		},
+		{
+			name: "PKCS1v15",
+			document: encryptedTestMessage(
+				encryptedKeyXml("key", KeyTransport_RSA_1_5, encryptTestKey(t, KeyTransport_RSA_1_5, &privateKey.PublicKey, aes256Key), "#data"),
+				encryptedDataXml(BlockEncryption_AES256_GCM, "", aes256CipherValue)),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Only the body is transformed, the key is found in its document
+			doc := parseTestDocument(t, tt.document)
+			body := doc.FindElement("//Body")
+			ctx := WithDecryptionKeyResolver(context.Background(), NewPrivateKeyDecryptionKeyResolver(privateKey))
+			actual := transformToString(t, ctx, NewDecryptTransform(), NewNodeSetData(canonicalizer.NewNodeSet(body)))
+			expected := `<soap:Body xmlns:soap="http://schemas.xmlsoap.org/soap/envelope/">` + testDecryptPlaintext + `</soap:Body>`
+			if actual != expected {
+				t.Errorf("expected:\n%s\ngot:\n%s", expected, actual)
+			}
+		})
+	}
+}
+
+func Test_DecryptTransform_WrongKey(t *testing.T) {
+	privateKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	otherKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	key := make([]byte, 16)
+	rand.Read(key)
+	otherBlockKey := make([]byte, 16)
+	rand.Read(otherBlockKey)
+
+	// The block encryption key is valid for the key transport, but not for the data
+	tamperedCipherValue := encryptTestData(t, BlockEncryption_AES128_CBC, otherBlockKey, []byte(testDecryptPlaintext))
+
+	tests := []struct {
+		name     string
+		document string
+		err      error
+	}{
+		{
+			name: "OAEP",
+			document: encryptedTestMessage(
+				encryptedKeyXml("key", KeyTransport_RSA_OAEP, encryptTestKey(t, KeyTransport_RSA_OAEP, &otherKey.PublicKey, key), "#data"),
+				encryptedDataXml(BlockEncryption_AES128_GCM, "", encryptTestData(t, BlockEncryption_AES128_GCM, key, []byte(testDecryptPlaintext)))),
+			err: rsa.ErrDecryption,
+		},
+		{
+			// An invalid PKCS #1 v1.5 key results in a random key
+			name: "PKCS1v15",
+			document: encryptedTestMessage(
+				encryptedKeyXml("key", KeyTransport_RSA_1_5, encryptTestKey(t, KeyTransport_RSA_1_5, &otherKey.PublicKey, key), "#data"),
+				encryptedDataXml(BlockEncryption_AES128_GCM, "", encryptTestData(t, BlockEncryption_AES128_GCM, key, []byte(testDecryptPlaintext)))),
+			err: ErrDecryptionFailed,
+		},
+		{
+			name: "BlockEncryption",
+			document: encryptedTestMessage(
+				encryptedKeyXml("key", KeyTransport_RSA_OAEP_MGF1, encryptTestKey(t, KeyTransport_RSA_OAEP_MGF1, &privateKey.PublicKey, key), "#data"),
+				encryptedDataXml(BlockEncryption_AES128_CBC, "", tamperedCipherValue)),
+			err: ErrDecryptionFailed,
+		},
+		{
+			name: "NoKey",
+			document: encryptedTestMessage(
+				encryptedKeyXml("key", KeyTransport_RSA_OAEP_MGF1, encryptTestKey(t, KeyTransport_RSA_OAEP_MGF1, &privateKey.PublicKey, key), "#other"),
+				encryptedDataXml(BlockEncryption_AES128_CBC, "", tamperedCipherValue)),
+			err: ErrDecryptionKeyNotFound,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc := parseTestDocument(t, tt.document)
+			ctx := WithDecryptionKeyResolver(context.Background(), NewPrivateKeyDecryptionKeyResolver(privateKey))
+			_, err := NewDecryptTransform().Transform(ctx, NewNodeSetData(canonicalizer.NewNodeSet(doc.FindElement("//Body"))))
+			if !errors.Is(err, tt.err) {
+				t.Errorf("expected %v, got %v", tt.err, err)
+			}
+		})
+	}
+}
+
+func Test_DecryptTransform_PaddingErrors(t *testing.T) {
+	key := make([]byte, 16)
+	rand.Read(key)
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ciphertext, err := base64.StdEncoding.DecodeString(encryptTestData(t, BlockEncryption_AES128_CBC, key, []byte(testDecryptPlaintext)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Invalid padding and invalid plaintext can not be told apart
+	tests := []struct {
+		name    string
+		padding byte
+	}{
+		{"ZeroPadding", 0},
+		{"PaddingTooLarge", 17},
+		{"InvalidPlaintext", 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// The previous ciphertext block is changed to set the last plaintext octet
+			tampered := append([]byte(nil), ciphertext...)
+			last := make([]byte, block.BlockSize())
+			block.Decrypt(last, tampered[len(tampered)-16:])
+			tampered[len(tampered)-17] = last[15] ^ tt.padding
+
+			doc := parseTestDocument(t, encryptedTestMessage("", encryptedDataXml(BlockEncryption_AES128_CBC, "", base64.StdEncoding.EncodeToString(tampered))))
+			ctx := WithDecryptionKeyResolver(context.Background(), NewFixedDecryptionKeyResolver(key))
+			_, err := NewDecryptTransform().Transform(ctx, NewNodeSetData(canonicalizer.NewNodeSet(doc.FindElement("//Body"))))
+			if err != ErrDecryptionFailed {
+				t.Errorf("expected ErrDecryptionFailed, got %v", err)
+			}
+		})
+	}
+}
+
+func Test_DecryptTransform_Document(t *testing.T) {
+	key := make([]byte, 16)
+	rand.Read(key)
+	document := "<?pi x?>\n" + encryptedDataXml(BlockEncryption_AES128_CBC, "", encryptTestData(t, BlockEncryption_AES128_CBC, key, []byte(testDecryptPlaintext))) + "\n<!--c-->"
+
+	// The encrypted document element is replaced in the document
+	ctx := WithDecryptionKeyResolver(context.Background(), NewFixedDecryptionKeyResolver(key))
+	data, err := NewDecryptTransform().Transform(ctx, NewOctetData([]byte(document)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	actual := transformToString(t, ctx, NewC14N10RecWithCommentsTransform(), data)
+	expected := "<?pi x?>\n" + testDecryptPlaintext + "\n<!--c-->"
+	if actual != expected {
+		t.Errorf("expected %q, got %q", expected, actual)
+	}
+}
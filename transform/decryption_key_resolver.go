@@ -0,0 +1,240 @@
+package transform
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	_ "crypto/sha1"
+	_ "crypto/sha256"
+	_ "crypto/sha512"
+	"encoding/base64"
+	"errors"
+	"strings"
+
+	"github.com/beevik/etree"
+)
+
+const (
+	XmlEncNamespaceUri   string = "http://www.w3.org/2001/04/xmlenc#"
+	XmlEnc11NamespaceUri string = "http://www.w3.org/2009/xmlenc11#"
+
+	KeyTransport_RSA_1_5       string = "http://www.w3.org/2001/04/xmlenc#rsa-1_5"
+	KeyTransport_RSA_OAEP_MGF1 string = "http://www.w3.org/2001/04/xmlenc#rsa-oaep-mgf1p"
+	KeyTransport_RSA_OAEP      string = "http://www.w3.org/2009/xmlenc11#rsa-oaep"
+)
+
+var (
+	ErrDecryptionKeyNotFound = errors.New("decryption key not found")
+	ErrDecryptionFailed      = errors.New("decryption failed")
+
+	oaepDigestMethods map[string]crypto.Hash = map[string]crypto.Hash{
+		"http://www.w3.org/2000/09/xmldsig#sha1":        crypto.SHA1,
+		"http://www.w3.org/2001/04/xmldsig-more#sha224": crypto.SHA224,
+		"http://www.w3.org/2001/04/xmlenc#sha256":       crypto.SHA256,
+		"http://www.w3.org/2001/04/xmldsig-more#sha384": crypto.SHA384,
+		"http://www.w3.org/2001/04/xmlenc#sha512":       crypto.SHA512,
+	}
+	oaepMaskGenerationFunctions map[string]crypto.Hash = map[string]crypto.Hash{
+		"http://www.w3.org/2009/xmlenc11#mgf1sha1":   crypto.SHA1,
+		"http://www.w3.org/2009/xmlenc11#mgf1sha224": crypto.SHA224,
+		"http://www.w3.org/2009/xmlenc11#mgf1sha256": crypto.SHA256,
+		"http://www.w3.org/2009/xmlenc11#mgf1sha384": crypto.SHA384,
+		"http://www.w3.org/2009/xmlenc11#mgf1sha512": crypto.SHA512,
+	}
+)
+
+// DecryptionKeyResolver returns the symmetric key used to encrypt an
+// xenc:EncryptedData element.
+type DecryptionKeyResolver interface {
+	ResolveKey(ctx context.Context, encryptedData *etree.Element) ([]byte, error)
+}
+
+type DecryptionKeyResolverFunc func(ctx context.Context, encryptedData *etree.Element) ([]byte, error)
+
+func (f DecryptionKeyResolverFunc) ResolveKey(ctx context.Context, encryptedData *etree.Element) ([]byte, error) {
+	return f(ctx, encryptedData)
+}
+
+type fixedDecryptionKeyResolver struct {
+	key []byte
+}
+
+func NewFixedDecryptionKeyResolver(key []byte) DecryptionKeyResolver {
+	return &fixedDecryptionKeyResolver{
+		key: key,
+	}
+}
+
+func (r *fixedDecryptionKeyResolver) ResolveKey(ctx context.Context, encryptedData *etree.Element) ([]byte, error) {
+	if r.key == nil {
+		return nil, ErrDecryptionKeyNotFound
+	}
+	return r.key, nil
+}
+
+type privateKeyDecryptionKeyResolver struct {
+	key crypto.Decrypter
+}
+
+// NewPrivateKeyDecryptionKeyResolver decrypts the xenc:EncryptedKey of the
+// EncryptedData with an RSA private key. The EncryptedKey is either contained in
+// the KeyInfo, referenced by a RetrievalMethod or refers to the EncryptedData
+// through its ReferenceList.
+func NewPrivateKeyDecryptionKeyResolver(key crypto.Decrypter) DecryptionKeyResolver {
+	return &privateKeyDecryptionKeyResolver{
+		key: key,
+	}
+}
+
+func (r *privateKeyDecryptionKeyResolver) ResolveKey(ctx context.Context, encryptedData *etree.Element) ([]byte, error) {
+	encryptedKey := findEncryptedKey(encryptedData)
+	if encryptedKey == nil {
+		return nil, ErrDecryptionKeyNotFound
+	}
+
+	encryptionMethod := selectChildElement(encryptedKey, "EncryptionMethod", XmlEncNamespaceUri)
+	if encryptionMethod == nil {
+		return nil, errors.New("encrypted key does not contain an EncryptionMethod element")
+	}
+	cipherValue, err := getCipherValue(encryptedKey)
+	if err != nil {
+		return nil, err
+	}
+
+	algorithm := encryptionMethod.SelectAttrValue("Algorithm", "")
+	switch algorithm {
+	case KeyTransport_RSA_1_5:
+		// Invalid padding results in a random key of the size of the block
+		// encryption key instead of an error, so this is not a padding oracle
+		keySize, err := getBlockEncryptionKeySize(encryptedData)
+		if err != nil {
+			return nil, err
+		}
+		return r.key.Decrypt(rand.Reader, cipherValue, &rsa.PKCS1v15DecryptOptions{SessionKeyLen: keySize})
+	case KeyTransport_RSA_OAEP_MGF1, KeyTransport_RSA_OAEP:
+		opts, err := getOAEPOptions(encryptionMethod)
+		if err != nil {
+			return nil, err
+		}
+		return r.key.Decrypt(rand.Reader, cipherValue, opts)
+	}
+	return nil, errors.New("unsupported key transport algorithm: " + algorithm)
+}
+
+func getBlockEncryptionKeySize(encryptedData *etree.Element) (int, error) {
+	encryptionMethod := selectChildElement(encryptedData, "EncryptionMethod", XmlEncNamespaceUri)
+	if encryptionMethod == nil {
+		return 0, errors.New("encrypted data does not contain an EncryptionMethod element")
+	}
+	algorithm := encryptionMethod.SelectAttrValue("Algorithm", "")
+	keySize, ok := blockEncryptionKeySizes[algorithm]
+	if !ok {
+		return 0, errors.New("unsupported block encryption algorithm: " + algorithm)
+	}
+	return keySize, nil
+}
+
+func getOAEPOptions(encryptionMethod *etree.Element) (*rsa.OAEPOptions, error) {
+	opts := &rsa.OAEPOptions{
+		Hash:    crypto.SHA1,
+		MGFHash: crypto.SHA1,
+	}
+
+	if digestMethod := selectChildElement(encryptionMethod, "DigestMethod", xmlDSigNamespaceUri); digestMethod != nil {
+		algorithm := digestMethod.SelectAttrValue("Algorithm", "")
+		digestHash, ok := oaepDigestMethods[algorithm]
+		if !ok {
+			return nil, errors.New("unsupported OAEP digest method: " + algorithm)
+		}
+		opts.Hash = digestHash
+	}
+	if encryptionMethod.SelectAttrValue("Algorithm", "") == KeyTransport_RSA_OAEP {
+		if mgf := selectChildElement(encryptionMethod, "MGF", XmlEnc11NamespaceUri); mgf != nil {
+			algorithm := mgf.SelectAttrValue("Algorithm", "")
+			mgfHash, ok := oaepMaskGenerationFunctions[algorithm]
+			if !ok {
+				return nil, errors.New("unsupported OAEP mask generation function: " + algorithm)
+			}
+			opts.MGFHash = mgfHash
+		}
+	} else {
+		opts.MGFHash = opts.Hash
+	}
+	if params := selectChildElement(encryptionMethod, "OAEPparams", XmlEncNamespaceUri); params != nil {
+		label, err := base64.StdEncoding.DecodeString(strings.TrimSpace(params.Text()))
+		if err != nil {
+			return nil, err
+		}
+		opts.Label = label
+	}
+	return opts, nil
+}
+
+func findEncryptedKey(encryptedData *etree.Element) *etree.Element {
+	document := encryptedData
+	for document.Parent() != nil {
+		document = document.Parent()
+	}
+
+	if keyInfo := selectChildElement(encryptedData, "KeyInfo", xmlDSigNamespaceUri); keyInfo != nil {
+		if encryptedKey := selectChildElement(keyInfo, "EncryptedKey", XmlEncNamespaceUri); encryptedKey != nil {
+			return encryptedKey
+		}
+		if retrievalMethod := selectChildElement(keyInfo, "RetrievalMethod", xmlDSigNamespaceUri); retrievalMethod != nil {
+			uri := retrievalMethod.SelectAttrValue("URI", "")
+			if strings.HasPrefix(uri, "#") {
+				return findElementById(document, uri[1:])
+			}
+		}
+	}
+
+	// An EncryptedKey may list the data it encrypts the key for
+	id := encryptedData.SelectAttrValue("Id", "")
+	if id == "" {
+		return nil
+	}
+	var found *etree.Element
+	var visit func(el *etree.Element)
+	visit = func(el *etree.Element) {
+		if found != nil {
+			return
+		}
+		if el.Tag == "EncryptedKey" && el.NamespaceURI() == XmlEncNamespaceUri {
+			if referenceList := selectChildElement(el, "ReferenceList", XmlEncNamespaceUri); referenceList != nil {
+				for _, dataReference := range referenceList.SelectElements("DataReference") {
+					if dataReference.SelectAttrValue("URI", "") == "#"+id {
+						found = el
+						return
+					}
+				}
+			}
+		}
+		for _, child := range el.ChildElements() {
+			visit(child)
+		}
+	}
+	visit(document)
+	return found
+}
+
+func getCipherValue(el *etree.Element) ([]byte, error) {
+	cipherData := selectChildElement(el, "CipherData", XmlEncNamespaceUri)
+	if cipherData == nil {
+		return nil, errors.New("encrypted element does not contain a CipherData element")
+	}
+	cipherValue := selectChildElement(cipherData, "CipherValue", XmlEncNamespaceUri)
+	if cipherValue == nil {
+		return nil, errors.New("encrypted element does not contain a CipherValue element")
+	}
+	return base64.StdEncoding.DecodeString(strings.Join(strings.Fields(cipherValue.Text()), ""))
+}
+
+func selectChildElement(el *etree.Element, tag string, namespaceUri string) *etree.Element {
+	for _, child := range el.SelectElements(tag) {
+		if child.NamespaceURI() == namespaceUri {
+			return child
+		}
+	}
+	return nil
+}
@@ -2,10 +2,9 @@ package transform
 
 import (
 	"context"
-	"errors"
 
 	"github.com/beevik/etree"
-	rhdsig "github.com/russellhaering/goxmldsig"
+	"github.com/deb-ict/go-xmldsig/canonicalizer"
 )
 
 type envelopedSignatureTransform struct {
@@ -19,24 +18,20 @@ func (t *envelopedSignatureTransform) GetAlgorithm() string {
 	return EnvelopedSignatureTransform
 }
 
-func (t *envelopedSignatureTransform) TransformXmlElement(ctx context.Context, el *etree.Element) ([]byte, error) {
-	signatureElement := el.FindElement("Signature[namespace-uri()='http://www.w3.org/2000/09/xmldsig#']")
-	if signatureElement == nil {
-		return nil, errors.New("Error applying canonicalization transform: Signature not found")
+func (t *envelopedSignatureTransform) Transform(ctx context.Context, data *Data) (*Data, error) {
+	nodeSet, err := data.NodeSet(ctx)
+	if err != nil {
+		return nil, err
 	}
-	signaturePath := t.mapPathToElement(el, signatureElement)
 
-	el = el.Copy()
-	if !t.removeElementAtPath(el, signaturePath) {
-		return nil, errors.New("Error applying canonicalization transform: Signature not found")
+	// Remove the signature containing the reference, not just any signature. A
+	// signature outside of the referenced data leaves the node-set unchanged.
+	signatureElement := GetSignatureElement(ctx)
+	if signatureElement == nil || !canonicalizer.IsDescendantOrSelf(signatureElement, nodeSet.Root()) {
+		return data, nil
 	}
 
-	canonicalizer := rhdsig.MakeNullCanonicalizer()
-	return canonicalizer.Canonicalize(el)
-}
-
-func (t *envelopedSignatureTransform) TransformData(ctx context.Context, data []byte) ([]byte, error) {
-	return nil, errors.New("enveloped signature transform cannot be applied to data")
+	return NewNodeSetData(nodeSet.WithoutSubtree(signatureElement)), nil
 }
 
 func (t *envelopedSignatureTransform) ReadXml(el *etree.Element) error {
@@ -46,61 +41,3 @@ func (t *envelopedSignatureTransform) ReadXml(el *etree.Element) error {
 func (t *envelopedSignatureTransform) WriteXml(el *etree.Element) error {
 	return nil
 }
-
-func (t *envelopedSignatureTransform) findParentElement(el *etree.Element, tag, namespaceUri string) (*etree.Element, error) {
-	if el == nil {
-		return nil, errors.New("Element not found")
-	}
-
-	if el.Tag == tag && el.NamespaceURI() == namespaceUri {
-		return el, nil
-	}
-
-	parent := el.Parent()
-	if parent != nil {
-		return t.findParentElement(parent, tag, namespaceUri)
-	}
-
-	return nil, errors.New("Element not found")
-}
-
-func (t *envelopedSignatureTransform) mapPathToElement(tree, el *etree.Element) []int {
-	for i, child := range tree.Child {
-		if child == el {
-			return []int{i}
-		}
-	}
-
-	for i, child := range tree.Child {
-		if childElement, ok := child.(*etree.Element); ok {
-			childPath := t.mapPathToElement(childElement, el)
-			if childPath != nil {
-				return append([]int{i}, childPath...)
-			}
-		}
-	}
-
-	return nil
-}
-
-func (t *envelopedSignatureTransform) removeElementAtPath(el *etree.Element, path []int) bool {
-	if len(path) == 0 {
-		return false
-	}
-
-	if len(el.Child) <= path[0] {
-		return false
-	}
-
-	childElement, ok := el.Child[path[0]].(*etree.Element)
-	if !ok {
-		return false
-	}
-
-	if len(path) == 1 {
-		el.RemoveChild(childElement)
-		return true
-	}
-
-	return t.removeElementAtPath(childElement, path[1:])
-}
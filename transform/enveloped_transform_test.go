@@ -0,0 +1,58 @@
+package transform
+
+import (
+	"context"
+	"testing"
+
+	"github.com/beevik/etree"
+	"github.com/deb-ict/go-xmldsig/canonicalizer"
+)
+
+func parseTestDocument(t *testing.T, s string) *etree.Document {
+	t.Helper()
+	doc := etree.NewDocument()
+	err := doc.ReadFromString(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return doc
+}
+
+func transformToString(t *testing.T, ctx context.Context, transform Transform, data *Data) string {
+	t.Helper()
+	result, err := transform.Transform(ctx, data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	octets, err := result.Octets(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(octets)
+}
+
+func Test_EnvelopedSignatureTransform(t *testing.T) {
+	doc := parseTestDocument(t, `<root><data Id="d"><v>1</v><Signature>enveloped</Signature></data><Signature>sibling</Signature></root>`)
+	data := doc.FindElement("//data")
+	enveloped := doc.FindElement("//data/Signature")
+	sibling := doc.FindElement("/root/Signature")
+
+	tests := []struct {
+		name      string
+		signature *etree.Element
+		expected  string
+	}{
+		{"Enveloped", enveloped, `<data Id="d"><v>1</v></data>`},
+		{"Sibling", sibling, `<data Id="d"><v>1</v><Signature>enveloped</Signature></data>`},
+		{"Missing", nil, `<data Id="d"><v>1</v><Signature>enveloped</Signature></data>`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := WithSignatureElement(context.Background(), tt.signature)
+			actual := transformToString(t, ctx, NewEnvelopedSignatureTransform(), NewNodeSetData(canonicalizer.NewNodeSet(data)))
+			if actual != tt.expected {
+				t.Errorf("expected %s, got %s", tt.expected, actual)
+			}
+		})
+	}
+}
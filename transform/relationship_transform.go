@@ -0,0 +1,122 @@
+package transform
+
+import (
+	"context"
+	"errors"
+	"sort"
+
+	"github.com/beevik/etree"
+	"github.com/deb-ict/go-xmldsig/canonicalizer"
+)
+
+const (
+	OpcRelationshipsNamespaceUri     string = "http://schemas.openxmlformats.org/package/2006/relationships"
+	OpcDigitalSignatureNamespaceUri  string = "http://schemas.openxmlformats.org/package/2006/digital-signature"
+	opcRelationshipTargetModeDefault string = "Internal"
+)
+
+type relationshipTransform struct {
+	sourceIds   []string
+	sourceTypes []string
+}
+
+func NewRelationshipTransform() Transform {
+	return &relationshipTransform{}
+}
+
+// NewRelationshipTransformWithSelection returns a relationship transform that
+// selects the relationships with the given ids and the given types.
+func NewRelationshipTransformWithSelection(sourceIds []string, sourceTypes []string) Transform {
+	return &relationshipTransform{
+		sourceIds:   sourceIds,
+		sourceTypes: sourceTypes,
+	}
+}
+
+func (t *relationshipTransform) GetAlgorithm() string {
+	return RelationshipTransform
+}
+
+func (t *relationshipTransform) Transform(ctx context.Context, data *Data) (*Data, error) {
+	nodeSet, err := data.NodeSet(ctx)
+	if err != nil {
+		return nil, err
+	}
+	el, err := nodeSet.Element()
+	if err != nil {
+		return nil, err
+	}
+	if el == nil || el.Tag != "Relationships" || el.NamespaceURI() != OpcRelationshipsNamespaceUri {
+		return nil, errors.New("relationship transform input is not a relationships part")
+	}
+
+	// Select the referenced relationships, sorted by id
+	sourceIds := map[string]bool{}
+	for _, id := range t.sourceIds {
+		sourceIds[id] = true
+	}
+	sourceTypes := map[string]bool{}
+	for _, sourceType := range t.sourceTypes {
+		sourceTypes[sourceType] = true
+	}
+	relationships := make([]*etree.Element, 0)
+	for _, relationship := range el.SelectElements("Relationship") {
+		if relationship.NamespaceURI() != OpcRelationshipsNamespaceUri {
+			continue
+		}
+		if sourceIds[relationship.SelectAttrValue("Id", "")] || sourceTypes[relationship.SelectAttrValue("Type", "")] {
+			relationships = append(relationships, relationship)
+		}
+	}
+	sort.SliceStable(relationships, func(i, j int) bool {
+		return relationships[i].SelectAttrValue("Id", "") < relationships[j].SelectAttrValue("Id", "")
+	})
+
+	// Only the Id, Type, Target and TargetMode attributes are kept
+	result := etree.NewElement("Relationships")
+	result.CreateAttr("xmlns", OpcRelationshipsNamespaceUri)
+	for _, relationship := range relationships {
+		resultRelationship := result.CreateElement("Relationship")
+		for _, name := range []string{"Id", "Type", "Target"} {
+			if attr := relationship.SelectAttr(name); attr != nil {
+				resultRelationship.CreateAttr(name, attr.Value)
+			}
+		}
+		resultRelationship.CreateAttr("TargetMode", relationship.SelectAttrValue("TargetMode", opcRelationshipTargetModeDefault))
+	}
+
+	return NewNodeSetData(canonicalizer.NewNodeSet(result)), nil
+}
+
+func (t *relationshipTransform) ReadXml(el *etree.Element) error {
+	t.sourceIds = make([]string, 0)
+	t.sourceTypes = make([]string, 0)
+	for _, child := range el.ChildElements() {
+		if child.NamespaceURI() != OpcDigitalSignatureNamespaceUri {
+			continue
+		}
+		switch child.Tag {
+		case "RelationshipReference":
+			t.sourceIds = append(t.sourceIds, child.SelectAttrValue("SourceId", ""))
+		case "RelationshipsGroupReference":
+			t.sourceTypes = append(t.sourceTypes, child.SelectAttrValue("SourceType", ""))
+		}
+	}
+	return nil
+}
+
+func (t *relationshipTransform) WriteXml(el *etree.Element) error {
+	for _, sourceId := range t.sourceIds {
+		referenceElement := el.CreateElement("RelationshipReference")
+		referenceElement.Space = "mdssi"
+		referenceElement.CreateAttr("xmlns:mdssi", OpcDigitalSignatureNamespaceUri)
+		referenceElement.CreateAttr("SourceId", sourceId)
+	}
+	for _, sourceType := range t.sourceTypes {
+		referenceElement := el.CreateElement("RelationshipsGroupReference")
+		referenceElement.Space = "mdssi"
+		referenceElement.CreateAttr("xmlns:mdssi", OpcDigitalSignatureNamespaceUri)
+		referenceElement.CreateAttr("SourceType", sourceType)
+	}
+	return nil
+}
@@ -0,0 +1,203 @@
+package transform
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"github.com/beevik/etree"
+	"github.com/deb-ict/go-xmldsig/canonicalizer"
+)
+
+const (
+	xmlDSigNamespaceUri string = "http://www.w3.org/2000/09/xmldsig#"
+	wsseNamespaceUri    string = "http://docs.oasis-open.org/wss/2004/01/oasis-200401-wss-wssecurity-secext-1.0.xsd"
+	wsuNamespaceUri     string = "http://docs.oasis-open.org/wss/2004/01/oasis-200401-wss-wssecurity-utility-1.0.xsd"
+
+	samlAssertionIdValueType string = "http://docs.oasis-open.org/wss/oasis-wss-saml-token-profile-1.0#SAMLAssertionID"
+	samlIdValueType          string = "http://docs.oasis-open.org/wss/oasis-wss-saml-token-profile-1.1#SAMLID"
+)
+
+type strTransform struct {
+	canonicalizer canonicalizer.Canonicalizer
+}
+
+func NewSTRTransform() Transform {
+	return &strTransform{}
+}
+
+func NewSTRTransformWithCanonicalizer(can canonicalizer.Canonicalizer) Transform {
+	return &strTransform{
+		canonicalizer: can,
+	}
+}
+
+func (t *strTransform) GetAlgorithm() string {
+	return STRTransform
+}
+
+func (t *strTransform) Transform(ctx context.Context, data *Data) (*Data, error) {
+	if t.canonicalizer == nil {
+		return nil, errors.New("str transform does not contain a CanonicalizationMethod parameter")
+	}
+	nodeSet, err := data.NodeSet(ctx)
+	if err != nil {
+		return nil, err
+	}
+	el, err := nodeSet.Element()
+	if err != nil {
+		return nil, err
+	}
+
+	// Tokens are looked up in the document of the referenced data
+	document := nodeSet.Root()
+	for document.Parent() != nil {
+		document = document.Parent()
+	}
+	el, err = t.replaceReferences(document, el)
+	if err != nil {
+		return nil, err
+	}
+
+	canonicalized, err := t.canonicalizer.Canonicalize(ctx, el)
+	if err != nil {
+		return nil, err
+	}
+	return NewOctetData(canonicalized), nil
+}
+
+func (t *strTransform) ReadXml(el *etree.Element) error {
+	var parametersElement *etree.Element
+	for _, child := range el.SelectElements("TransformationParameters") {
+		if child.NamespaceURI() != wsseNamespaceUri {
+			continue
+		}
+		if parametersElement != nil {
+			return errors.New("str transform contains multiple TransformationParameters elements")
+		}
+		parametersElement = child
+	}
+	if parametersElement == nil {
+		return errors.New("str transform does not contain a TransformationParameters element")
+	}
+
+	var canonicalizationMethodElement *etree.Element
+	for _, child := range parametersElement.SelectElements("CanonicalizationMethod") {
+		if child.NamespaceURI() != xmlDSigNamespaceUri {
+			continue
+		}
+		if canonicalizationMethodElement != nil {
+			return errors.New("str transform contains multiple CanonicalizationMethod elements")
+		}
+		canonicalizationMethodElement = child
+	}
+	if canonicalizationMethodElement == nil {
+		return errors.New("str transform does not contain a CanonicalizationMethod parameter")
+	}
+
+	can, err := canonicalizer.LoadCanonicalizer(canonicalizationMethodElement.SelectAttrValue("Algorithm", ""), canonicalizationMethodElement)
+	if err != nil {
+		return err
+	}
+	t.canonicalizer = can
+	return nil
+}
+
+func (t *strTransform) WriteXml(el *etree.Element) error {
+	if t.canonicalizer == nil {
+		return errors.New("str transform does not contain a CanonicalizationMethod parameter")
+	}
+
+	parametersElement := el.CreateElement("TransformationParameters")
+	parametersElement.Space = "wsse"
+	parametersElement.CreateAttr("xmlns:wsse", wsseNamespaceUri)
+
+	canonicalizationMethodElement := parametersElement.CreateElement("CanonicalizationMethod")
+	canonicalizationMethodElement.Space = el.Space
+	canonicalizationMethodElement.CreateAttr("Algorithm", t.canonicalizer.GetAlgorithm())
+	return t.canonicalizer.WriteXml(canonicalizationMethodElement)
+}
+
+// replaceReferences replaces every SecurityTokenReference in the element by
+// the token it refers to.
+func (t *strTransform) replaceReferences(document *etree.Element, el *etree.Element) (*etree.Element, error) {
+	if el.Tag == "SecurityTokenReference" && el.NamespaceURI() == wsseNamespaceUri {
+		return t.dereference(document, el)
+	}
+
+	for _, child := range el.ChildElements() {
+		replaced, err := t.replaceReferences(document, child)
+		if err != nil {
+			return nil, err
+		}
+		if replaced != child {
+			el.InsertChildAt(child.Index(), replaced)
+			el.RemoveChild(child)
+		}
+	}
+	return el, nil
+}
+
+func (t *strTransform) dereference(document *etree.Element, str *etree.Element) (*etree.Element, error) {
+	var token *etree.Element
+	for _, child := range str.ChildElements() {
+		if child.NamespaceURI() != wsseNamespaceUri {
+			continue
+		}
+
+		switch child.Tag {
+		case "Reference":
+			uri := child.SelectAttrValue("URI", "")
+			if !strings.HasPrefix(uri, "#") {
+				return nil, errors.New("str transform does not support external token references: " + uri)
+			}
+			token = findElementById(document, uri[1:])
+		case "KeyIdentifier":
+			valueType := child.SelectAttrValue("ValueType", "")
+			if valueType != samlAssertionIdValueType && valueType != samlIdValueType {
+				return nil, errors.New("str transform does not support key identifier value type: " + valueType)
+			}
+			token = findElementById(document, strings.TrimSpace(child.Text()))
+			if token != nil && token.Tag != "Assertion" {
+				token = nil
+			}
+		case "Embedded":
+			elements := child.ChildElements()
+			if len(elements) != 1 {
+				return nil, errors.New("str transform embedded reference does not contain a single token")
+			}
+			return canonicalizer.NewNodeSet(elements[0]).Element()
+		default:
+			continue
+		}
+		break
+	}
+	if token == nil {
+		return nil, errors.New("str transform could not dereference the security token")
+	}
+
+	// The token is rendered with the namespaces in scope of its own location
+	return canonicalizer.NewNodeSet(token).Element()
+}
+
+// findElementById returns the element with the given wsu:Id, Id, ID or
+// AssertionID attribute.
+func findElementById(el *etree.Element, id string) *etree.Element {
+	for _, attr := range el.Attr {
+		if attr.Value != id {
+			continue
+		}
+		switch {
+		case attr.Key == "Id" && (attr.Space == "" || attr.NamespaceURI() == wsuNamespaceUri):
+			return el
+		case attr.Space == "" && (attr.Key == "ID" || attr.Key == "AssertionID"):
+			return el
+		}
+	}
+	for _, child := range el.ChildElements() {
+		if found := findElementById(child, id); found != nil {
+			return found
+		}
+	}
+	return nil
+}
@@ -0,0 +1,172 @@
+package transform
+
+import (
+	"context"
+	"testing"
+
+	"github.com/beevik/etree"
+	"github.com/deb-ict/go-xmldsig/canonicalizer"
+)
+
+const testSTRDocument = `<soap:Envelope xmlns:soap="http://schemas.xmlsoap.org/soap/envelope/"` +
+	` xmlns:wsse="http://docs.oasis-open.org/wss/2004/01/oasis-200401-wss-wssecurity-secext-1.0.xsd"` +
+	` xmlns:wsu="http://docs.oasis-open.org/wss/2004/01/oasis-200401-wss-wssecurity-utility-1.0.xsd">` +
+	`<soap:Header><wsse:Security>` +
+	`<wsse:BinarySecurityToken wsu:Id="token">AAAA</wsse:BinarySecurityToken>` +
+	`<saml2:Assertion xmlns:saml2="urn:oasis:names:tc:SAML:2.0:assertion" ID="saml2">` +
+	`<saml2:Subject><wsse:SecurityTokenReference><wsse:Reference URI="#token"/></wsse:SecurityTokenReference></saml2:Subject>` +
+	`</saml2:Assertion>` +
+	`<saml:Assertion xmlns:saml="urn:oasis:names:tc:SAML:1.0:assertion" AssertionID="saml1"/>` +
+	`<wsse:SecurityTokenReference wsu:Id="reference"><wsse:Reference URI="#token"/></wsse:SecurityTokenReference>` +
+	`<wsse:SecurityTokenReference wsu:Id="saml2-key-identifier">` +
+	`<wsse:KeyIdentifier ValueType="http://docs.oasis-open.org/wss/oasis-wss-saml-token-profile-1.1#SAMLID">saml2</wsse:KeyIdentifier>` +
+	`</wsse:SecurityTokenReference>` +
+	`<wsse:SecurityTokenReference wsu:Id="saml1-key-identifier">` +
+	`<wsse:KeyIdentifier ValueType="http://docs.oasis-open.org/wss/oasis-wss-saml-token-profile-1.0#SAMLAssertionID">saml1</wsse:KeyIdentifier>` +
+	`</wsse:SecurityTokenReference>` +
+	`<wsse:SecurityTokenReference wsu:Id="embedded"><wsse:Embedded><e:Token xmlns:e="urn:e">BBBB</e:Token></wsse:Embedded></wsse:SecurityTokenReference>` +
+	`<wsse:SecurityTokenReference wsu:Id="token-identifier">` +
+	`<wsse:KeyIdentifier ValueType="http://docs.oasis-open.org/wss/oasis-wss-saml-token-profile-1.1#SAMLID">token</wsse:KeyIdentifier>` +
+	`</wsse:SecurityTokenReference>` +
+	`<wsse:SecurityTokenReference wsu:Id="missing"><wsse:Reference URI="#missing-token"/></wsse:SecurityTokenReference>` +
+	`<wsse:SecurityTokenReference wsu:Id="external"><wsse:Reference URI="token.xml"/></wsse:SecurityTokenReference>` +
+	`<KeyInfo wsu:Id="nested"><wsse:SecurityTokenReference><wsse:Reference URI="#saml2"/></wsse:SecurityTokenReference></KeyInfo>` +
+	`</wsse:Security></soap:Header>` +
+	`</soap:Envelope>`
+
+func strTransformInput(t *testing.T, id string) *Data {
+	t.Helper()
+	doc := parseTestDocument(t, testSTRDocument)
+	el := findElementById(doc.Root(), id)
+	if el == nil {
+		t.Fatalf("element %s not found", id)
+	}
+	return NewNodeSetData(canonicalizer.NewNodeSet(el))
+}
+
+func Test_STRTransform(t *testing.T) {
+	tests := []struct {
+		name     string
+		id       string
+		expected string
+	}{
+		{
+			name: "Reference",
+			id:   "reference",
+			expected: `<wsse:BinarySecurityToken xmlns:wsse="http://docs.oasis-open.org/wss/2004/01/oasis-200401-wss-wssecurity-secext-1.0.xsd"` +
+				` xmlns:wsu="http://docs.oasis-open.org/wss/2004/01/oasis-200401-wss-wssecurity-utility-1.0.xsd" wsu:Id="token">AAAA</wsse:BinarySecurityToken>`,
+		},
+		{
+			name: "SAMLID",
+			id:   "saml2-key-identifier",
+			expected: `<saml2:Assertion xmlns:saml2="urn:oasis:names:tc:SAML:2.0:assertion" ID="saml2"><saml2:Subject>` +
+				`<wsse:SecurityTokenReference xmlns:wsse="http://docs.oasis-open.org/wss/2004/01/oasis-200401-wss-wssecurity-secext-1.0.xsd">` +
+				`<wsse:Reference URI="#token"></wsse:Reference></wsse:SecurityTokenReference>` +
+				`</saml2:Subject></saml2:Assertion>`,
+		},
+		{
+			name:     "SAMLAssertionID",
+			id:       "saml1-key-identifier",
+			expected: `<saml:Assertion xmlns:saml="urn:oasis:names:tc:SAML:1.0:assertion" AssertionID="saml1"></saml:Assertion>`,
+		},
+		{
+			name:     "Embedded",
+			id:       "embedded",
+			expected: `<e:Token xmlns:e="urn:e">BBBB</e:Token>`,
+		},
+		{
+			// The references in the replaced token itself are not dereferenced
+			name: "Nested",
+			id:   "nested",
+			expected: `<KeyInfo xmlns:wsu="http://docs.oasis-open.org/wss/2004/01/oasis-200401-wss-wssecurity-utility-1.0.xsd" wsu:Id="nested">` +
+				`<saml2:Assertion xmlns:saml2="urn:oasis:names:tc:SAML:2.0:assertion" ID="saml2"><saml2:Subject>` +
+				`<wsse:SecurityTokenReference xmlns:wsse="http://docs.oasis-open.org/wss/2004/01/oasis-200401-wss-wssecurity-secext-1.0.xsd">` +
+				`<wsse:Reference URI="#token"></wsse:Reference></wsse:SecurityTokenReference>` +
+				`</saml2:Subject></saml2:Assertion>` +
+				`</KeyInfo>`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			transform := NewSTRTransformWithCanonicalizer(canonicalizer.NewC14N10ExcCanonicalizer())
+			actual := transformToString(t, context.Background(), transform, strTransformInput(t, tt.id))
+			if actual != tt.expected {
+				t.Errorf("expected:\n%s\ngot:\n%s", tt.expected, actual)
+			}
+		})
+	}
+}
+
+func Test_STRTransform_Errors(t *testing.T) {
+	tests := []struct {
+		name string
+		id   string
+	}{
+		{"MissingToken", "missing"},
+		{"ExternalReference", "external"},
+		{"KeyIdentifierNotAssertion", "token-identifier"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			transform := NewSTRTransformWithCanonicalizer(canonicalizer.NewC14N10ExcCanonicalizer())
+			_, err := transform.Transform(context.Background(), strTransformInput(t, tt.id))
+			if err == nil {
+				t.Error("expected the reference not to be dereferenced")
+			}
+		})
+	}
+
+	_, err := NewSTRTransform().Transform(context.Background(), strTransformInput(t, "reference"))
+	if err == nil {
+		t.Error("expected a transform without CanonicalizationMethod to fail")
+	}
+}
+
+func Test_STRTransform_Xml(t *testing.T) {
+	tests := []struct {
+		name string
+		xml  string
+	}{
+		{"NoParameters", `<ds:Transform xmlns:ds="http://www.w3.org/2000/09/xmldsig#"/>`},
+		{
+			"NoCanonicalizationMethod",
+			`<ds:Transform xmlns:ds="http://www.w3.org/2000/09/xmldsig#">` +
+				`<wsse:TransformationParameters xmlns:wsse="http://docs.oasis-open.org/wss/2004/01/oasis-200401-wss-wssecurity-secext-1.0.xsd"/>` +
+				`</ds:Transform>`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := NewSTRTransform().ReadXml(parseTestDocument(t, tt.xml).Root())
+			if err == nil {
+				t.Error("expected the transform to be rejected")
+			}
+		})
+	}
+
+	// The CanonicalizationMethod parameter is written and read again
+	doc := etree.NewDocument()
+	el := doc.CreateElement("ds:Transform")
+	el.CreateAttr("xmlns:ds", xmlDSigNamespaceUri)
+	err := NewSTRTransformWithCanonicalizer(canonicalizer.NewC14N10ExcCanonicalizer()).WriteXml(el)
+	if err != nil {
+		t.Fatal(err)
+	}
+	written, err := doc.WriteToString()
+	if err != nil {
+		t.Fatal(err)
+	}
+	loaded := NewSTRTransform()
+	err = loaded.ReadXml(parseTestDocument(t, written).Root())
+	if err != nil {
+		t.Fatal(err)
+	}
+	actual := transformToString(t, context.Background(), loaded, strTransformInput(t, "embedded"))
+	if actual != `<e:Token xmlns:e="urn:e">BBBB</e:Token>` {
+		t.Errorf("unexpected output of the loaded transform: %s", actual)
+	}
+	err = NewSTRTransform().WriteXml(etree.NewElement("Transform"))
+	if err == nil {
+		t.Error("expected writing a transform without CanonicalizationMethod to fail")
+	}
+}
@@ -0,0 +1,276 @@
+package transform
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"errors"
+	"io"
+	"mime"
+	"mime/quotedprintable"
+	"net/textproto"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/beevik/etree"
+	"github.com/deb-ict/go-xmldsig/canonicalizer"
+)
+
+var (
+	// The MIME headers included by the complete transform, in canonical order
+	swaMimeHeaders []string = []string{
+		"Content-Description",
+		"Content-Disposition",
+		"Content-ID",
+		"Content-Language",
+		"Content-Location",
+		"Content-Type",
+	}
+	swaXmlMediaType  = regexp.MustCompile(`^(text/xml|application/xml|[a-z]+/[^;]*\+xml)$`)
+	swaTextMediaType = regexp.MustCompile(`^text/`)
+)
+
+type swaTransform struct {
+	complete bool
+}
+
+func NewAttachmentContentSignatureTransform() Transform {
+	return &swaTransform{
+		complete: false,
+	}
+}
+
+func NewAttachmentCompleteSignatureTransform() Transform {
+	return &swaTransform{
+		complete: true,
+	}
+}
+
+func (t *swaTransform) GetAlgorithm() string {
+	if t.complete {
+		return AttachmentCompleteSignatureTransform
+	} else {
+		return AttachmentContentSignatureTransform
+	}
+}
+
+func (t *swaTransform) Transform(ctx context.Context, data *Data) (*Data, error) {
+	if data.IsNodeSet() || data.Header() == nil {
+		return nil, errors.New("attachment transform can only be applied to a MIME part")
+	}
+	header := data.Header()
+
+	// The headers are canonicalized here so invalid headers fail the transform,
+	// the content is decoded while the data is digested or transformed
+	var headers bytes.Buffer
+	if t.complete {
+		err := t.writeHeaders(&headers, header)
+		if err != nil {
+			return nil, err
+		}
+	}
+	r, err := data.openReader(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	written := false
+	return newWriterData(func(ctx context.Context, w io.Writer) error {
+		if written {
+			return errOctetStreamRead
+		}
+		written = true
+		_, err := w.Write(headers.Bytes())
+		if err != nil {
+			return err
+		}
+		return t.writeContent(ctx, w, header, r)
+	}), nil
+}
+
+func (t *swaTransform) ReadXml(el *etree.Element) error {
+	return nil
+}
+
+func (t *swaTransform) WriteXml(el *etree.Element) error {
+	return nil
+}
+
+// writeHeaders writes the MIME header canonicalization of the profile
+func (t *swaTransform) writeHeaders(w io.Writer, header textproto.MIMEHeader) error {
+	for _, name := range swaMimeHeaders {
+		values := header.Values(name)
+		if len(values) == 0 {
+			if name == "Content-Type" {
+				_, err := io.WriteString(w, "Content-Type: text/plain;charset=\"us-ascii\"\r\n")
+				if err != nil {
+					return err
+				}
+			}
+			continue
+		}
+
+		value := values[0]
+		switch name {
+		case "Content-Description":
+			decoded, err := new(mime.WordDecoder).DecodeHeader(value)
+			if err == nil {
+				value = decoded
+			}
+			value = collapseWhitespace(value)
+		case "Content-Type", "Content-Disposition":
+			canonical, err := canonicalizeMediaType(value)
+			if err != nil {
+				return err
+			}
+			value = canonical
+		default:
+			value = collapseWhitespace(removeComments(value))
+		}
+		_, err := io.WriteString(w, name+": "+value+"\r\n")
+		if err != nil {
+			return err
+		}
+	}
+	_, err := io.WriteString(w, "\r\n")
+	return err
+}
+
+func (t *swaTransform) writeContent(ctx context.Context, w io.Writer, header textproto.MIMEHeader, r io.Reader) error {
+	// Remove the transfer encoding of the MIME part, the attachment contains the
+	// body as it was transmitted
+	switch strings.ToLower(strings.TrimSpace(header.Get("Content-Transfer-Encoding"))) {
+	case "base64":
+		r = base64.NewDecoder(base64.StdEncoding, &whitespaceFilter{r: r})
+	case "quoted-printable":
+		r = quotedprintable.NewReader(r)
+	}
+
+	mediaType, _, err := mime.ParseMediaType(removeComments(header.Get("Content-Type")))
+	if err != nil {
+		mediaType = ""
+	}
+	switch {
+	case swaXmlMediaType.MatchString(mediaType):
+		// XML content is canonicalized using exclusive c14n without comments
+		doc, err := parseXmlReader(r)
+		if err != nil {
+			return err
+		}
+		return canonicalizeNodeSetTo(ctx, canonicalizer.NewC14N10ExcCanonicalizer(), canonicalizer.NewNodeSet(&doc.Element), w)
+	case swaTextMediaType.MatchString(mediaType):
+		// Text content uses CRLF line endings
+		_, err := io.Copy(&lineEndingWriter{w: w}, r)
+		return err
+	}
+
+	_, err = io.Copy(w, r)
+	return err
+}
+
+func canonicalizeMediaType(value string) (string, error) {
+	mediaType, params, err := mime.ParseMediaType(removeComments(value))
+	if err != nil {
+		return "", err
+	}
+
+	names := make([]string, 0, len(params))
+	for name := range params {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var sb strings.Builder
+	sb.WriteString(mediaType)
+	for _, name := range names {
+		sb.WriteString(";")
+		sb.WriteString(name)
+		sb.WriteString("=\"")
+		sb.WriteString(strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(params[name]))
+		sb.WriteString("\"")
+	}
+	return sb.String(), nil
+}
+
+// removeComments removes RFC 822 comments outside quoted strings
+func removeComments(value string) string {
+	var sb strings.Builder
+	depth := 0
+	quoted := false
+	escaped := false
+	for _, r := range value {
+		switch {
+		case escaped:
+			escaped = false
+		case r == '\\':
+			escaped = true
+		case r == '"' && depth == 0:
+			quoted = !quoted
+		case r == '(' && !quoted:
+			depth++
+			continue
+		case r == ')' && !quoted && depth > 0:
+			depth--
+			continue
+		}
+		if depth == 0 {
+			sb.WriteRune(r)
+		}
+	}
+	return sb.String()
+}
+
+func collapseWhitespace(value string) string {
+	return strings.Join(strings.Fields(value), " ")
+}
+
+// whitespaceFilter removes the whitespace of base64 encoded content
+type whitespaceFilter struct {
+	r io.Reader
+}
+
+func (f *whitespaceFilter) Read(p []byte) (int, error) {
+	for {
+		n, err := f.r.Read(p)
+		filtered := 0
+		for _, b := range p[:n] {
+			if b != ' ' && b != '\t' && b != '\r' && b != '\n' {
+				p[filtered] = b
+				filtered++
+			}
+		}
+		if filtered > 0 || err != nil {
+			return filtered, err
+		}
+	}
+}
+
+// lineEndingWriter converts CR, LF and CRLF line endings to CRLF, a line ending
+// can be split over writes.
+type lineEndingWriter struct {
+	w      io.Writer
+	cr     bool
+	buffer []byte
+}
+
+func (lw *lineEndingWriter) Write(p []byte) (int, error) {
+	buffer := lw.buffer[:0]
+	for _, b := range p {
+		switch {
+		case b == '\n' && lw.cr:
+			// The line ending was written for the CR
+		case b == '\r' || b == '\n':
+			buffer = append(buffer, '\r', '\n')
+		default:
+			buffer = append(buffer, b)
+		}
+		lw.cr = b == '\r'
+	}
+	lw.buffer = buffer
+	_, err := lw.w.Write(buffer)
+	if err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
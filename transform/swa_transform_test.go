@@ -0,0 +1,207 @@
+package transform
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/textproto"
+	"strings"
+	"testing"
+	"testing/iotest"
+)
+
+type failingWriter struct {
+	remaining int
+}
+
+func (w *failingWriter) Write(p []byte) (int, error) {
+	if len(p) > w.remaining {
+		n := w.remaining
+		w.remaining = 0
+		return n, errors.New("write failed")
+	}
+	w.remaining -= len(p)
+	return len(p), nil
+}
+
+// trackingReader records whether it was read and the writer it was copied to
+type trackingReader struct {
+	r         io.Reader
+	read      bool
+	writtenTo io.Writer
+}
+
+func (tr *trackingReader) Read(p []byte) (int, error) {
+	tr.read = true
+	return tr.r.Read(p)
+}
+
+func (tr *trackingReader) WriteTo(w io.Writer) (int64, error) {
+	tr.read = true
+	tr.writtenTo = w
+	return io.Copy(w, tr.r)
+}
+
+func Test_SwaTransform(t *testing.T) {
+	tests := []struct {
+		name      string
+		transform Transform
+		header    textproto.MIMEHeader
+		body      string
+		expected  string
+	}{
+		{
+			name:      "ContentBase64",
+			transform: NewAttachmentContentSignatureTransform(),
+			header:    textproto.MIMEHeader{"Content-Type": {"application/octet-stream"}, "Content-Transfer-Encoding": {"base64"}},
+			body:      "aGVs\r\nbG8=",
+			expected:  "hello",
+		},
+		{
+			name:      "ContentQuotedPrintable",
+			transform: NewAttachmentContentSignatureTransform(),
+			header:    textproto.MIMEHeader{"Content-Type": {"application/octet-stream"}, "Content-Transfer-Encoding": {"quoted-printable"}},
+			body:      "caf=C3=A9",
+			expected:  "café",
+		},
+		{
+			// Without a transfer encoding the body is not decoded again
+			name:      "ContentDecoded",
+			transform: NewAttachmentContentSignatureTransform(),
+			header:    textproto.MIMEHeader{"Content-Type": {"application/octet-stream"}},
+			body:      "aGVsbG8=",
+			expected:  "aGVsbG8=",
+		},
+		{
+			name:      "ContentText",
+			transform: NewAttachmentContentSignatureTransform(),
+			header:    textproto.MIMEHeader{"Content-Type": {"text/plain"}},
+			body:      "a\nb\rc\r\n",
+			expected:  "a\r\nb\r\nc\r\n",
+		},
+		{
+			name:      "ContentXml",
+			transform: NewAttachmentContentSignatureTransform(),
+			header:    textproto.MIMEHeader{"Content-Type": {"application/xml"}},
+			body:      `<?xml version="1.0"?><a  b='1'><!--c--><x/></a>`,
+			expected:  `<a b="1"><x></x></a>`,
+		},
+		{
+			// Processing instructions outside the document element are canonicalized as well
+			name:      "ContentXmlProcInst",
+			transform: NewAttachmentContentSignatureTransform(),
+			header:    textproto.MIMEHeader{"Content-Type": {"text/xml"}},
+			body:      "<?pi x?>\n<a/>\n<!--c-->",
+			expected:  "<?pi x?>\n<a></a>",
+		},
+		{
+			name:      "Complete",
+			transform: NewAttachmentCompleteSignatureTransform(),
+			header: textproto.MIMEHeader{
+				"Content-Type":              {`text/plain; (comment) charset=UTF-8`},
+				"Content-Id":                {"<part1@example.com>"},
+				"Content-Description":       {"  A   description "},
+				"Content-Transfer-Encoding": {"base64"},
+				"X-Other":                   {"ignored"},
+			},
+			body:     "dGV4dA==",
+			expected: "Content-Description: A description\r\nContent-ID: <part1@example.com>\r\nContent-Type: text/plain;charset=\"UTF-8\"\r\n\r\ntext",
+		},
+		{
+			name:      "CompleteDefaultContentType",
+			transform: NewAttachmentCompleteSignatureTransform(),
+			header:    textproto.MIMEHeader{},
+			body:      "text",
+			expected:  "Content-Type: text/plain;charset=\"us-ascii\"\r\n\r\ntext",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			actual := transformToString(t, context.Background(), tt.transform, NewAttachmentData(tt.header, []byte(tt.body)))
+			if actual != tt.expected {
+				t.Errorf("expected %q, got %q", tt.expected, actual)
+			}
+
+			// The content is decoded while it is read, one byte at a time
+			actual = transformToString(t, context.Background(), tt.transform, NewAttachmentStreamData(tt.header, iotest.OneByteReader(strings.NewReader(tt.body))))
+			if actual != tt.expected {
+				t.Errorf("expected %q from a stream, got %q", tt.expected, actual)
+			}
+		})
+	}
+}
+
+func Test_SwaTransform_Streams(t *testing.T) {
+	ctx := context.Background()
+	header := textproto.MIMEHeader{"Content-Type": {"application/octet-stream"}}
+	reader := &trackingReader{r: strings.NewReader("content")}
+
+	transformed, err := NewAttachmentCompleteSignatureTransform().Transform(ctx, NewAttachmentStreamData(header, reader))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reader.read {
+		t.Error("expected the content not to be read by the transform")
+	}
+
+	// The content is copied to the writer of the digest without buffering it
+	var w strings.Builder
+	err = transformed.WriteOctets(ctx, &w)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reader.writtenTo != &w {
+		t.Errorf("expected the content to be copied to the writer, got %T", reader.writtenTo)
+	}
+	expected := "Content-Type: application/octet-stream\r\n\r\ncontent"
+	if w.String() != expected {
+		t.Errorf("expected %q, got %q", expected, w.String())
+	}
+
+	err = transformed.WriteOctets(ctx, io.Discard)
+	if err == nil {
+		t.Error("expected the stream to be written once")
+	}
+}
+
+func Test_SwaTransform_InvalidHeader(t *testing.T) {
+	header := textproto.MIMEHeader{"Content-Type": {"text/plain; charset"}}
+	_, err := NewAttachmentCompleteSignatureTransform().Transform(context.Background(), NewAttachmentData(header, []byte("text")))
+	if err == nil {
+		t.Error("expected an invalid content type to fail the transform")
+	}
+}
+
+func Test_LineEndingWriter(t *testing.T) {
+	var buffer bytes.Buffer
+	w := &lineEndingWriter{w: &buffer}
+	for _, chunk := range []string{"a\r", "\nb\r", "\r", "\n", "c\n"} {
+		_, err := io.WriteString(w, chunk)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	expected := "a\r\nb\r\n\r\nc\r\n"
+	if buffer.String() != expected {
+		t.Errorf("expected %q, got %q", expected, buffer.String())
+	}
+}
+
+func Test_SwaTransform_RequiresMimePart(t *testing.T) {
+	_, err := NewAttachmentContentSignatureTransform().Transform(context.Background(), NewOctetData([]byte("data")))
+	if err == nil {
+		t.Error("expected an octet stream without headers to be rejected")
+	}
+}
+
+func Test_SwaTransform_WriteHeadersError(t *testing.T) {
+	header := textproto.MIMEHeader{"Content-Type": {"text/plain"}, "Content-Id": {"<part1>"}}
+	transform := &swaTransform{complete: true}
+	for _, remaining := range []int{0, 10, 40, 47} {
+		err := transform.writeHeaders(&failingWriter{remaining: remaining}, header)
+		if err == nil || !strings.Contains(err.Error(), "write failed") {
+			t.Errorf("expected the write error after %d bytes, got %v", remaining, err)
+		}
+	}
+}
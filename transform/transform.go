@@ -3,6 +3,8 @@ package transform
 import (
 	"context"
 	"errors"
+	"io"
+	"sync"
 
 	"github.com/beevik/etree"
 	"github.com/deb-ict/go-xmldsig/canonicalizer"
@@ -12,35 +14,94 @@ type CreateTransform func() Transform
 
 const (
 	EnvelopedSignatureTransform string = "http://www.w3.org/2000/09/xmldsig#enveloped-signature"
+	XPathTransform              string = "http://www.w3.org/TR/1999/REC-xpath-19991116"
+	XPathFilter2Transform       string = "http://www.w3.org/2002/06/xmldsig-filter2"
+	Base64Transform             string = "http://www.w3.org/2000/09/xmldsig#base64"
+	XsltTransform               string = "http://www.w3.org/TR/1999/REC-xslt-19991116"
+	DecryptXmlTransform         string = "http://www.w3.org/2002/07/decrypt#XML"
+	RelationshipTransform       string = "http://schemas.openxmlformats.org/package/2006/RelationshipTransform"
+
+	AttachmentContentSignatureTransform  string = "http://docs.oasis-open.org/wss/oasis-wss-SwAProfile-1.1#Attachment-Content-Signature-Transform"
+	AttachmentCompleteSignatureTransform string = "http://docs.oasis-open.org/wss/oasis-wss-SwAProfile-1.1#Attachment-Complete-Signature-Transform"
+	STRTransform                         string = "http://docs.oasis-open.org/wss/2004/01/oasis-200401-wss-soap-message-security-1.0#STR-Transform"
 )
 
 var (
 	registeredTransforms map[string]CreateTransform = map[string]CreateTransform{
 		EnvelopedSignatureTransform:                     NewEnvelopedSignatureTransform,
+		XPathTransform:                                  NewXPathTransform,
+		XPathFilter2Transform:                           NewXPathFilter2Transform,
+		Base64Transform:                                 NewBase64Transform,
+		XsltTransform:                                   NewXsltTransform,
+		DecryptXmlTransform:                             NewDecryptTransform,
+		RelationshipTransform:                           NewRelationshipTransform,
+		AttachmentContentSignatureTransform:             NewAttachmentContentSignatureTransform,
+		AttachmentCompleteSignatureTransform:            NewAttachmentCompleteSignatureTransform,
+		STRTransform:                                    NewSTRTransform,
 		canonicalizer.C14N10RecNamespaceUri:             NewC14N10RecTransform,
 		canonicalizer.C14N10RecWithCommentsNamespaceUri: NewC14N10RecWithCommentsTransform,
 		canonicalizer.C14N10ExcNamespaceUri:             NewC14N10ExcTransform,
 		canonicalizer.C14N10ExcWithCommentsNamespaceUri: NewC14N10ExcWithCommentsTransform,
 		canonicalizer.C14N11NamespaceUri:                NewC14N11Transform,
 		canonicalizer.C14N11WithCommentsNamespaceUri:    NewC14N11WithCommentsTransform,
+		canonicalizer.C14N20NamespaceUri:                NewC14N20Transform,
 	}
+	registeredTransformsLock sync.RWMutex
 )
 
 type Transform interface {
 	GetAlgorithm() string
-	TransformXmlElement(ctx context.Context, el *etree.Element) ([]byte, error)
-	TransformData(ctx context.Context, data []byte) ([]byte, error)
+	Transform(ctx context.Context, data *Data) (*Data, error)
 	ReadXml(el *etree.Element) error
 	WriteXml(el *etree.Element) error
 }
 
 func RegisterTransform(uri string, method CreateTransform) {
+	registeredTransformsLock.Lock()
+	defer registeredTransformsLock.Unlock()
 	registeredTransforms[uri] = method
 }
 
 func GetTransform(uri string) (Transform, error) {
-	if method, ok := registeredTransforms[uri]; ok {
+	registeredTransformsLock.RLock()
+	method, ok := registeredTransforms[uri]
+	registeredTransformsLock.RUnlock()
+	if ok {
 		return method(), nil
 	}
 	return nil, errors.New("transform not found")
 }
+
+func canonicalizeData(ctx context.Context, can canonicalizer.Canonicalizer, data *Data) (*Data, error) {
+	nodeSet, err := data.NodeSet(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return newCanonicalizedData(can, nodeSet), nil
+}
+
+// canonicalizeNodeSetTo falls back to the element subtree of the node-set for
+// canonicalizers that cannot process a document subset.
+func canonicalizeNodeSetTo(ctx context.Context, can canonicalizer.Canonicalizer, nodeSet *canonicalizer.NodeSet, w io.Writer) error {
+	if streamCanonicalizer, ok := can.(canonicalizer.StreamCanonicalizer); ok {
+		return streamCanonicalizer.CanonicalizeNodeSetTo(ctx, nodeSet, w)
+	}
+
+	var canonicalized []byte
+	var err error
+	if nodeSetCanonicalizer, ok := can.(canonicalizer.NodeSetCanonicalizer); ok {
+		canonicalized, err = nodeSetCanonicalizer.CanonicalizeNodeSet(ctx, nodeSet)
+	} else {
+		var el *etree.Element
+		el, err = nodeSet.Element()
+		if err != nil {
+			return err
+		}
+		canonicalized, err = can.Canonicalize(ctx, el)
+	}
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(canonicalized)
+	return err
+}
@@ -0,0 +1,228 @@
+package transform
+
+import (
+	"context"
+	"errors"
+	"sort"
+
+	"github.com/beevik/etree"
+	"github.com/deb-ict/go-xmldsig/internal/xpath"
+)
+
+const (
+	XPathFilter2Intersect string = "intersect"
+	XPathFilter2Subtract  string = "subtract"
+	XPathFilter2Union     string = "union"
+)
+
+type XPathFilter2 struct {
+	Filter     string
+	XPath      string
+	Namespaces map[string]string
+	expression *xpath.Expr
+	namespaces map[string]string
+	here       *xpath.Node
+}
+
+type xpathFilter2Transform struct {
+	filters []*XPathFilter2
+}
+
+func NewXPathFilter2Transform() Transform {
+	return &xpathFilter2Transform{}
+}
+
+func NewXPathFilter2TransformWithFilters(filters ...*XPathFilter2) Transform {
+	return &xpathFilter2Transform{
+		filters: filters,
+	}
+}
+
+func (t *xpathFilter2Transform) GetAlgorithm() string {
+	return XPathFilter2Transform
+}
+
+func (t *xpathFilter2Transform) Transform(ctx context.Context, data *Data) (*Data, error) {
+	if len(t.filters) == 0 {
+		return nil, errors.New("xpath filter 2.0 transform does not contain an XPath element")
+	}
+	nodeSet, err := data.NodeSet(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// The expressions are evaluated with the document root node as context node
+	root := nodeSet.Root()
+	for root.Parent() != nil {
+		root = root.Parent()
+	}
+	rootNode, _ := xpath.NewTokenNode(root)
+
+	subtrees := make([]*xpathSubtrees, 0, len(t.filters))
+	for _, filter := range t.filters {
+		err := filter.compile()
+		if err != nil {
+			return nil, err
+		}
+		xpathContext := &xpath.Context{
+			Namespaces: filter.Namespaces,
+			Here:       filter.here,
+		}
+		if filter.namespaces != nil {
+			xpathContext.Namespaces = filter.namespaces
+		}
+		nodes, err := filter.expression.EvaluateNodeSet(xpathContext, rootNode)
+		if err != nil {
+			return nil, err
+		}
+		subtrees = append(subtrees, newXPathSubtrees(filter.Filter, nodes))
+	}
+
+	result := nodeSet.Filter(func(token etree.Token) bool {
+		return t.contains(subtrees, func(s *xpathSubtrees) bool {
+			return s.containsToken(token)
+		})
+	}).FilterAttrs(func(el *etree.Element, attr *etree.Attr) bool {
+		return t.contains(subtrees, func(s *xpathSubtrees) bool {
+			return s.attrs[attr] || s.containsToken(el)
+		})
+	}).FilterNamespaces(func(el *etree.Element, prefix string) bool {
+		return t.contains(subtrees, func(s *xpathSubtrees) bool {
+			return s.containsToken(el)
+		})
+	})
+	return NewNodeSetData(result), nil
+}
+
+func (t *xpathFilter2Transform) ReadXml(el *etree.Element) error {
+	t.filters = make([]*XPathFilter2, 0)
+	for _, xpathElement := range el.SelectElements("XPath") {
+		if xpathElement.NamespaceURI() != XPathFilter2Transform {
+			continue
+		}
+
+		// Prefixes are bound by the namespaces in scope of the XPath element
+		filter := &XPathFilter2{
+			Filter:     xpathElement.SelectAttrValue("Filter", ""),
+			XPath:      xpathElement.Text(),
+			Namespaces: map[string]string{},
+			namespaces: xpath.InScopeNamespaces(xpathElement),
+		}
+		for _, attr := range xpathElement.Attr {
+			if attr.Space == "xmlns" && attr.Key != "dsig-xpath" {
+				filter.Namespaces[attr.Key] = attr.Value
+			}
+		}
+		for _, child := range xpathElement.Child {
+			if _, ok := child.(*etree.CharData); ok {
+				here, _ := xpath.NewTokenNode(child)
+				filter.here = &here
+				break
+			}
+		}
+		err := filter.compile()
+		if err != nil {
+			return err
+		}
+		t.filters = append(t.filters, filter)
+	}
+
+	if len(t.filters) == 0 {
+		return errors.New("xpath filter 2.0 transform does not contain an XPath element")
+	}
+	return nil
+}
+
+func (t *xpathFilter2Transform) WriteXml(el *etree.Element) error {
+	for _, filter := range t.filters {
+		xpathElement := el.CreateElement("XPath")
+		xpathElement.Space = "dsig-xpath"
+		xpathElement.CreateAttr("xmlns:dsig-xpath", XPathFilter2Transform)
+		xpathElement.CreateAttr("Filter", filter.Filter)
+
+		prefixes := make([]string, 0, len(filter.Namespaces))
+		for prefix := range filter.Namespaces {
+			if prefix != "" && prefix != "dsig-xpath" {
+				prefixes = append(prefixes, prefix)
+			}
+		}
+		sort.Strings(prefixes)
+		for _, prefix := range prefixes {
+			xpathElement.CreateAttr("xmlns:"+prefix, filter.Namespaces[prefix])
+		}
+		xpathElement.SetText(filter.XPath)
+	}
+	return nil
+}
+
+func (t *xpathFilter2Transform) contains(subtrees []*xpathSubtrees, contains func(s *xpathSubtrees) bool) bool {
+	// The filter node-set starts with all nodes of the document
+	result := true
+	for _, s := range subtrees {
+		switch s.filter {
+		case XPathFilter2Intersect:
+			result = result && contains(s)
+		case XPathFilter2Subtract:
+			result = result && !contains(s)
+		case XPathFilter2Union:
+			result = result || contains(s)
+		}
+	}
+	return result
+}
+
+func (filter *XPathFilter2) compile() error {
+	switch filter.Filter {
+	case XPathFilter2Intersect, XPathFilter2Subtract, XPathFilter2Union:
+	default:
+		return errors.New("xpath filter 2.0 transform contains an invalid filter: " + filter.Filter)
+	}
+	if filter.expression != nil {
+		return nil
+	}
+	expression, err := xpath.Compile(filter.XPath)
+	if err != nil {
+		return err
+	}
+	filter.expression = expression
+	return nil
+}
+
+// xpathSubtrees is the node-set selected by a filter, expanded with the
+// descendants of the selected nodes.
+type xpathSubtrees struct {
+	filter string
+	tokens map[etree.Token]bool
+	attrs  map[*etree.Attr]bool
+}
+
+func newXPathSubtrees(filter string, nodes []xpath.Node) *xpathSubtrees {
+	s := &xpathSubtrees{
+		filter: filter,
+		tokens: map[etree.Token]bool{},
+		attrs:  map[*etree.Attr]bool{},
+	}
+	for _, node := range nodes {
+		switch node.Type {
+		case xpath.AttributeNode:
+			s.attrs[node.Attr] = true
+		case xpath.NamespaceNode:
+			continue
+		default:
+			s.tokens[node.Token] = true
+		}
+	}
+	return s
+}
+
+func (s *xpathSubtrees) containsToken(token etree.Token) bool {
+	if s.tokens[token] {
+		return true
+	}
+	for parent := token.Parent(); parent != nil; parent = parent.Parent() {
+		if s.tokens[parent] {
+			return true
+		}
+	}
+	return false
+}
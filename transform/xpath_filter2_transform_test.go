@@ -0,0 +1,142 @@
+package transform
+
+import (
+	"context"
+	"testing"
+
+	"github.com/deb-ict/go-xmldsig/canonicalizer"
+)
+
+const testXPathFilter2Document = `<Document>` +
+	`<ToBeSigned><!--c--><Data/><NotToBeSigned><ReallyToBeSigned><!--c--><Data/></ReallyToBeSigned></NotToBeSigned></ToBeSigned>` +
+	`<ToBeSigned><Data/><NotToBeSigned><Data/></NotToBeSigned></ToBeSigned>` +
+	`</Document>`
+
+func Test_XPathFilter2Transform(t *testing.T) {
+	tests := []struct {
+		name     string
+		filters  []*XPathFilter2
+		expected string
+	}{
+		{
+			name:     "Intersect",
+			filters:  []*XPathFilter2{{Filter: XPathFilter2Intersect, XPath: "//ToBeSigned"}},
+			expected: `<ToBeSigned><Data></Data><NotToBeSigned><ReallyToBeSigned><Data></Data></ReallyToBeSigned></NotToBeSigned></ToBeSigned><ToBeSigned><Data></Data><NotToBeSigned><Data></Data></NotToBeSigned></ToBeSigned>`,
+		},
+		{
+			name:     "Subtract",
+			filters:  []*XPathFilter2{{Filter: XPathFilter2Subtract, XPath: "//NotToBeSigned"}},
+			expected: `<Document><ToBeSigned><Data></Data></ToBeSigned><ToBeSigned><Data></Data></ToBeSigned></Document>`,
+		},
+		{
+			name: "Union",
+			filters: []*XPathFilter2{
+				{Filter: XPathFilter2Subtract, XPath: "//ToBeSigned"},
+				{Filter: XPathFilter2Union, XPath: "//Data"},
+			},
+			expected: `<Document><Data></Data><Data></Data><Data></Data><Data></Data></Document>`,
+		},
+		{
+			// The example of the XPath Filter 2.0 specification
+			name: "Chained",
+			filters: []*XPathFilter2{
+				{Filter: XPathFilter2Intersect, XPath: "//ToBeSigned"},
+				{Filter: XPathFilter2Subtract, XPath: "//NotToBeSigned"},
+				{Filter: XPathFilter2Union, XPath: "//ReallyToBeSigned"},
+			},
+			expected: `<ToBeSigned><Data></Data><ReallyToBeSigned><Data></Data></ReallyToBeSigned></ToBeSigned><ToBeSigned><Data></Data></ToBeSigned>`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc := parseTestDocument(t, testXPathFilter2Document)
+			nodeSet := canonicalizer.NewNodeSet(&doc.Element).WithoutComments()
+			actual := transformToString(t, context.Background(), NewXPathFilter2TransformWithFilters(tt.filters...), NewNodeSetData(nodeSet))
+			if actual != tt.expected {
+				t.Errorf("expected %s, got %s", tt.expected, actual)
+			}
+		})
+	}
+}
+
+func Test_XPathFilter2Transform_ChainedTransforms(t *testing.T) {
+	// Filters in consecutive transforms apply to the node-set of the previous one
+	ctx := context.Background()
+	doc := parseTestDocument(t, testXPathFilter2Document)
+	data := NewNodeSetData(canonicalizer.NewNodeSet(&doc.Element).WithoutComments())
+	data, err := NewXPathFilter2TransformWithFilters(&XPathFilter2{Filter: XPathFilter2Intersect, XPath: "//ToBeSigned"}).Transform(ctx, data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err = NewXPathFilter2TransformWithFilters(&XPathFilter2{Filter: XPathFilter2Subtract, XPath: "//NotToBeSigned"}).Transform(ctx, data)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// A union does not add nodes that a previous transform removed
+	actual := transformToString(t, ctx, NewXPathFilter2TransformWithFilters(&XPathFilter2{Filter: XPathFilter2Union, XPath: "//ReallyToBeSigned"}), data)
+	expected := `<ToBeSigned><Data></Data></ToBeSigned><ToBeSigned><Data></Data></ToBeSigned>`
+	if actual != expected {
+		t.Errorf("expected %s, got %s", expected, actual)
+	}
+}
+
+func Test_XPathFilter2Transform_Xml(t *testing.T) {
+	transform := NewXPathFilter2TransformWithFilters(
+		&XPathFilter2{Filter: XPathFilter2Intersect, XPath: "//t:ToBeSigned", Namespaces: map[string]string{"t": "urn:t"}},
+		&XPathFilter2{Filter: XPathFilter2Subtract, XPath: "//t:NotToBeSigned", Namespaces: map[string]string{"t": "urn:t"}},
+	)
+	doc := parseTestDocument(t, `<ds:Transform xmlns:ds="http://www.w3.org/2000/09/xmldsig#" Algorithm="http://www.w3.org/2002/06/xmldsig-filter2"/>`)
+	err := transform.WriteXml(doc.Root())
+	if err != nil {
+		t.Fatal(err)
+	}
+	written, err := doc.WriteToString()
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := `<ds:Transform xmlns:ds="http://www.w3.org/2000/09/xmldsig#" Algorithm="http://www.w3.org/2002/06/xmldsig-filter2">` +
+		`<dsig-xpath:XPath xmlns:dsig-xpath="http://www.w3.org/2002/06/xmldsig-filter2" Filter="intersect" xmlns:t="urn:t">//t:ToBeSigned</dsig-xpath:XPath>` +
+		`<dsig-xpath:XPath xmlns:dsig-xpath="http://www.w3.org/2002/06/xmldsig-filter2" Filter="subtract" xmlns:t="urn:t">//t:NotToBeSigned</dsig-xpath:XPath>` +
+		`</ds:Transform>`
+	if written != expected {
+		t.Errorf("expected:\n%s\ngot:\n%s", expected, written)
+	}
+
+	// The transform that is read again selects the same nodes
+	loaded := NewXPathFilter2Transform()
+	err = loaded.ReadXml(parseTestDocument(t, written).Root())
+	if err != nil {
+		t.Fatal(err)
+	}
+	input := parseTestDocument(t, `<Document xmlns="urn:t"><ToBeSigned><Data/><NotToBeSigned/></ToBeSigned><Other/></Document>`)
+	actual := transformToString(t, context.Background(), loaded, NewNodeSetData(canonicalizer.NewNodeSet(&input.Element)))
+	if actual != `<ToBeSigned xmlns="urn:t"><Data></Data></ToBeSigned>` {
+		t.Errorf("unexpected output of the loaded transform: %s", actual)
+	}
+}
+
+func Test_XPathFilter2Transform_Errors(t *testing.T) {
+	tests := []struct {
+		name string
+		xml  string
+	}{
+		{"NoXPath", `<ds:Transform xmlns:ds="http://www.w3.org/2000/09/xmldsig#"/>`},
+		{"OtherNamespace", `<ds:Transform xmlns:ds="http://www.w3.org/2000/09/xmldsig#"><ds:XPath Filter="intersect">/</ds:XPath></ds:Transform>`},
+		{"InvalidFilter", `<ds:Transform xmlns:ds="http://www.w3.org/2000/09/xmldsig#"><f:XPath xmlns:f="http://www.w3.org/2002/06/xmldsig-filter2" Filter="except">/</f:XPath></ds:Transform>`},
+		{"InvalidExpression", `<ds:Transform xmlns:ds="http://www.w3.org/2000/09/xmldsig#"><f:XPath xmlns:f="http://www.w3.org/2002/06/xmldsig-filter2" Filter="union">//[</f:XPath></ds:Transform>`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := NewXPathFilter2Transform().ReadXml(parseTestDocument(t, tt.xml).Root())
+			if err == nil {
+				t.Error("expected the transform to be rejected")
+			}
+		})
+	}
+
+	_, err := NewXPathFilter2Transform().Transform(context.Background(), NewOctetData([]byte("<a/>")))
+	if err == nil {
+		t.Error("expected a transform without filters to fail")
+	}
+}
@@ -0,0 +1,155 @@
+package transform
+
+import (
+	"context"
+	"errors"
+
+	"github.com/beevik/etree"
+	"github.com/deb-ict/go-xmldsig/canonicalizer"
+	"github.com/deb-ict/go-xmldsig/internal/xpath"
+)
+
+type xpathTransform struct {
+	expression *xpath.Expr
+	namespaces map[string]string
+	here       *xpath.Node
+}
+
+func NewXPathTransform() Transform {
+	return &xpathTransform{}
+}
+
+func (t *xpathTransform) GetAlgorithm() string {
+	return XPathTransform
+}
+
+func (t *xpathTransform) Transform(ctx context.Context, data *Data) (*Data, error) {
+	if t.expression == nil {
+		return nil, errors.New("xpath transform does not contain an XPath expression")
+	}
+	nodeSet, err := data.NodeSet(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// Evaluate the expression for every node of the input node-set
+	xpathContext := &xpath.Context{
+		Namespaces: t.namespaces,
+		Here:       t.here,
+	}
+	tokens := map[etree.Token]bool{}
+	attrs := map[*etree.Attr]bool{}
+	namespaces := map[*etree.Element]map[string]bool{}
+	err = t.evaluate(xpathContext, nodeSet, nodeSet.Root(), tokens, attrs, namespaces)
+	if err != nil {
+		return nil, err
+	}
+
+	result := nodeSet.Filter(func(token etree.Token) bool {
+		return tokens[token]
+	}).FilterAttrs(func(el *etree.Element, attr *etree.Attr) bool {
+		return attrs[attr]
+	}).FilterNamespaces(func(el *etree.Element, prefix string) bool {
+		return namespaces[el][prefix]
+	})
+	return NewNodeSetData(result), nil
+}
+
+func (t *xpathTransform) ReadXml(el *etree.Element) error {
+	xpathElements := make([]*etree.Element, 0)
+	for _, xpathElement := range el.SelectElements("XPath") {
+		if xpathElement.NamespaceURI() == xmlDSigNamespaceUri {
+			xpathElements = append(xpathElements, xpathElement)
+		}
+	}
+	if len(xpathElements) != 1 {
+		return errors.New("xpath transform does not contain a single XPath element")
+	}
+	xpathElement := xpathElements[0]
+
+	expression, err := xpath.Compile(xpathElement.Text())
+	if err != nil {
+		return err
+	}
+	t.expression = expression
+
+	// Prefixes are bound by the namespaces in scope of the XPath element
+	t.namespaces = xpath.InScopeNamespaces(xpathElement)
+	t.here = nil
+	for _, child := range xpathElement.Child {
+		if _, ok := child.(*etree.CharData); ok {
+			here, _ := xpath.NewTokenNode(child)
+			t.here = &here
+			break
+		}
+	}
+
+	return nil
+}
+
+func (t *xpathTransform) WriteXml(el *etree.Element) error {
+	// The XPath element is written by the Transform element
+	return nil
+}
+
+func (t *xpathTransform) evaluate(ctx *xpath.Context, nodeSet *canonicalizer.NodeSet, el *etree.Element, tokens map[etree.Token]bool, attrs map[*etree.Attr]bool, namespaces map[*etree.Element]map[string]bool) error {
+	if nodeSet.ContainsToken(el) {
+		included, err := t.evaluateNode(ctx, el)
+		if err != nil {
+			return err
+		}
+		tokens[el] = included
+	}
+
+	namespaces[el] = map[string]bool{}
+	for prefix, uri := range xpath.InScopeNamespaces(el) {
+		if !nodeSet.ContainsNamespace(el, prefix) {
+			continue
+		}
+		included, err := t.expression.EvaluateBoolean(ctx, xpath.NewNamespaceNode(el, prefix, uri))
+		if err != nil {
+			return err
+		}
+		namespaces[el][prefix] = included
+	}
+
+	for i := range el.Attr {
+		attr := &el.Attr[i]
+		if xpath.IsNamespaceAttr(attr) || !nodeSet.ContainsAttr(el, attr) {
+			continue
+		}
+		included, err := t.expression.EvaluateBoolean(ctx, xpath.NewAttrNode(el, attr))
+		if err != nil {
+			return err
+		}
+		attrs[attr] = included
+	}
+
+	for _, child := range el.Child {
+		if childElement, ok := child.(*etree.Element); ok {
+			err := t.evaluate(ctx, nodeSet, childElement, tokens, attrs, namespaces)
+			if err != nil {
+				return err
+			}
+			continue
+		}
+		if !nodeSet.ContainsToken(child) {
+			continue
+		}
+		included, err := t.evaluateNode(ctx, child)
+		if err != nil {
+			return err
+		}
+		tokens[child] = included
+	}
+
+	return nil
+}
+
+func (t *xpathTransform) evaluateNode(ctx *xpath.Context, token etree.Token) (bool, error) {
+	node, ok := xpath.NewTokenNode(token)
+	if !ok {
+		return false, nil
+	}
+	return t.expression.EvaluateBoolean(ctx, node)
+}
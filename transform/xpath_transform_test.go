@@ -0,0 +1,55 @@
+package transform
+
+import (
+	"context"
+	"testing"
+
+	"github.com/deb-ict/go-xmldsig/canonicalizer"
+)
+
+func Test_XPathTransform(t *testing.T) {
+	doc := parseTestDocument(t, `<root xmlns:p="urn:p"><p:keep a="1">text</p:keep><drop/><!--comment--></root>`)
+	transformDoc := parseTestDocument(t, `<ds:Transform xmlns:ds="http://www.w3.org/2000/09/xmldsig#" Algorithm="http://www.w3.org/TR/1999/REC-xpath-19991116">`+
+		`<ds:XPath xmlns:q="urn:p">not(self::drop) and not(parent::q:keep and name() = 'a')</ds:XPath>`+
+		`</ds:Transform>`)
+
+	transform := NewXPathTransform()
+	err := transform.ReadXml(transformDoc.Root())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The prefix of the expression is bound by the XPath element, comments are
+	// removed when the node-set is converted to octets
+	expected := `<root xmlns:p="urn:p"><p:keep>text</p:keep></root>`
+	actual := transformToString(t, context.Background(), transform, NewNodeSetData(canonicalizer.NewNodeSet(doc.Root())))
+	if actual != expected {
+		t.Errorf("expected %s, got %s", expected, actual)
+	}
+}
+
+func Test_XPathTransform_XPathNamespace(t *testing.T) {
+	tests := []struct {
+		name  string
+		xml   string
+		fails bool
+	}{
+		{"DSig", `<ds:Transform xmlns:ds="http://www.w3.org/2000/09/xmldsig#"><ds:XPath>true()</ds:XPath></ds:Transform>`, false},
+		{"OtherNamespace", `<ds:Transform xmlns:ds="http://www.w3.org/2000/09/xmldsig#"><XPath xmlns="urn:other">true()</XPath></ds:Transform>`, true},
+		{"Unqualified", `<ds:Transform xmlns:ds="http://www.w3.org/2000/09/xmldsig#"><XPath>true()</XPath></ds:Transform>`, true},
+		{"Multiple", `<ds:Transform xmlns:ds="http://www.w3.org/2000/09/xmldsig#"><ds:XPath>true()</ds:XPath><ds:XPath>false()</ds:XPath></ds:Transform>`, true},
+		{"IgnoredOther", `<ds:Transform xmlns:ds="http://www.w3.org/2000/09/xmldsig#"><XPath xmlns="urn:other">false()</XPath><ds:XPath>true()</ds:XPath></ds:Transform>`, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc := parseTestDocument(t, tt.xml)
+			err := NewXPathTransform().ReadXml(doc.Root())
+			if tt.fails && err == nil {
+				t.Error("expected the transform to be rejected")
+			}
+			if !tt.fails && err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+		})
+	}
+}
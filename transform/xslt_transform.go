@@ -0,0 +1,110 @@
+package transform
+
+import (
+	"context"
+	"errors"
+
+	"github.com/beevik/etree"
+	"github.com/deb-ict/go-xmldsig/canonicalizer"
+	"github.com/deb-ict/go-xmldsig/internal/xslt"
+)
+
+// XsltProcessor applies an XSLT 1.0 stylesheet to a document and returns the
+// serialized result.
+type XsltProcessor interface {
+	Process(ctx context.Context, stylesheet *etree.Element, doc *etree.Document) ([]byte, error)
+}
+
+type xsltProcessor struct {
+}
+
+// NewXsltProcessor returns the built-in processor, which supports a subset of
+// XSLT 1.0: templates, xsl:value-of, xsl:apply-templates, xsl:copy-of, xsl:if,
+// xsl:choose, xsl:for-each and xsl:text.
+func NewXsltProcessor() XsltProcessor {
+	return &xsltProcessor{}
+}
+
+func (p *xsltProcessor) Process(ctx context.Context, stylesheet *etree.Element, doc *etree.Document) ([]byte, error) {
+	compiled, err := xslt.Compile(stylesheet)
+	if err != nil {
+		return nil, err
+	}
+	return compiled.Transform(doc)
+}
+
+type xsltTransform struct {
+	stylesheet *etree.Element
+}
+
+func NewXsltTransform() Transform {
+	return &xsltTransform{}
+}
+
+func NewXsltTransformWithStylesheet(stylesheet *etree.Element) Transform {
+	return &xsltTransform{
+		stylesheet: stylesheet,
+	}
+}
+
+func (t *xsltTransform) GetAlgorithm() string {
+	return XsltTransform
+}
+
+func (t *xsltTransform) Transform(ctx context.Context, data *Data) (*Data, error) {
+	processor := GetXsltProcessor(ctx)
+	if processor == nil {
+		return nil, errors.New("xslt transform is disabled, enable it with WithXsltProcessor")
+	}
+	if t.stylesheet == nil {
+		return nil, errors.New("xslt transform does not contain a stylesheet")
+	}
+
+	// The input of the transform is parsed from the octet stream
+	octets, err := data.Octets(ctx)
+	if err != nil {
+		return nil, err
+	}
+	doc, err := parseXmlOctets(octets)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := processor.Process(ctx, t.stylesheet, doc)
+	if err != nil {
+		return nil, err
+	}
+	return NewOctetData(result), nil
+}
+
+func (t *xsltTransform) ReadXml(el *etree.Element) error {
+	var stylesheetElement *etree.Element
+	for _, child := range el.ChildElements() {
+		if child.NamespaceURI() != xslt.XslNamespaceUri || (child.Tag != "stylesheet" && child.Tag != "transform") {
+			continue
+		}
+		if stylesheetElement != nil {
+			return errors.New("xslt transform contains multiple stylesheet elements")
+		}
+		stylesheetElement = child
+	}
+	if stylesheetElement == nil {
+		return errors.New("xslt transform does not contain a stylesheet")
+	}
+
+	// Keep the namespaces in scope of the stylesheet
+	stylesheet, err := canonicalizer.NewNodeSet(stylesheetElement).Element()
+	if err != nil {
+		return err
+	}
+	t.stylesheet = stylesheet
+	return nil
+}
+
+func (t *xsltTransform) WriteXml(el *etree.Element) error {
+	if t.stylesheet == nil {
+		return errors.New("xslt transform does not contain a stylesheet")
+	}
+	el.AddChild(t.stylesheet.Copy())
+	return nil
+}
@@ -0,0 +1,84 @@
+package transform
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+const testXsltTransform = `<ds:Transform xmlns:ds="http://www.w3.org/2000/09/xmldsig#" Algorithm="http://www.w3.org/TR/1999/REC-xslt-19991116">` +
+	`<xsl:stylesheet version="1.0" xmlns:xsl="http://www.w3.org/1999/XSL/Transform">` +
+	`<xsl:output method="text"/>` +
+	`<xsl:template match="/">Total: <xsl:value-of select="sum(//amount)"/></xsl:template>` +
+	`</xsl:stylesheet>` +
+	`</ds:Transform>`
+
+const testXsltDocument = `<invoice><amount>10</amount><amount>5</amount></invoice>`
+
+func Test_XsltTransform_Disabled(t *testing.T) {
+	transform := NewXsltTransform()
+	err := transform.ReadXml(parseTestDocument(t, testXsltTransform).Root())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The transform is refused unless a processor is set on the context
+	_, err = transform.Transform(context.Background(), NewOctetData([]byte(testXsltDocument)))
+	if err == nil || !strings.Contains(err.Error(), "disabled") {
+		t.Errorf("expected the xslt transform to be disabled, got %v", err)
+	}
+}
+
+func Test_XsltTransform(t *testing.T) {
+	ctx := WithXsltProcessor(context.Background(), NewXsltProcessor())
+	transform := NewXsltTransform()
+	err := transform.ReadXml(parseTestDocument(t, testXsltTransform).Root())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	actual := transformToString(t, ctx, transform, NewOctetData([]byte(testXsltDocument)))
+	if actual != "Total: 15" {
+		t.Errorf("expected %q, got %q", "Total: 15", actual)
+	}
+
+	// The stylesheet is written again with the namespaces in scope
+	el := parseTestDocument(t, `<ds:Transform xmlns:ds="http://www.w3.org/2000/09/xmldsig#"/>`).Root()
+	err = transform.WriteXml(el)
+	if err != nil {
+		t.Fatal(err)
+	}
+	written := NewXsltTransform()
+	err = written.ReadXml(el)
+	if err != nil {
+		t.Fatal(err)
+	}
+	actual = transformToString(t, ctx, written, NewOctetData([]byte(testXsltDocument)))
+	if actual != "Total: 15" {
+		t.Errorf("expected %q after writing the stylesheet, got %q", "Total: 15", actual)
+	}
+}
+
+func Test_XsltTransform_Errors(t *testing.T) {
+	ctx := WithXsltProcessor(context.Background(), NewXsltProcessor())
+
+	_, err := NewXsltTransform().Transform(ctx, NewOctetData([]byte(testXsltDocument)))
+	if err == nil {
+		t.Error("expected a transform without stylesheet to fail")
+	}
+
+	err = NewXsltTransform().ReadXml(parseTestDocument(t, `<ds:Transform xmlns:ds="http://www.w3.org/2000/09/xmldsig#"><stylesheet/></ds:Transform>`).Root())
+	if err == nil {
+		t.Error("expected a stylesheet outside the XSL namespace to be rejected")
+	}
+
+	transform := NewXsltTransform()
+	err = transform.ReadXml(parseTestDocument(t, testXsltTransform).Root())
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = transform.Transform(ctx, NewOctetData([]byte(`<!DOCTYPE invoice><invoice/>`)))
+	if err == nil {
+		t.Error("expected a document type declaration to be rejected")
+	}
+}
@@ -2,14 +2,17 @@ package xmldsig
 
 import (
 	"context"
+	"errors"
 
 	"github.com/beevik/etree"
+	"github.com/deb-ict/go-xmldsig/transform"
 )
 
 type Transforms struct {
-	Transforms []*Transform
-	reference  *Reference
-	cachedXml  *etree.Element
+	Transforms      []*Transform
+	reference       *Reference
+	retrievalMethod *RetrievalMethod
+	cachedXml       *etree.Element
 }
 
 func newTransforms(reference *Reference) *Transforms {
@@ -18,26 +21,43 @@ func newTransforms(reference *Reference) *Transforms {
 	}
 }
 
-func (xml *Transforms) transformXmlElement(ctx context.Context, el *etree.Element) ([]byte, error) {
-	var data []byte
-	var err error
+func NewTransforms(transforms ...*Transform) *Transforms {
+	xml := newTransforms(nil)
+	for _, transform := range transforms {
+		xml.AddTransform(transform)
+	}
+	return xml
+}
+
+func (xml *Transforms) AddTransform(transform *Transform) {
+	if transform == nil {
+		return
+	}
+	transform.transforms = xml
+	xml.Transforms = append(xml.Transforms, transform)
+}
+
+func (xml *Transforms) bind(reference *Reference) {
+	xml.reference = reference
 	for _, transform := range xml.Transforms {
-		if data == nil {
-			data, err = transform.transformXmlElement(ctx, el)
-		} else {
-			data, err = transform.transformData(ctx, data)
-		}
+		transform.transforms = xml
+	}
+}
+
+func (xml *Transforms) validate() error {
+	for _, transform := range xml.Transforms {
+		err := transform.ensureTransform()
 		if err != nil {
-			return nil, err
+			return err
 		}
 	}
-	return data, nil
+	return nil
 }
 
-func (xml *Transforms) transformData(ctx context.Context, data []byte) ([]byte, error) {
+func (xml *Transforms) transform(ctx context.Context, data *transform.Data) (*transform.Data, error) {
 	var err error
 	for _, transform := range xml.Transforms {
-		data, err = transform.transformData(ctx, data)
+		data, err = transform.transform(ctx, data)
 		if err != nil {
 			return nil, err
 		}
@@ -45,8 +65,29 @@ func (xml *Transforms) transformData(ctx context.Context, data []byte) ([]byte,
 	return data, nil
 }
 
+func (xml *Transforms) bindXml(el *etree.Element) error {
+	transformElements := selectChildElements(el, "Transform", XmlDSigNamespaceUri)
+	if len(transformElements) != len(xml.Transforms) {
+		return errors.New("transforms element does not match the transforms")
+	}
+	for i, transform := range xml.Transforms {
+		err := transform.bindXml(transformElements[i])
+		if err != nil {
+			return err
+		}
+	}
+	xml.cachedXml = el
+	return nil
+}
+
 func (xml *Transforms) root() *SignedXml {
-	return xml.reference.root()
+	if xml.reference != nil {
+		return xml.reference.root()
+	}
+	if xml.retrievalMethod != nil {
+		return xml.retrievalMethod.root()
+	}
+	return nil
 }
 
 func (xml *Transforms) loadXml(el *etree.Element) error {
@@ -55,7 +96,7 @@ func (xml *Transforms) loadXml(el *etree.Element) error {
 		return err
 	}
 
-	transformElements := el.SelectElements("Transform")
+	transformElements := selectChildElements(el, "Transform", XmlDSigNamespaceUri)
 	for _, transformElement := range transformElements {
 		transform := newTransform(xml)
 		err := transform.loadXml(transformElement)
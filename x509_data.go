@@ -0,0 +1,157 @@
+package xmldsig
+
+import (
+	"crypto/x509"
+	"encoding/base64"
+	"errors"
+
+	"github.com/beevik/etree"
+)
+
+type X509IssuerSerial struct {
+	IssuerName   string
+	SerialNumber string
+}
+
+type X509Data struct {
+	IssuerSerials []*X509IssuerSerial
+	SKIs          []string
+	SubjectNames  []string
+	Certificates  []string
+	CRLs          []string
+	keyInfo       *KeyInfo
+	cachedXml     *etree.Element
+}
+
+func newX509Data(keyInfo *KeyInfo) *X509Data {
+	return &X509Data{
+		keyInfo: keyInfo,
+	}
+}
+
+func NewX509Data() *X509Data {
+	return newX509Data(nil)
+}
+
+func (xml *X509Data) WithCertificate(cert *x509.Certificate) *X509Data {
+	if cert != nil {
+		xml.Certificates = append(xml.Certificates, base64.StdEncoding.EncodeToString(cert.Raw))
+	}
+	return xml
+}
+
+func (xml *X509Data) WithIssuerSerial(cert *x509.Certificate) *X509Data {
+	if cert != nil {
+		xml.IssuerSerials = append(xml.IssuerSerials, &X509IssuerSerial{
+			IssuerName:   cert.Issuer.String(),
+			SerialNumber: cert.SerialNumber.String(),
+		})
+	}
+	return xml
+}
+
+func (xml *X509Data) WithSubjectName(cert *x509.Certificate) *X509Data {
+	if cert != nil {
+		xml.SubjectNames = append(xml.SubjectNames, cert.Subject.String())
+	}
+	return xml
+}
+
+func (xml *X509Data) WithSKI(cert *x509.Certificate) *X509Data {
+	if cert != nil && len(cert.SubjectKeyId) > 0 {
+		xml.SKIs = append(xml.SKIs, base64.StdEncoding.EncodeToString(cert.SubjectKeyId))
+	}
+	return xml
+}
+
+func (xml *X509Data) GetCertificates() ([]*x509.Certificate, error) {
+	certificates := make([]*x509.Certificate, 0, len(xml.Certificates))
+	for _, certificate := range xml.Certificates {
+		cert, err := parseBase64Certificate(certificate)
+		if err != nil {
+			return nil, err
+		}
+		certificates = append(certificates, cert)
+	}
+	return certificates, nil
+}
+
+func (xml *X509Data) root() *SignedXml {
+	if xml.keyInfo == nil {
+		return nil
+	}
+	return xml.keyInfo.root()
+}
+
+func (xml *X509Data) loadXml(el *etree.Element) error {
+	err := validateElement(el, "X509Data", XmlDSigNamespaceUri)
+	if err != nil {
+		return err
+	}
+
+	for _, childElement := range el.ChildElements() {
+		if childElement.NamespaceURI() != XmlDSigNamespaceUri {
+			continue
+		}
+		switch childElement.Tag {
+		case "X509IssuerSerial":
+			issuerNameElement, err := getSingleChildElement(childElement, "X509IssuerName", XmlDSigNamespaceUri)
+			if err != nil {
+				return err
+			}
+			serialNumberElement, err := getSingleChildElement(childElement, "X509SerialNumber", XmlDSigNamespaceUri)
+			if err != nil {
+				return err
+			}
+			xml.IssuerSerials = append(xml.IssuerSerials, &X509IssuerSerial{
+				IssuerName:   issuerNameElement.Text(),
+				SerialNumber: serialNumberElement.Text(),
+			})
+		case "X509SKI":
+			xml.SKIs = append(xml.SKIs, childElement.Text())
+		case "X509SubjectName":
+			xml.SubjectNames = append(xml.SubjectNames, childElement.Text())
+		case "X509Certificate":
+			xml.Certificates = append(xml.Certificates, childElement.Text())
+		case "X509CRL":
+			xml.CRLs = append(xml.CRLs, childElement.Text())
+		}
+	}
+
+	xml.cachedXml = el
+	return nil
+}
+
+func (xml *X509Data) getXml() (*etree.Element, error) {
+	el := etree.NewElement("X509Data")
+	el.Space = xml.root().getElementSpace(XmlDSigNamespaceUri)
+
+	if len(xml.IssuerSerials)+len(xml.SKIs)+len(xml.SubjectNames)+len(xml.Certificates)+len(xml.CRLs) == 0 {
+		return nil, errors.New("x509 data does not contain any child elements")
+	}
+
+	for _, issuerSerial := range xml.IssuerSerials {
+		issuerSerialElement := el.CreateElement("X509IssuerSerial")
+		issuerSerialElement.Space = xml.root().getElementSpace(XmlDSigNamespaceUri)
+		issuerNameElement := issuerSerialElement.CreateElement("X509IssuerName")
+		issuerNameElement.Space = xml.root().getElementSpace(XmlDSigNamespaceUri)
+		issuerNameElement.SetText(issuerSerial.IssuerName)
+		serialNumberElement := issuerSerialElement.CreateElement("X509SerialNumber")
+		serialNumberElement.Space = xml.root().getElementSpace(XmlDSigNamespaceUri)
+		serialNumberElement.SetText(issuerSerial.SerialNumber)
+	}
+	xml.createTextElements(el, "X509SKI", xml.SKIs)
+	xml.createTextElements(el, "X509SubjectName", xml.SubjectNames)
+	xml.createTextElements(el, "X509Certificate", xml.Certificates)
+	xml.createTextElements(el, "X509CRL", xml.CRLs)
+
+	return el, nil
+}
+
+func (xml *X509Data) createTextElements(el *etree.Element, tag string, values []string) {
+	for _, value := range values {
+		childElement := el.CreateElement(tag)
+		childElement.Space = xml.root().getElementSpace(XmlDSigNamespaceUri)
+		childElement.SetText(value)
+	}
+}
@@ -2,12 +2,20 @@ package xmldsig
 
 import (
 	"errors"
+	"sync"
 
 	"github.com/beevik/etree"
 )
 
 const (
-	XmlDSigNamespaceUri string = "http://www.w3.org/2000/09/xmldsig#"
+	MaskGenerationFunction_MGF1 string = "http://www.w3.org/2007/05/xmldsig-more#MGF1"
+)
+
+const (
+	XmlDSigNamespaceUri     string = "http://www.w3.org/2000/09/xmldsig#"
+	XmlDSig11NamespaceUri   string = "http://www.w3.org/2009/xmldsig11#"
+	XmlDSigMoreNamespaceUri string = "http://www.w3.org/2007/05/xmldsig-more#"
+	WsseNamespaceUri        string = "http://docs.oasis-open.org/wss/2004/01/oasis-200401-wss-wssecurity-secext-1.0.xsd"
 )
 
 var (
@@ -15,10 +23,15 @@ var (
 	ErrInvalidElementTag      = errors.New("invalid element tag")
 	ErrInvalidSignatureMethod = errors.New("invalid signature method")
 	ErrInvalidDigestMethod    = errors.New("invalid digest method")
+	ErrInvalidSigningKey      = errors.New("invalid signing key")
+	ErrInvalidVerificationKey = errors.New("invalid verification key")
+	ErrKeyNotFound            = errors.New("verification key not found")
+	ErrUnsupportedDSAKeyValue = errors.New("dsa key values are not supported")
 )
 
 var (
-	referenceElementResolvers map[string]ResolveReferenceMethod = map[string]ResolveReferenceMethod{}
+	referenceElementResolvers     map[string]ResolveReferenceMethod = map[string]ResolveReferenceMethod{}
+	referenceElementResolversLock sync.RWMutex
 )
 
 func CryptographicEquals(a, b []byte) bool {
@@ -43,7 +56,7 @@ func validateElement(el *etree.Element, tag string, namespaceUri string) error {
 }
 
 func getSingleChildElement(el *etree.Element, tag string, namespaceUri string) (*etree.Element, error) {
-	elements := el.SelectElements(tag)
+	elements := selectChildElements(el, tag, namespaceUri)
 	if len(elements) == 0 {
 		return nil, newChildElementNotFoundError(el, tag, namespaceUri)
 	}
@@ -54,7 +67,7 @@ func getSingleChildElement(el *etree.Element, tag string, namespaceUri string) (
 }
 
 func getOptionalSingleChildElement(el *etree.Element, tag string, namespaceUri string) (*etree.Element, error) {
-	elements := el.SelectElements(tag)
+	elements := selectChildElements(el, tag, namespaceUri)
 	if len(elements) > 1 {
 		return nil, NewMultipleChildElementsFoundError(el, tag, namespaceUri)
 	}
@@ -63,3 +76,13 @@ func getOptionalSingleChildElement(el *etree.Element, tag string, namespaceUri s
 	}
 	return nil, nil
 }
+
+func selectChildElements(el *etree.Element, tag string, namespaceUri string) []*etree.Element {
+	elements := make([]*etree.Element, 0)
+	for _, childElement := range el.SelectElements(tag) {
+		if childElement.NamespaceURI() == namespaceUri {
+			elements = append(elements, childElement)
+		}
+	}
+	return elements
+}